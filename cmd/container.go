@@ -1,42 +1,81 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"os"
 
 	"gofiber-baro/internal/domain"
 	"gofiber-baro/internal/handler"
+	"gofiber-baro/internal/mailer"
 	"gofiber-baro/internal/repository"
+	"gofiber-baro/internal/service/apikey"
 	"gofiber-baro/internal/service/attendance"
+	"gofiber-baro/internal/service/audit"
+	"gofiber-baro/internal/service/group"
 	"gofiber-baro/internal/service/holiday"
 	leaveService "gofiber-baro/internal/service/leave"
 	notificationService "gofiber-baro/internal/service/notification"
+	"gofiber-baro/internal/service/privacy"
 	reflectionService "gofiber-baro/internal/service/reflection"
+	"gofiber-baro/internal/service/session"
+	"gofiber-baro/internal/service/sso"
 	userService "gofiber-baro/internal/service/user"
+	"gofiber-baro/internal/service/wellbeing"
 	"gofiber-baro/internal/storage"
+	"gofiber-baro/pkg/middleware"
 
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type Container struct {
 	DB *mongo.Database
 
-	UserRepo           domain.UserRepository
-	AttendanceRepo     domain.AttendanceRepository
-	AttendanceCodeRepo domain.AttendanceCodeRepository
-	LeaveRepo          domain.LeaveRequestRepository
-	HolidayRepo        domain.HolidayRepository
-	TalkBoardRepo      domain.TalkBoardRepository
-	NotificationRepo   domain.NotificationRepository
-	StampRepo          domain.StampRepository
-	CohortRepo         domain.CohortRepository
+	UserRepo            domain.UserRepository
+	AttendanceRepo      domain.AttendanceRepository
+	AttendanceCodeRepo  domain.AttendanceCodeRepository
+	LeaveRepo           domain.LeaveRequestRepository
+	HolidayRepo         domain.HolidayRepository
+	TalkBoardRepo       domain.TalkBoardRepository
+	NotificationRepo    domain.NotificationRepository
+	StampRepo           domain.StampRepository
+	CohortRepo          domain.CohortRepository
+	MembershipRepo      domain.CohortMembershipRepository
+	RefreshTokenRepo    domain.RefreshTokenRepository
+	PasswordResetRepo   domain.PasswordResetRepository
+	AuditLogRepo        domain.AuditLogRepository
+	LoginThrottleRepo   domain.LoginThrottleRepository
+	LockoutEventRepo    domain.LockoutEventRepository
+	APIKeyRepo          domain.APIKeyRepository
+	APIKeyUsageRepo     domain.APIKeyUsageRepository
+	PrivacyRequestRepo  domain.PrivacyRequestRepository
+	GroupRepo           domain.GroupRepository
+	GroupMembershipRepo domain.GroupMembershipRepository
+	ReflectionRepo      domain.ReflectionRepository
+	TemplateRepo        domain.ReflectionTemplateRepository
+	FeedbackRepo        domain.FeedbackRepository
+	WellbeingRepo       domain.WellbeingCaseRepository
 
 	StampStorage storage.Storage
+	Mailer       mailer.Mailer
 
 	UserService                 *userService.Service
 	BadgeService                *userService.BadgeService
 	FertilizerService           *userService.FertilizerService
+	TransferService             *userService.TransferService
+	SessionService              *session.Service
+	LoginGuard                  *session.LoginGuard
+	PasswordService             *userService.PasswordService
+	TOTPService                 *userService.TOTPService
+	BulkImportService           *userService.BulkImportService
+	SSOService                  *sso.Service
 	ReflectionService           *reflectionService.Service
 	BarometerService            *reflectionService.BarometerService
+	TemplateService             *reflectionService.TemplateService
+	FeedbackService             *reflectionService.FeedbackService
+	ReminderService             *reflectionService.ReminderService
 	LeaveService                *leaveService.Service
 	HolidayService              *holiday.Service
 	NotificationService         *notificationService.Service
@@ -45,8 +84,14 @@ type Container struct {
 	AttendanceStatsService      *attendance.StatsService
 	AttendanceOverviewService   *attendance.OverviewService
 	AttendanceExportService     *attendance.ExportService
+	AuditService                *audit.Service
+	APIKeyService               *apikey.Service
+	PrivacyService              *privacy.Service
+	GroupService                *group.Service
+	WellbeingService            *wellbeing.Service
 
 	UserHandler         *handler.UserHandler
+	AuthHandler         *handler.AuthHandler
 	AdminHandler        *handler.AdminHandler
 	AttendanceHandler   *handler.AttendanceHandler
 	LeaveHandler        *handler.LeaveHandler
@@ -54,6 +99,15 @@ type Container struct {
 	TalkBoardHandler    *handler.TalkBoardHandler
 	NotificationHandler *handler.NotificationHandler
 	StampHandler        *handler.StampHandler
+	AuditHandler        *handler.AuditHandler
+	APIKeyHandler       *handler.APIKeyHandler
+	PrivacyHandler      *handler.PrivacyHandler
+	BulkImportHandler   *handler.BulkImportHandler
+	GroupHandler        *handler.GroupHandler
+	TemplateHandler     *handler.ReflectionTemplateHandler
+	FeedbackHandler     *handler.FeedbackHandler
+	WellbeingHandler    *handler.WellbeingHandler
+	ReminderHandler     *handler.ReflectionReminderHandler
 }
 
 func NewContainer(db *mongo.Database) *Container {
@@ -61,7 +115,9 @@ func NewContainer(db *mongo.Database) *Container {
 
 	c.initRepositories()
 	c.initStorage()
+	c.initMailer()
 	c.initServices()
+	c.initAuthorization()
 	c.initHandlers()
 
 	return c
@@ -77,6 +133,23 @@ func (c *Container) initRepositories() {
 	c.NotificationRepo = repository.NewNotificationRepository(c.DB)
 	c.StampRepo = repository.NewStampRepository(c.DB)
 	c.CohortRepo = repository.NewCohortRepository(c.DB)
+	c.MembershipRepo = repository.NewCohortMembershipRepository(c.DB)
+	c.RefreshTokenRepo = repository.NewRefreshTokenRepository(c.DB)
+	c.PasswordResetRepo = repository.NewPasswordResetRepository(c.DB)
+	c.AuditLogRepo = repository.NewAuditLogRepository(c.DB)
+	c.LoginThrottleRepo = repository.NewLoginThrottleRepository(c.DB)
+	c.LockoutEventRepo = repository.NewLockoutEventRepository(c.DB)
+	c.APIKeyRepo = repository.NewAPIKeyRepository(c.DB)
+	c.APIKeyUsageRepo = repository.NewAPIKeyUsageRepository(c.DB)
+	c.PrivacyRequestRepo = repository.NewPrivacyRequestRepository(c.DB)
+	c.GroupRepo = repository.NewGroupRepository(c.DB)
+	c.GroupMembershipRepo = repository.NewGroupMembershipRepository(c.DB)
+	// Until every server writes to the reflections collection and the
+	// migration has been pruned, also read the legacy users.reflections.
+	c.ReflectionRepo = repository.NewReflectionRepository(c.DB, os.Getenv("REFLECTIONS_DUAL_READ") != "false")
+	c.TemplateRepo = repository.NewReflectionTemplateRepository(c.DB)
+	c.FeedbackRepo = repository.NewFeedbackRepository(c.DB)
+	c.WellbeingRepo = repository.NewWellbeingCaseRepository(c.DB)
 }
 
 func (c *Container) initStorage() {
@@ -88,26 +161,150 @@ func (c *Container) initStorage() {
 	c.StampStorage = s
 }
 
+func (c *Container) initMailer() {
+	m, err := mailer.New()
+	if err != nil {
+		log.Printf("WARNING: SMTP misconfigured, falling back to log mailer: %v", err)
+		m = mailer.NewLogMailer()
+	}
+	c.Mailer = m
+}
+
 func (c *Container) initServices() {
-	c.UserService = userService.NewService(c.UserRepo)
+	c.WellbeingService = wellbeing.NewService(c.WellbeingRepo, c.ReflectionRepo, c.UserRepo)
+	c.UserService = userService.NewService(c.UserRepo, c.ReflectionRepo, c.TemplateRepo, c.WellbeingService)
 	c.BadgeService = userService.NewBadgeService(c.UserRepo)
-	c.ReflectionService = reflectionService.NewService(c.DB)
+	c.TransferService = userService.NewTransferService(c.UserRepo, c.MembershipRepo, c.AttendanceRepo, c.LeaveRepo, c.ReflectionRepo)
+	c.SessionService = session.NewService(c.RefreshTokenRepo, c.UserRepo)
+	c.LoginGuard = session.NewLoginGuard(c.LoginThrottleRepo, c.LockoutEventRepo, c.UserRepo)
+	c.PasswordService = userService.NewPasswordService(c.UserRepo, c.PasswordResetRepo, c.Mailer)
+	c.TOTPService = userService.NewTOTPService(c.UserRepo)
+	c.GroupService = group.NewService(c.GroupRepo, c.GroupMembershipRepo, c.UserRepo)
+	c.BulkImportService = userService.NewBulkImportService(c.UserService, c.GroupService)
+	c.SSOService = sso.NewService(c.UserRepo)
+	c.ReflectionService = reflectionService.NewService(c.DB, c.ReflectionRepo)
 	c.BarometerService = reflectionService.NewBarometerService(c.DB)
+	c.TemplateService = reflectionService.NewTemplateService(c.TemplateRepo)
+	c.FeedbackService = reflectionService.NewFeedbackService(c.FeedbackRepo, c.ReflectionRepo, c.UserRepo)
 	c.LeaveService = leaveService.NewService(c.LeaveRepo, c.UserService)
 	c.HolidayService = holiday.NewService(c.HolidayRepo, c.DB)
 	c.FertilizerService = userService.NewFertilizerService(c.UserRepo, c.HolidayService)
 	c.NotificationService = notificationService.NewService(c.NotificationRepo)
+	c.ReminderService = reflectionService.NewReminderService(c.UserRepo, c.ReflectionRepo, c.CohortRepo, c.HolidayService, c.NotificationRepo)
 
 	c.AttendanceCodeService = attendance.NewCodeService(c.AttendanceCodeRepo, c.AttendanceRepo, c.UserService)
 	c.AttendanceSubmissionService = attendance.NewSubmissionService(c.AttendanceRepo, c.UserService)
 	c.AttendanceStatsService = attendance.NewStatsService(c.AttendanceRepo, c.UserService)
 	c.AttendanceOverviewService = attendance.NewOverviewService(c.AttendanceRepo, c.AttendanceCodeRepo, c.UserService)
 	c.AttendanceExportService = attendance.NewExportService(c.AttendanceRepo, c.UserService)
+
+	c.AuditService = audit.NewService(c.AuditLogRepo, c.UserRepo)
+	c.APIKeyService = apikey.NewService(c.APIKeyRepo, c.APIKeyUsageRepo)
+	c.PrivacyService = privacy.NewService(c.DB, c.PrivacyRequestRepo, c.StampStorage)
+}
+
+// initAuthorization wires the database lookups the auth middleware needs:
+// token revocation, API keys and the cohort of each resource kind used in
+// route scopes.
+func (c *Container) initAuthorization() {
+	middleware.SetTokenRevocationChecker(c.UserService)
+	middleware.SetAPIKeyAuthenticator(c.APIKeyService)
+
+	middleware.RegisterCohortResolver("user", func(ctx context.Context, id string) (int, error) {
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return 0, middleware.ErrResourceNotFound
+		}
+		state, err := c.UserRepo.FindAuthState(ctx, oid)
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return 0, middleware.ErrResourceNotFound
+		}
+		if err != nil {
+			return 0, err
+		}
+		return state.CohortNumber, nil
+	})
+
+	middleware.RegisterCohortResolver("attendance_record", func(ctx context.Context, id string) (int, error) {
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return 0, middleware.ErrResourceNotFound
+		}
+		record, err := c.AttendanceRepo.FindByID(ctx, oid)
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return 0, middleware.ErrResourceNotFound
+		}
+		if err != nil {
+			return 0, err
+		}
+		return record.CohortNumber, nil
+	})
+
+	middleware.RegisterCohortResolver("leave_request", func(ctx context.Context, id string) (int, error) {
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return 0, middleware.ErrResourceNotFound
+		}
+		request, err := c.LeaveRepo.FindByID(ctx, oid)
+		if errors.Is(err, repository.ErrLeaveRequestNotFound) {
+			return 0, middleware.ErrResourceNotFound
+		}
+		if err != nil {
+			return 0, err
+		}
+		return request.CohortNumber, nil
+	})
+
+	middleware.RegisterCohortResolver("group", func(ctx context.Context, id string) (int, error) {
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return 0, middleware.ErrResourceNotFound
+		}
+		g, err := c.GroupRepo.FindByID(ctx, oid)
+		if errors.Is(err, domain.ErrGroupNotFound) {
+			return 0, middleware.ErrResourceNotFound
+		}
+		if err != nil {
+			return 0, err
+		}
+		return g.CohortNumber, nil
+	})
+
+	middleware.RegisterCohortResolver("reflection_template", func(ctx context.Context, id string) (int, error) {
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return 0, middleware.ErrResourceNotFound
+		}
+		t, err := c.TemplateRepo.FindByID(ctx, oid)
+		if errors.Is(err, domain.ErrTemplateNotFound) {
+			return 0, middleware.ErrResourceNotFound
+		}
+		if err != nil {
+			return 0, err
+		}
+		return t.CohortNumber, nil
+	})
+
+	middleware.RegisterCohortResolver("wellbeing_case", func(ctx context.Context, id string) (int, error) {
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return 0, middleware.ErrResourceNotFound
+		}
+		wc, err := c.WellbeingRepo.FindByID(ctx, oid)
+		if errors.Is(err, domain.ErrCaseNotFound) {
+			return 0, middleware.ErrResourceNotFound
+		}
+		if err != nil {
+			return 0, err
+		}
+		return wc.CohortNumber, nil
+	})
 }
 
 func (c *Container) initHandlers() {
-	c.UserHandler = handler.NewUserHandler(c.UserService, c.FertilizerService)
-	c.AdminHandler = handler.NewAdminHandler(c.UserService, c.BadgeService, c.FertilizerService, c.ReflectionService, c.BarometerService)
+	c.UserHandler = handler.NewUserHandler(c.UserService, c.FertilizerService, c.SessionService, c.LoginGuard, c.TOTPService, c.SSOService)
+	c.AuthHandler = handler.NewAuthHandler(c.SessionService, c.PasswordService, c.UserService, c.TOTPService)
+	c.AdminHandler = handler.NewAdminHandler(c.UserService, c.BadgeService, c.FertilizerService, c.ReflectionService, c.BarometerService, c.TransferService, c.SessionService, c.LoginGuard, c.TOTPService, c.GroupService)
 	c.AttendanceHandler = handler.NewAttendanceHandler(
 		c.AttendanceCodeService,
 		c.AttendanceSubmissionService,
@@ -121,4 +318,13 @@ func (c *Container) initHandlers() {
 	c.TalkBoardHandler = handler.NewTalkBoardHandler(c.TalkBoardRepo, c.UserService)
 	c.NotificationHandler = handler.NewNotificationHandler(c.NotificationService)
 	c.StampHandler = handler.NewStampHandler(c.StampRepo, c.CohortRepo, c.UserService, c.StampStorage)
+	c.AuditHandler = handler.NewAuditHandler(c.AuditService)
+	c.APIKeyHandler = handler.NewAPIKeyHandler(c.APIKeyService)
+	c.PrivacyHandler = handler.NewPrivacyHandler(c.PrivacyService)
+	c.BulkImportHandler = handler.NewBulkImportHandler(c.UserService, c.BulkImportService)
+	c.GroupHandler = handler.NewGroupHandler(c.GroupService)
+	c.TemplateHandler = handler.NewReflectionTemplateHandler(c.TemplateService)
+	c.FeedbackHandler = handler.NewFeedbackHandler(c.FeedbackService)
+	c.WellbeingHandler = handler.NewWellbeingHandler(c.WellbeingService)
+	c.ReminderHandler = handler.NewReflectionReminderHandler(c.ReminderService)
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"gofiber-baro/config"
 	"gofiber-baro/internal/jobs"
 	"log"
@@ -70,7 +71,34 @@ func main() {
 
 	container := NewContainer(config.DB)
 
+	// `baro-backend migrate-reflections [-prune]` copies embedded reflections
+	// into their collection and exits.
+	if len(os.Args) > 1 && os.Args[1] == "migrate-reflections" {
+		migrateReflections(container, os.Args[2:])
+		return
+	}
+
+	if _, err := container.GroupService.MigrateLegacyNames(); err != nil {
+		log.Printf("[ERROR] Group migration failed: %v", err)
+	}
+	// The charts, session analytics and exports read the reflections
+	// collection only, so copy over whatever was embedded since last time.
+	if _, err := container.ReflectionService.CopyPending(); err != nil {
+		log.Printf("[ERROR] Reflection copy failed: %v", err)
+	}
+	if _, err := container.ReflectionService.NormalizeZones(); err != nil {
+		log.Printf("[ERROR] Barometer zone normalisation failed: %v", err)
+	}
+	if _, err := container.FeedbackService.MigrateFeedback(); err != nil {
+		log.Printf("[ERROR] Feedback migration failed: %v", err)
+	}
+	if _, err := container.ReflectionService.IndexSearchText(); err != nil {
+		log.Printf("[ERROR] Reflection search indexing failed: %v", err)
+	}
+
 	go jobs.RunCohortLockJob(context.Background(), config.DB, time.Hour)
+	go jobs.RunPrivacyErasureJob(context.Background(), container.PrivacyService, time.Minute)
+	go jobs.RunReflectionReminderJob(context.Background(), container.ReminderService, 15*time.Minute)
 
 	app := fiber.New()
 
@@ -123,6 +151,7 @@ func main() {
 
 	handlers := Handlers{
 		User:         container.UserHandler,
+		Auth:         container.AuthHandler,
 		Admin:        container.AdminHandler,
 		Attendance:   container.AttendanceHandler,
 		Leave:        container.LeaveHandler,
@@ -130,6 +159,15 @@ func main() {
 		TalkBoard:    container.TalkBoardHandler,
 		Notification: container.NotificationHandler,
 		Stamp:        container.StampHandler,
+		Audit:        container.AuditHandler,
+		APIKey:       container.APIKeyHandler,
+		Privacy:      container.PrivacyHandler,
+		BulkImport:   container.BulkImportHandler,
+		Group:        container.GroupHandler,
+		Template:     container.TemplateHandler,
+		Feedback:     container.FeedbackHandler,
+		Wellbeing:    container.WellbeingHandler,
+		Reminder:     container.ReminderHandler,
 	}
 
 	setupRoutes(app, handlers)
@@ -157,3 +195,21 @@ func buildCSP() string {
 
 	return csp
 }
+
+func migrateReflections(container *Container, args []string) {
+	fs := flag.NewFlagSet("migrate-reflections", flag.ExitOnError)
+	prune := fs.Bool("prune", false, "remove copied reflections from user documents")
+	fs.Parse(args)
+
+	result, err := container.ReflectionService.Migrate(*prune)
+	if err != nil {
+		log.Fatalf("Reflection migration failed after %d users: %v", result.Users, err)
+	}
+	if _, err := container.ReflectionService.NormalizeZones(); err != nil {
+		log.Fatalf("Barometer zone normalisation failed: %v", err)
+	}
+	if _, err := container.FeedbackService.MigrateFeedback(); err != nil {
+		log.Fatalf("Feedback migration failed: %v", err)
+	}
+	log.Printf("Reflection migration done: %d users, %d copied, %d conflicts, %d pruned", result.Users, result.Copied, result.Conflicts, result.Pruned)
+}
@@ -12,6 +12,7 @@ import (
 
 type Handlers struct {
 	User         *handler.UserHandler
+	Auth         *handler.AuthHandler
 	Admin        *handler.AdminHandler
 	Attendance   *handler.AttendanceHandler
 	Leave        *handler.LeaveHandler
@@ -19,6 +20,15 @@ type Handlers struct {
 	TalkBoard    *handler.TalkBoardHandler
 	Notification *handler.NotificationHandler
 	Stamp        *handler.StampHandler
+	Audit        *handler.AuditHandler
+	APIKey       *handler.APIKeyHandler
+	Privacy      *handler.PrivacyHandler
+	BulkImport   *handler.BulkImportHandler
+	Group        *handler.GroupHandler
+	Template     *handler.ReflectionTemplateHandler
+	Feedback     *handler.FeedbackHandler
+	Wellbeing    *handler.WellbeingHandler
+	Reminder     *handler.ReflectionReminderHandler
 }
 
 func setupRoutes(app *fiber.App, h Handlers) {
@@ -35,22 +45,48 @@ func setupRoutes(app *fiber.App, h Handlers) {
 		},
 	})
 	app.Post("/login", loginLimiter, h.User.LoginUser)
+	app.Post("/login/2fa", loginLimiter, h.User.LoginTOTP)
+	app.Get("/login/oidc", loginLimiter, h.User.OIDCLoginURL)
+	app.Post("/login/oidc", loginLimiter, h.User.OIDCLogin)
 	app.Get("/api/verify-token", middleware.AuthMiddleware, h.User.VerifyToken)
 
+	auth := app.Group("/auth")
+	auth.Post("/refresh", loginLimiter, h.Auth.Refresh)
+	auth.Post("/logout", h.Auth.Logout)
+	auth.Post("/logout-all", middleware.AuthMiddleware, h.Auth.LogoutAll)
+	auth.Post("/change-password", middleware.AuthMiddleware, h.Auth.ChangePassword)
+	auth.Post("/forgot-password", loginLimiter, h.Auth.ForgotPassword)
+	auth.Post("/reset-password", loginLimiter, h.Auth.ResetPassword)
+	auth.Post("/2fa/enroll", middleware.AuthMiddleware, h.Auth.EnrollTOTP)
+	auth.Post("/2fa/verify", middleware.AuthMiddleware, h.Auth.VerifyTOTP)
+	auth.Post("/2fa/disable", middleware.AuthMiddleware, h.Auth.DisableTOTP)
+
 	notifications := app.Group("/api/notifications", middleware.AuthMiddleware)
 	notifications.Get("", h.Notification.GetActiveNotifications)
 	notifications.Post("/:id/read", h.Notification.MarkAsRead)
 
+	// Route-level permission checks (see pkg/middleware/rbac.go).
+	require := middleware.Require
+	cohortQuery := middleware.CohortQuery("cohort")
+	userParam := middleware.ResourceParam("user", "id")
+
 	protected := app.Group("/users", middleware.AuthMiddleware)
 	protected.Get("/", h.User.GetAllUsers)
 	protected.Get("/genmate-garden", h.User.GetGenmateGarden)
 	protected.Get("/:id", h.User.GetUserByID)
-	protected.Put("/:id", h.User.UpdateUser)
-	protected.Post("/:id/reflections", h.User.CreateReflection)
-	protected.Get("/:id/reflections", h.User.GetUserReflections)
+	protected.Put("/:id", require(middleware.PermUsersManage, userParam), h.Audit.Trail, h.User.UpdateUser)
+	protected.Post("/:id/reflections", middleware.SelfOr("id", require(middleware.PermUsersManage, userParam)), h.User.CreateReflection)
+	protected.Put("/:id/reflections/:reflectionId", middleware.SelfOr("id", require(middleware.PermUsersManage, userParam)), h.User.UpdateReflection)
+	protected.Get("/:id/reflections", middleware.SelfOr("id", require(middleware.PermReflectionsRead, userParam)), h.User.GetUserReflections)
+	protected.Get("/:id/reflections/:reflectionId/feedback", middleware.SelfOr("id", require(middleware.PermReflectionsRead, userParam)), h.Feedback.GetThread)
+	protected.Post("/:id/reflections/:reflectionId/feedback", middleware.SelfOr("id", require(middleware.PermReflectionsFeedback, userParam)), h.Feedback.PostFeedback)
+	protected.Patch("/:id/reflections/:reflectionId/feedback/:entryId", middleware.SelfOr("id", require(middleware.PermReflectionsFeedback, userParam)), h.Feedback.EditFeedback)
+	protected.Delete("/:id/reflections/:reflectionId/feedback/:entryId", middleware.SelfOr("id", require(middleware.PermReflectionsFeedback, userParam)), h.Feedback.DeleteFeedback)
+	protected.Get("/:id/feedback/unread", middleware.SelfOr("id", require(middleware.PermReflectionsRead, userParam)), h.Feedback.GetUnreadFeedback)
+	protected.Get("/:id/reflection-template", middleware.SelfOr("id", require(middleware.PermReflectionsRead, userParam)), h.User.GetReflectionTemplate)
 	protected.Put("/:id/personal-details", h.User.UpdatePersonalDetails)
 	protected.Post("/:id/profile/comments", h.User.AddProfileComment)
-	protected.Delete("/:id/profile/comments/:commentId", h.User.DeleteProfileComment)
+	protected.Delete("/:id/profile/comments/:commentId", require(middleware.PermBoardModerate, userParam), h.Audit.Trail, h.User.DeleteProfileComment)
 	protected.Post("/:id/profile/reactions", h.User.AddProfileReaction)
 	protected.Post("/:id/plant/reactions", h.User.AddPlantReaction)
 	protected.Post("/:id/fertilizer/protect", h.User.UseFertilizerProtect)
@@ -69,51 +105,106 @@ func setupRoutes(app *fiber.App, h Handlers) {
 		},
 	})
 
-	admin := app.Group("/admin", middleware.AuthMiddleware, middleware.CheckAdminRole, adminLimiter)
-	admin.Get("/users", h.Admin.GetAllUsers)
-	admin.Get("/userreflections/:id", h.Admin.GetUserWithReflections)
-	admin.Post("/users/:id/badges", h.Admin.AwardBadge)
-	admin.Delete("/users/:id", h.Admin.DeleteUser)
-	admin.Post("/badges/bulk", h.Admin.BulkAwardBadge)
-	admin.Post("/users/:id/fertilizer", h.Admin.GrantFertilizer)
-	admin.Post("/fertilizer/bulk", h.Admin.BulkGrantFertilizer)
-	admin.Patch("/users/:id/plant", h.Admin.UpdatePlantOverride)
-	admin.Post("/users/bulk-register", h.Admin.BulkRegisterUsers)
-	admin.Put("/users/:userId/reflections/:reflectionId/feedback", h.Admin.UpdateReflectionFeedback)
-	admin.Get("/barometer", h.Admin.GetUserBarometerData)
-	admin.Get("/reflections", h.Admin.GetAllReflections)
-	admin.Get("/reflections/chartday", h.Admin.GetAllUsersBarometerData)
-	admin.Get("/reflections/weekly", h.Admin.GetWeeklySummary)
-	admin.Get("/emoji-zone-table", h.Admin.GetEmojiZoneTableData)
-
-	admin.Post("/attendance/generate-code", h.Attendance.GenerateAttendanceCode)
-	admin.Get("/attendance/active-code", h.Attendance.GetActiveAttendanceCode)
-	admin.Get("/attendance/today", h.Attendance.GetTodayOverview)
-	admin.Post("/attendance/manual", h.Attendance.ManualMarkAttendance)
-	admin.Get("/attendance/logs", h.Attendance.GetAttendanceLogs)
-	admin.Get("/attendance/stats", h.Attendance.GetAttendanceStats)
-	admin.Get("/attendance/daily-stats", h.Attendance.GetDailyAttendanceStats)
-	admin.Get("/attendance/student/:id", h.Attendance.GetStudentAttendanceHistory)
-	admin.Post("/attendance/lock", h.Attendance.LockSession)
-	admin.Post("/attendance/bulk", h.Attendance.BulkMarkAttendance)
-	admin.Delete("/attendance/:id", h.Attendance.DeleteAttendanceRecord)
-	admin.Get("/attendance/export/salesforce", h.Attendance.ExportToSalesforce)
-	admin.Get("/attendance/export", h.Attendance.ExportAttendance)
-	admin.Patch("/users/:id/salesforce-id", h.Attendance.UpdateSalesforceID)
-	admin.Patch("/users/:id/attendance-status", h.Attendance.UpdateAttendanceStatus)
-
-	admin.Post("/holidays", h.Holiday.CreateHoliday)
-	admin.Get("/holidays", h.Holiday.GetHolidays)
-	admin.Delete("/holidays/:id", h.Holiday.DeleteHoliday)
-
-	admin.Post("/leave-requests", h.Leave.CreateLeaveRequestAdmin)
-	admin.Get("/leave-requests", h.Leave.GetAllLeaveRequests)
-	admin.Patch("/leave-requests/:id", h.Leave.UpdateLeaveRequestStatus)
-
-	admin.Post("/notifications", h.Notification.CreateNotification)
-	admin.Get("/notifications", h.Notification.GetAllNotifications)
-	admin.Put("/notifications/:id", h.Notification.UpdateNotification)
-	admin.Delete("/notifications/:id", h.Notification.DeleteNotification)
+	// Staff roles reach /admin; each route then declares the permission it
+	// needs and, for cohort-scoped roles (coach, TA), which cohort it touches.
+	// A route without a scope is closed to scoped roles. Audit.Trail logs
+	// every successful write.
+	admin := app.Group("/admin", middleware.AuthMiddleware, middleware.RequireStaff, adminLimiter, h.Audit.Trail)
+	admin.Get("/users", require(middleware.PermUsersRead, cohortQuery), h.Admin.GetAllUsers)
+	admin.Get("/userreflections/:id", require(middleware.PermReflectionsRead, userParam), h.Admin.GetUserWithReflections)
+	admin.Post("/users/:id/badges", require(middleware.PermRewardsGrant, userParam), h.Admin.AwardBadge)
+	admin.Delete("/users/:id", require(middleware.PermUsersManage, userParam), h.Admin.DeleteUser)
+	admin.Post("/badges/bulk", require(middleware.PermRewardsGrant), h.Admin.BulkAwardBadge)
+	admin.Post("/users/:id/fertilizer", require(middleware.PermRewardsGrant, userParam), h.Admin.GrantFertilizer)
+	admin.Post("/fertilizer/bulk", require(middleware.PermRewardsGrant), h.Admin.BulkGrantFertilizer)
+	admin.Patch("/users/:id/plant", require(middleware.PermUsersManage, userParam), h.Admin.UpdatePlantOverride)
+	admin.Post("/users/:id/cohort-transfer", require(middleware.PermUsersManage, userParam), h.Admin.TransferCohort)
+	admin.Get("/users/:id/cohort-history", require(middleware.PermUsersRead, userParam), h.Admin.GetCohortHistory)
+	admin.Post("/users/:id/impersonate", require(middleware.PermUsersImpersonate), h.Admin.ImpersonateUser)
+	admin.Post("/users/:id/revoke-sessions", require(middleware.PermUsersManage, userParam), h.Admin.RevokeUserSessions)
+	admin.Patch("/users/:id/disabled", require(middleware.PermUsersManage, userParam), h.Admin.SetUserDisabled)
+	admin.Post("/users/:id/unlock", require(middleware.PermUsersManage, userParam), h.Admin.UnlockUser)
+	admin.Get("/lockouts", require(middleware.PermUsersManage), h.Admin.GetLockouts)
+	admin.Patch("/users/:id/2fa", require(middleware.PermUsersManage, userParam), h.Admin.SetTOTPRequired)
+	admin.Delete("/users/:id/2fa", require(middleware.PermUsersManage, userParam), h.Admin.ResetTOTP)
+	admin.Put("/users/:id/role", require(middleware.PermRolesManage), h.Admin.SetUserRole)
+	admin.Post("/users/bulk-register", require(middleware.PermUsersManage), h.Admin.BulkRegisterUsers)
+	admin.Post("/users/bulk-upload", require(middleware.PermUsersManage), h.BulkImport.UploadUsers)
+	admin.Get("/users/bulk-upload/:id", require(middleware.PermUsersManage), h.BulkImport.GetBulkUpload)
+	admin.Get("/users/bulk-upload/:id/results", require(middleware.PermUsersManage), h.BulkImport.DownloadBulkUploadResults)
+	admin.Put("/users/:userId/reflections/:reflectionId/feedback", require(middleware.PermReflectionsFeedback, middleware.ResourceParam("user", "userId")), h.Feedback.UpdateReflectionFeedback)
+	admin.Get("/users/:id/reflections/:reflectionId/feedback", require(middleware.PermReflectionsRead, userParam), h.Feedback.GetThread)
+	admin.Post("/users/:id/reflections/:reflectionId/feedback", require(middleware.PermReflectionsFeedback, userParam), h.Feedback.PostFeedback)
+	admin.Patch("/users/:id/reflections/:reflectionId/feedback/:entryId", require(middleware.PermReflectionsFeedback, userParam), h.Feedback.EditFeedback)
+	admin.Delete("/users/:id/reflections/:reflectionId/feedback/:entryId", require(middleware.PermReflectionsFeedback, userParam), h.Feedback.DeleteFeedback)
+	admin.Get("/barometer", require(middleware.PermReflectionsRead, cohortQuery), h.Admin.GetUserBarometerData)
+	admin.Get("/reflections", require(middleware.PermReflectionsRead, cohortQuery), h.Admin.GetAllReflections)
+	admin.Get("/reflections/chartday", require(middleware.PermReflectionsRead, cohortQuery), h.Admin.GetAllUsersBarometerData)
+	admin.Get("/reflections/weekly", require(middleware.PermReflectionsRead, cohortQuery), h.Admin.GetWeeklySummary)
+	admin.Get("/reflections/search", require(middleware.PermReflectionsRead, cohortQuery), h.Admin.SearchReflections)
+	admin.Get("/reflections/sessions", require(middleware.PermReflectionsRead, cohortQuery), h.Admin.GetSessionAnalytics)
+	admin.Get("/reflections/sessions/answers", require(middleware.PermReflectionsRead, cohortQuery), h.Admin.GetSessionAnswers)
+	admin.Get("/reflections/export", require(middleware.PermReflectionsRead, cohortQuery), h.Admin.ExportReflections)
+	admin.Get("/reflections/missing", require(middleware.PermReflectionsRead, cohortQuery), h.Reminder.GetMissingReflections)
+	admin.Get("/audit-logs", require(middleware.PermAuditRead), h.Audit.GetAuditLogs)
+	admin.Get("/users/:id/export", require(middleware.PermPrivacyManage), h.Privacy.ExportUserData)
+	admin.Post("/users/:id/erase", require(middleware.PermPrivacyManage), h.Privacy.EraseUserData)
+	admin.Get("/privacy-requests", require(middleware.PermPrivacyManage), h.Privacy.GetPrivacyRequests)
+	admin.Post("/api-keys", require(middleware.PermAPIKeysManage), h.APIKey.CreateAPIKey)
+	admin.Get("/api-keys", require(middleware.PermAPIKeysManage), h.APIKey.GetAPIKeys)
+	admin.Delete("/api-keys/:id", require(middleware.PermAPIKeysManage), h.APIKey.RevokeAPIKey)
+	admin.Get("/api-keys/:id/usage", require(middleware.PermAPIKeysManage), h.APIKey.GetAPIKeyUsage)
+	groupParam := middleware.ResourceParam("group", "id")
+	admin.Get("/groups", require(middleware.PermUsersRead, cohortQuery), h.Group.GetGroups)
+	admin.Post("/groups", require(middleware.PermUsersManage, middleware.CohortBody("cohort_number")), h.Group.CreateGroup)
+	admin.Post("/groups/migrate", require(middleware.PermUsersManage), h.Group.MigrateGroups)
+	admin.Get("/groups/:id", require(middleware.PermUsersRead, groupParam), h.Group.GetGroup)
+	admin.Patch("/groups/:id", require(middleware.PermUsersManage, groupParam), h.Group.UpdateGroup)
+	admin.Delete("/groups/:id", require(middleware.PermUsersManage, groupParam), h.Group.DeleteGroup)
+	admin.Get("/groups/:id/members", require(middleware.PermUsersRead, groupParam), h.Group.GetGroupMembers)
+	admin.Post("/groups/:id/members", require(middleware.PermUsersManage, groupParam), h.Group.AddGroupMembers)
+	admin.Delete("/groups/:id/members", require(middleware.PermUsersManage, groupParam), h.Group.RemoveGroupMembers)
+	templateParam := middleware.ResourceParam("reflection_template", "id")
+	admin.Get("/reflection-templates", require(middleware.PermReflectionsRead, cohortQuery), h.Template.GetTemplates)
+	admin.Post("/reflection-templates", require(middleware.PermCohortsManage, middleware.CohortBody("cohort_number")), h.Template.CreateTemplate)
+	admin.Delete("/reflection-templates/active", require(middleware.PermCohortsManage, cohortQuery), h.Template.DeactivateTemplates)
+	admin.Get("/reflection-templates/:id", require(middleware.PermReflectionsRead, templateParam), h.Template.GetTemplate)
+	admin.Post("/reflection-templates/:id/activate", require(middleware.PermCohortsManage, templateParam), h.Template.ActivateTemplate)
+	caseParam := middleware.ResourceParam("wellbeing_case", "id")
+	admin.Get("/wellbeing/cases", require(middleware.PermReflectionsRead, cohortQuery), h.Wellbeing.GetCases)
+	admin.Get("/wellbeing/cases/:id", require(middleware.PermReflectionsRead, caseParam), h.Wellbeing.GetCase)
+	admin.Patch("/wellbeing/cases/:id", require(middleware.PermReflectionsFeedback, caseParam), h.Wellbeing.UpdateCase)
+	admin.Get("/wellbeing/rules", require(middleware.PermReflectionsRead, middleware.AnyCohort), h.Wellbeing.GetRules)
+	admin.Get("/emoji-zone-table", require(middleware.PermReflectionsRead), h.Admin.GetEmojiZoneTableData)
+
+	admin.Post("/attendance/generate-code", require(middleware.PermAttendanceManage, middleware.CohortBody("cohort")), h.Attendance.GenerateAttendanceCode)
+	admin.Get("/attendance/active-code", require(middleware.PermAttendanceManage, cohortQuery), h.Attendance.GetActiveAttendanceCode)
+	admin.Get("/attendance/today", require(middleware.PermAttendanceManage, cohortQuery), h.Attendance.GetTodayOverview)
+	admin.Post("/attendance/manual", require(middleware.PermAttendanceManage, middleware.ResourceBody("user", "user_id")), h.Attendance.ManualMarkAttendance)
+	admin.Get("/attendance/logs", require(middleware.PermAttendanceManage, cohortQuery), h.Attendance.GetAttendanceLogs)
+	admin.Get("/attendance/stats", require(middleware.PermAttendanceManage, cohortQuery), h.Attendance.GetAttendanceStats)
+	admin.Get("/attendance/daily-stats", require(middleware.PermAttendanceManage, cohortQuery), h.Attendance.GetDailyAttendanceStats)
+	admin.Get("/attendance/student/:id", require(middleware.PermAttendanceManage, userParam), h.Attendance.GetStudentAttendanceHistory)
+	admin.Post("/attendance/lock", require(middleware.PermAttendanceManage, middleware.CohortBody("cohort")), h.Attendance.LockSession)
+	admin.Post("/attendance/bulk", require(middleware.PermAttendanceManage, middleware.ResourceBody("user", "user_ids")), h.Attendance.BulkMarkAttendance)
+	admin.Delete("/attendance/:id", require(middleware.PermAttendanceManage, middleware.ResourceParam("attendance_record", "id")), h.Attendance.DeleteAttendanceRecord)
+	admin.Get("/attendance/export/salesforce", require(middleware.PermAttendanceExport, cohortQuery), h.Attendance.ExportToSalesforce)
+	admin.Get("/attendance/export", require(middleware.PermAttendanceExport, cohortQuery), h.Attendance.ExportAttendance)
+	admin.Patch("/users/:id/salesforce-id", require(middleware.PermUsersManage, userParam), h.Attendance.UpdateSalesforceID)
+	admin.Patch("/users/:id/attendance-status", require(middleware.PermUsersManage, userParam), h.Attendance.UpdateAttendanceStatus)
+
+	admin.Post("/holidays", require(middleware.PermHolidaysManage), h.Holiday.CreateHoliday)
+	admin.Get("/holidays", require(middleware.PermAttendanceManage, middleware.AnyCohort), h.Holiday.GetHolidays)
+	admin.Delete("/holidays/:id", require(middleware.PermHolidaysManage), h.Holiday.DeleteHoliday)
+
+	admin.Post("/leave-requests", require(middleware.PermLeaveManage, middleware.ResourceBody("user", "user_id")), h.Leave.CreateLeaveRequestAdmin)
+	admin.Get("/leave-requests", require(middleware.PermLeaveManage, cohortQuery), h.Leave.GetAllLeaveRequests)
+	admin.Patch("/leave-requests/:id", require(middleware.PermLeaveManage, middleware.ResourceParam("leave_request", "id")), h.Leave.UpdateLeaveRequestStatus)
+
+	admin.Post("/notifications", require(middleware.PermNotificationsManage), h.Notification.CreateNotification)
+	admin.Get("/notifications", require(middleware.PermNotificationsManage), h.Notification.GetAllNotifications)
+	admin.Put("/notifications/:id", require(middleware.PermNotificationsManage), h.Notification.UpdateNotification)
+	admin.Delete("/notifications/:id", require(middleware.PermNotificationsManage), h.Notification.DeleteNotification)
 
 	student := app.Group("/attendance", middleware.AuthMiddleware)
 	student.Post("/submit", h.Attendance.SubmitAttendance)
@@ -145,8 +236,9 @@ func setupRoutes(app *fiber.App, h Handlers) {
 	cohorts.Get("/:cohortNumber", h.Stamp.GetCohort)
 	cohorts.Get("/:cohortNumber/stamps", h.Stamp.GetCohortStamps)
 
-	admin.Put("/cohorts/:cohortNumber", h.Stamp.SetCohortLockAt)
-	admin.Post("/cohorts/:cohortNumber/poster", h.Stamp.UploadPoster)
-	admin.Delete("/cohorts/:cohortNumber/stamps", h.Stamp.ClearCohortStamps)
-	admin.Delete("/cohorts/:cohortNumber/stamps/:stampId", h.Stamp.DeleteStamp)
+	cohortParam := middleware.CohortParam("cohortNumber")
+	admin.Put("/cohorts/:cohortNumber", require(middleware.PermCohortsManage, cohortParam), h.Stamp.SetCohortLockAt)
+	admin.Post("/cohorts/:cohortNumber/poster", require(middleware.PermCohortsManage, cohortParam), h.Stamp.UploadPoster)
+	admin.Delete("/cohorts/:cohortNumber/stamps", require(middleware.PermCohortsManage, cohortParam), h.Stamp.ClearCohortStamps)
+	admin.Delete("/cohorts/:cohortNumber/stamps/:stampId", require(middleware.PermCohortsManage, cohortParam), h.Stamp.DeleteStamp)
 }
@@ -71,6 +71,14 @@ func createIndexes(ctx context.Context) error {
 		{
 			Keys: bson.D{{Key: "jsd_number", Value: 1}},
 		},
+		{
+			Keys:    bson.D{{Key: "project_group_id", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys:    bson.D{{Key: "genmate_group_id", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
 	}
 	_, err := usersColl.Indexes().CreateMany(ctx, userIndexes)
 	if err != nil {
@@ -179,6 +187,273 @@ func createIndexes(ctx context.Context) error {
 		return err
 	}
 
+	// 7. Cohort Memberships Indexes
+	membershipsColl := DB.Collection("cohort_memberships")
+	membershipIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "created_at", Value: 1},
+			},
+		},
+	}
+	_, err = membershipsColl.Indexes().CreateMany(ctx, membershipIndexes)
+	if err != nil {
+		return err
+	}
+
+	// 8. Refresh Tokens Indexes
+	refreshColl := DB.Collection("refresh_tokens")
+	refreshIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "family_id", Value: 1}},
+		},
+		{
+			// Expired tokens are useless; let Mongo drop them.
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+	_, err = refreshColl.Indexes().CreateMany(ctx, refreshIndexes)
+	if err != nil {
+		return err
+	}
+
+	// 9. Password Reset Indexes
+	resetColl := DB.Collection("password_resets")
+	resetIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+	_, err = resetColl.Indexes().CreateMany(ctx, resetIndexes)
+	if err != nil {
+		return err
+	}
+
+	// 10. Audit Log Indexes
+	auditColl := DB.Collection("audit_logs")
+	auditIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "createdAt", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "action", Value: 1}, {Key: "createdAt", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "actor_id", Value: 1}, {Key: "createdAt", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "target_id", Value: 1}, {Key: "createdAt", Value: -1}},
+		},
+	}
+	_, err = auditColl.Indexes().CreateMany(ctx, auditIndexes)
+	if err != nil {
+		return err
+	}
+
+	// 11. Login Throttle & Lockout Indexes
+	throttleColl := DB.Collection("login_throttles")
+	throttleIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			// Failures are forgotten after an hour and lockouts last 15
+			// minutes, so a throttle idle for two hours is dead weight,
+			// e.g. from attempts on emails nobody has.
+			Keys:    bson.D{{Key: "last_failed_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32((2 * time.Hour).Seconds())),
+		},
+	}
+	_, err = throttleColl.Indexes().CreateMany(ctx, throttleIndexes)
+	if err != nil {
+		return err
+	}
+
+	lockoutColl := DB.Collection("lockout_events")
+	lockoutIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "locked_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "locked_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "email", Value: 1}},
+		},
+	}
+	_, err = lockoutColl.Indexes().CreateMany(ctx, lockoutIndexes)
+	if err != nil {
+		return err
+	}
+
+	// 12. API Key Indexes
+	apiKeyColl := DB.Collection("api_keys")
+	apiKeyIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "key_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	_, err = apiKeyColl.Indexes().CreateMany(ctx, apiKeyIndexes)
+	if err != nil {
+		return err
+	}
+
+	apiKeyUsageColl := DB.Collection("api_key_usage")
+	apiKeyUsageIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "key_id", Value: 1}, {Key: "used_at", Value: -1}},
+		},
+	}
+	_, err = apiKeyUsageColl.Indexes().CreateMany(ctx, apiKeyUsageIndexes)
+	if err != nil {
+		return err
+	}
+
+	// 13. Privacy Request Indexes
+	privacyColl := DB.Collection("privacy_requests")
+	privacyIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "type", Value: 1}, {Key: "status", Value: 1}, {Key: "requested_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "requested_at", Value: -1}},
+		},
+	}
+	_, err = privacyColl.Indexes().CreateMany(ctx, privacyIndexes)
+	if err != nil {
+		return err
+	}
+
+	// 14. Group Indexes
+	groupsColl := DB.Collection("groups")
+	groupIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "cohort_number", Value: 1},
+				{Key: "kind", Value: 1},
+				{Key: "name_key", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	_, err = groupsColl.Indexes().CreateMany(ctx, groupIndexes)
+	if err != nil {
+		return err
+	}
+
+	// 15. Reflection Indexes
+	reflectionsColl := DB.Collection("reflections")
+	reflectionIndexes := []mongo.IndexModel{
+		{
+			// One reflection per learner per day
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "day", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "cohort_number", Value: 1}, {Key: "day", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "date", Value: -1}},
+		},
+	}
+	_, err = reflectionsColl.Indexes().CreateMany(ctx, reflectionIndexes)
+	if err != nil {
+		return err
+	}
+
+	// 16. Reflection Template Indexes
+	templatesColl := DB.Collection("reflection_templates")
+	_, err = templatesColl.Indexes().CreateOne(ctx, mongo.IndexModel{
+		// Versions are numbered per cohort
+		Keys:    bson.D{{Key: "cohort_number", Value: 1}, {Key: "version", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	// 17. Reflection Feedback Indexes
+	feedbackColl := DB.Collection("reflection_feedback")
+	_, err = feedbackColl.Indexes().CreateOne(ctx, mongo.IndexModel{
+		// A reflection's thread in order
+		Keys: bson.D{{Key: "reflection_id", Value: 1}, {Key: "created_at", Value: 1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	// 18. Wellbeing Case Indexes
+	caseColl := DB.Collection("wellbeing_cases")
+	caseIndexes := []mongo.IndexModel{
+		{
+			// One unresolved case per learner and rule
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "rule_id", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"active": true}),
+		},
+		{
+			Keys: bson.D{
+				{Key: "cohort_number", Value: 1},
+				{Key: "status", Value: 1},
+				{Key: "updated_at", Value: -1},
+			},
+		},
+	}
+	_, err = caseColl.Indexes().CreateMany(ctx, caseIndexes)
+	if err != nil {
+		return err
+	}
+
+	// 19. Notification Indexes
+	notificationColl := DB.Collection("notifications")
+	_, err = notificationColl.Indexes().CreateOne(ctx, mongo.IndexModel{
+		// One generated notification per recipient, kind and day
+		Keys: bson.D{
+			{Key: "recipient_id", Value: 1},
+			{Key: "kind", Value: 1},
+			{Key: "day", Value: 1},
+		},
+		Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"recipient_id": bson.M{"$exists": true}}),
+	})
+	if err != nil {
+		return err
+	}
+
+	// 20. Reflection Search Index
+	_, err = reflectionsColl.Indexes().CreateOne(ctx, mongo.IndexModel{
+		// search_text is pre-split (see domain.SearchTerms), so no stemming
+		// or stop words
+		Keys: bson.D{{Key: "search_text", Value: "text"}},
+		Options: options.Index().
+			SetName("reflection_search").
+			SetDefaultLanguage("none"),
+	})
+	if err != nil {
+		return err
+	}
+
 	log.Println("Database indexes synchronized successfully")
 	return nil
 }
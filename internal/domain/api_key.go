@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrAPIKeyNotFound = errors.New("API key not found")
+
+// APIKey lets an integration (e.g. the Salesforce sync) call the API without
+// a user login. Only the SHA-256 of the key is stored; Prefix is kept in the
+// clear so admins can tell keys apart.
+type APIKey struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"_id"`
+	Name        string             `bson:"name" json:"name"`
+	Prefix      string             `bson:"prefix" json:"prefix"`
+	KeyHash     string             `bson:"key_hash" json:"-"`
+	Permissions []string           `bson:"permissions" json:"permissions"`
+	CreatedBy   primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	ExpiresAt   *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	LastUsedAt  *time.Time         `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	RevokedAt   *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+}
+
+// Active reports whether the key can still authenticate at t.
+func (k *APIKey) Active(t time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || k.ExpiresAt.After(t)
+}
+
+// APIKeyUsage is one request made with an API key.
+type APIKeyUsage struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"_id"`
+	KeyID      primitive.ObjectID `bson:"key_id" json:"key_id"`
+	Method     string             `bson:"method" json:"method"`
+	Route      string             `bson:"route" json:"route"`
+	StatusCode int                `bson:"status_code" json:"status_code"`
+	IPAddress  string             `bson:"ip_address" json:"ip_address"`
+	UsedAt     time.Time          `bson:"used_at" json:"used_at"`
+}
+
+type APIKeyRepository interface {
+	Insert(ctx context.Context, key *APIKey) error
+	FindByHash(ctx context.Context, hash string) (*APIKey, error)
+	FindAll(ctx context.Context) ([]APIKey, error)
+	Revoke(ctx context.Context, id primitive.ObjectID, at time.Time) error
+	TouchLastUsed(ctx context.Context, id primitive.ObjectID, at time.Time) error
+}
+
+type APIKeyUsageRepository interface {
+	Insert(ctx context.Context, usage *APIKeyUsage) error
+	// FindByKey returns usage newest first.
+	FindByKey(ctx context.Context, keyID primitive.ObjectID, skip, limit int64) ([]APIKeyUsage, int64, error)
+}
@@ -128,6 +128,8 @@ type AttendanceRepository interface {
 	UpdateRecord(ctx interface{}, id primitive.ObjectID, update interface{}) error
 	UpdateRecords(ctx interface{}, filter AttendanceRecordFilter, update interface{}) error
 	DeleteRecord(ctx interface{}, id primitive.ObjectID, deletedBy string) error
+	// ReassignCohort moves a learner's records dated on or after fromDate to cohort.
+	ReassignCohort(ctx interface{}, userID primitive.ObjectID, fromDate string, cohort int) (int64, error)
 	CountRecords(ctx interface{}, filter AttendanceRecordFilter) (int64, error)
 	AggregateStats(ctx interface{}, pipeline interface{}) ([]AttendanceStats, error)
 	AggregateDailyStats(ctx interface{}, pipeline interface{}) ([]map[string]interface{}, error)
@@ -12,14 +12,28 @@ type AuditLog struct {
 	Action     string             `bson:"action" json:"action"`         // e.g., "UPDATE_ATTENDANCE", "AWARD_BADGE"
 	ActorID    primitive.ObjectID `bson:"actor_id" json:"actor_id"`     // Who did it
 	ActorName  string             `bson:"actor_name" json:"actor_name"` // Cache name for easy display
+	TargetType string             `bson:"target_type,omitempty" json:"target_type,omitempty"` // "user", "leave_request", "cohort", ...
 	TargetID   primitive.ObjectID `bson:"target_id" json:"target_id"`   // Who/What it was done to
 	TargetName string             `bson:"target_name" json:"target_name"`
 	Details    string             `bson:"details" json:"details"`       // Human readable description
+	Before     map[string]interface{} `bson:"before,omitempty" json:"before,omitempty"` // Changed fields, old values
+	After      map[string]interface{} `bson:"after,omitempty" json:"after,omitempty"`   // Changed fields, new values
 	IPAddress  string             `bson:"ip_address" json:"ip_address"`
 	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
 }
 
+// AuditLogFilter narrows GET /admin/audit-logs. Zero values match everything.
+type AuditLogFilter struct {
+	Action   string
+	ActorID  primitive.ObjectID
+	TargetID primitive.ObjectID
+	From     time.Time
+	To       time.Time
+}
+
 type AuditLogRepository interface {
 	Insert(ctx context.Context, log *AuditLog) error
-	FindAll(ctx context.Context, filter interface{}, limit int64) ([]AuditLog, error)
+	// FindAll returns the page newest first along with the total match count.
+	// A limit of 0 returns every match.
+	FindAll(ctx context.Context, filter AuditLogFilter, skip, limit int64) ([]AuditLog, int64, error)
 }
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrBulkImportNotFound = errors.New("bulk import not found")
+
+// ErrBulkImportGone is returned for an import ID this server doesn't hold:
+// imports live in the memory of the server that ran them, for a day.
+var ErrBulkImportGone = errors.New("bulk import is no longer available; imports are kept for 24 hours on the server that ran them and are lost on restart")
+var ErrBulkImportRunning = errors.New("bulk import is still running")
+var ErrBulkImportFailed = errors.New("bulk import failed; no results were produced")
+var ErrBulkResultsCollected = errors.New("bulk import results have already been downloaded")
+var ErrUnsupportedSheet = errors.New("file must be a .csv or .xlsx sheet")
+
+type BulkImportStatus string
+
+const (
+	BulkImportRunning   BulkImportStatus = "running"
+	BulkImportCompleted BulkImportStatus = "completed"
+	BulkImportFailed    BulkImportStatus = "failed"
+)
+
+// BulkImport tracks an uploaded registration sheet being committed in the
+// background. It lives in memory only: the results sheet holds generated
+// passwords, so it is handed out once and never written to the database.
+type BulkImport struct {
+	ID                 string             `json:"id"`
+	Filename           string             `json:"filename"`
+	CohortNumber       int                `json:"cohort_number"`
+	Status             BulkImportStatus   `json:"status"`
+	Total              int                `json:"total"`
+	Created            int                `json:"created"`
+	Skipped            int                `json:"skipped"`
+	Failed             int                `json:"failed"`
+	StartedBy          primitive.ObjectID `json:"started_by"`
+	StartedAt          time.Time          `json:"started_at"`
+	FinishedAt         *time.Time         `json:"finished_at,omitempty"`
+	ResultsCollected   bool               `json:"results_collected"`
+	ResultsCollectedAt *time.Time         `json:"results_collected_at,omitempty"`
+}
@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrInvalidCohort = errors.New("cohort number must be positive")
+var ErrSameCohort = errors.New("learner is already in that cohort")
+var ErrInvalidEffectiveDate = errors.New("effective date must be YYYY-MM-DD and not in the future")
+
+// CohortMembership is one span of a learner's time in a cohort. EndDate is
+// exclusive and empty while the membership is current.
+type CohortMembership struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"_id"`
+	UserID        primitive.ObjectID `bson:"user_id" json:"user_id"`
+	CohortNumber  int                `bson:"cohort_number" json:"cohort_number"`
+	StartDate     string             `bson:"start_date,omitempty" json:"start_date,omitempty"`
+	EndDate       string             `bson:"end_date,omitempty" json:"end_date,omitempty"`
+	Reason        string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	TransferredBy string             `bson:"transferred_by,omitempty" json:"transferred_by,omitempty"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// CohortTransferResult reports what a transfer touched so admins can verify it.
+type CohortTransferResult struct {
+	UserID                primitive.ObjectID `json:"user_id"`
+	FromCohort            int                `json:"from_cohort"`
+	ToCohort              int                `json:"to_cohort"`
+	EffectiveDate         string             `json:"effective_date"`
+	AttendanceMigrated    int64              `json:"attendance_migrated"`
+	LeaveRequestsMigrated int64              `json:"leave_requests_migrated"`
+	ReflectionsMigrated   int64              `json:"reflections_migrated"`
+}
+
+type CohortMembershipRepository interface {
+	Insert(ctx context.Context, membership *CohortMembership) error
+	FindByUserID(ctx context.Context, userID primitive.ObjectID) ([]CohortMembership, error)
+	CloseOpen(ctx context.Context, userID primitive.ObjectID, endDate string) (int64, error)
+}
@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrGroupNotFound = errors.New("group not found")
+var ErrGroupExists = errors.New("a group with this name already exists in the cohort")
+var ErrInvalidGroupKind = errors.New("group kind must be project or genmate")
+
+type GroupKind string
+
+const (
+	GroupKindProject GroupKind = "project"
+	GroupKindGenmate GroupKind = "genmate"
+)
+
+func (k GroupKind) Valid() bool {
+	return k == GroupKindProject || k == GroupKindGenmate
+}
+
+// UserIDField is the users field holding the ID of a member's group of this
+// kind.
+func (k GroupKind) UserIDField() string {
+	return string(k) + "_group_id"
+}
+
+// UserNameField is the users field holding the group's name, kept in sync so
+// exports and older clients still read a plain string.
+func (k GroupKind) UserNameField() string {
+	return string(k) + "_group"
+}
+
+// Group is a project or genmate group within one cohort. Learners belong to
+// at most one group of each kind.
+type Group struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"_id"`
+	CohortNumber int                `bson:"cohort_number" json:"cohort_number"`
+	Kind         GroupKind          `bson:"kind" json:"kind"`
+	Name         string             `bson:"name" json:"name"`
+	// NameKey is the lower-cased name, unique per cohort and kind, so
+	// "Team 1" and "team 1 " cannot both exist.
+	NameKey     string    `bson:"name_key" json:"-"`
+	Mentor      string    `bson:"mentor,omitempty" json:"mentor,omitempty"`
+	ChannelURL  string    `bson:"channel_url,omitempty" json:"channel_url,omitempty"`
+	Description string    `bson:"description,omitempty" json:"description,omitempty"`
+	MemberCount int       `bson:"-" json:"member_count"`
+	CreatedAt   time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+type GroupRepository interface {
+	Insert(ctx context.Context, group *Group) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*Group, error)
+	FindByNameKey(ctx context.Context, cohort int, kind GroupKind, nameKey string) (*Group, error)
+	// FindByCohort lists a cohort's groups by name; an empty kind means both.
+	FindByCohort(ctx context.Context, cohort int, kind GroupKind) ([]Group, error)
+	Update(ctx context.Context, id primitive.ObjectID, fields map[string]interface{}) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// GroupMembershipRepository assigns users to groups. Membership lives on the
+// user document.
+type GroupMembershipRepository interface {
+	// Assign puts the given users of the group's cohort into it, replacing
+	// their previous group of that kind, and returns how many were matched.
+	Assign(ctx context.Context, group *Group, userIDs []primitive.ObjectID) (int64, error)
+	// Unassign removes the given users from the group; nil removes everyone.
+	Unassign(ctx context.Context, group *Group, userIDs []primitive.ObjectID) (int64, error)
+	// Rename copies a renamed group's name onto its members.
+	Rename(ctx context.Context, group *Group) error
+	// CountMembers returns the number of non-deleted members per group ID.
+	CountMembers(ctx context.Context, groupIDs []primitive.ObjectID) (map[primitive.ObjectID]int, error)
+	// LegacyNames lists the distinct free-text group names per cohort of
+	// users who have a name of this kind but no group ID yet.
+	LegacyNames(ctx context.Context, kind GroupKind) ([]LegacyGroupName, error)
+	// AssignLegacy links the users of the group's cohort whose free-text
+	// name is one of names and who have no group ID of that kind.
+	AssignLegacy(ctx context.Context, group *Group, names []string) (int64, error)
+}
+
+// LegacyGroupName is one distinct free-text group name found on users.
+type LegacyGroupName struct {
+	CohortNumber int    `bson:"cohort_number"`
+	Name         string `bson:"name"`
+}
@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+var ErrInvalidLearnerStatus = errors.New("status must be one of active, on-hold, dropout, dismissed, graduated")
+
+// LearnerStatus is where a learner stands in the programme. Only active
+// learners are expected in class, so only they count toward stats and exports.
+type LearnerStatus string
+
+const (
+	LearnerActive    LearnerStatus = "active"
+	LearnerOnHold    LearnerStatus = "on-hold"
+	LearnerDropout   LearnerStatus = "dropout"
+	LearnerDismissed LearnerStatus = "dismissed"
+	LearnerGraduated LearnerStatus = "graduated"
+)
+
+func (s LearnerStatus) IsValid() bool {
+	switch s {
+	case LearnerActive, LearnerOnHold, LearnerDropout, LearnerDismissed, LearnerGraduated:
+		return true
+	}
+	return false
+}
+
+// StatusTransition records a learner entering a status from EffectiveDate
+// (YYYY-MM-DD, inclusive) onward.
+type StatusTransition struct {
+	Status        LearnerStatus `bson:"status" json:"status"`
+	EffectiveDate string        `bson:"effective_date" json:"effective_date"`
+	Reason        string        `bson:"reason,omitempty" json:"reason,omitempty"`
+	ChangedBy     string        `bson:"changed_by,omitempty" json:"changed_by,omitempty"`
+	ChangedAt     time.Time     `bson:"changed_at" json:"changed_at"`
+}
+
+// StatusOn returns the learner's status on date. Learners without a history
+// fall back to the legacy attendance_status, which applies to every date.
+func (u *User) StatusOn(date string) LearnerStatus {
+	if len(u.StatusHistory) == 0 {
+		if u.AttendanceStatus == "" {
+			return LearnerActive
+		}
+		return LearnerStatus(u.AttendanceStatus)
+	}
+
+	history := make([]StatusTransition, len(u.StatusHistory))
+	copy(history, u.StatusHistory)
+	sort.SliceStable(history, func(i, j int) bool {
+		if history[i].EffectiveDate != history[j].EffectiveDate {
+			return history[i].EffectiveDate < history[j].EffectiveDate
+		}
+		return history[i].ChangedAt.Before(history[j].ChangedAt)
+	})
+
+	status := LearnerActive
+	for _, t := range history {
+		if t.EffectiveDate > date {
+			break
+		}
+		status = t.Status
+	}
+	return status
+}
+
+// CountsOn reports whether the learner should appear in attendance stats and
+// exports for date.
+func (u *User) CountsOn(date string) bool {
+	return u.StatusOn(date) == LearnerActive
+}
+
+// ActiveDuring reports whether the learner was active on any day in
+// [start, end].
+func (u *User) ActiveDuring(start, end string) bool {
+	if u.CountsOn(start) {
+		return true
+	}
+	for _, t := range u.StatusHistory {
+		if t.Status == LearnerActive && t.EffectiveDate > start && t.EffectiveDate <= end {
+			return true
+		}
+	}
+	return false
+}
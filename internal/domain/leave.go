@@ -54,5 +54,6 @@ type LeaveRequestRepository interface {
 	FindByID(ctx interface{}, id primitive.ObjectID) (*LeaveRequest, error)
 	FindAll(ctx interface{}, filter LeaveRequestFilter) ([]LeaveRequest, error)
 	FindByUserID(ctx interface{}, userID primitive.ObjectID) ([]LeaveRequest, error)
+	ReassignCohort(ctx interface{}, userID primitive.ObjectID, fromDate string, cohort int) (int64, error)
 	UpdateStatus(ctx interface{}, id primitive.ObjectID, status LeaveRequestStatus, reviewedBy primitive.ObjectID, reviewedByName, reviewNotes string) error
 }
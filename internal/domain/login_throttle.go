@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrAccountLocked = errors.New("account is temporarily locked after too many failed logins")
+var ErrLoginThrottled = errors.New("too many failed logins, please wait before trying again")
+
+// LoginThrottle tracks consecutive failed logins for one email address. It is
+// keyed by the normalised email rather than the user so unknown addresses are
+// throttled the same way and the response never reveals which emails exist.
+type LoginThrottle struct {
+	Email         string     `bson:"email" json:"email"`
+	FailedCount   int        `bson:"failed_count" json:"failed_count"`
+	LastFailedAt  time.Time  `bson:"last_failed_at" json:"last_failed_at"`
+	NextAllowedAt *time.Time `bson:"next_allowed_at,omitempty" json:"next_allowed_at,omitempty"`
+	LockedUntil   *time.Time `bson:"locked_until,omitempty" json:"locked_until,omitempty"`
+}
+
+// LockoutEvent is written each time an account is locked, for admins to
+// review.
+type LockoutEvent struct {
+	ID             primitive.ObjectID  `bson:"_id,omitempty" json:"_id"`
+	UserID         primitive.ObjectID  `bson:"user_id,omitempty" json:"user_id,omitempty"` // zero for unknown emails
+	Email          string              `bson:"email" json:"email"`
+	IPAddress      string              `bson:"ip_address" json:"ip_address"`
+	FailedAttempts int                 `bson:"failed_attempts" json:"failed_attempts"`
+	LockedAt       time.Time           `bson:"locked_at" json:"locked_at"`
+	LockedUntil    time.Time           `bson:"locked_until" json:"locked_until"`
+	UnlockedAt     *time.Time          `bson:"unlocked_at,omitempty" json:"unlocked_at,omitempty"`
+	UnlockedBy     *primitive.ObjectID `bson:"unlocked_by,omitempty" json:"unlocked_by,omitempty"`
+}
+
+type LoginThrottleRepository interface {
+	Find(ctx context.Context, email string) (*LoginThrottle, error)
+	// RecordFailure bumps the failure count (starting over when the last
+	// failure is older than resetAfter) and returns the updated state.
+	RecordFailure(ctx context.Context, email string, at time.Time, resetAfter time.Duration) (*LoginThrottle, error)
+	SetNextAllowed(ctx context.Context, email string, at time.Time) error
+	// Lock sets locked_until and clears the failure count.
+	Lock(ctx context.Context, email string, until time.Time) error
+	Reset(ctx context.Context, email string) error
+}
+
+type LockoutEventRepository interface {
+	Insert(ctx context.Context, event *LockoutEvent) error
+	// FindAll returns events newest first. A zero userID matches every user.
+	FindAll(ctx context.Context, userID primitive.ObjectID, skip, limit int64) ([]LockoutEvent, int64, error)
+	// MarkUnlocked closes every still-active lockout for email.
+	MarkUnlocked(ctx context.Context, email string, by primitive.ObjectID, at time.Time) error
+}
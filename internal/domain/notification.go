@@ -18,14 +18,32 @@ type Notification struct {
 	EndDate     time.Time            `json:"end_date" bson:"end_date"`
 	CreatedAt   time.Time            `json:"created_at" bson:"created_at"`
 	ReadByUsers []primitive.ObjectID `json:"read_by_users" bson:"read_by_users"`
+	// RecipientID addresses the notification to one user; broadcasts to
+	// everyone leave it zero. Kind and Day identify generated ones, such as
+	// a day's reflection reminder, so they are only created once.
+	RecipientID primitive.ObjectID `json:"recipient_id,omitempty" bson:"recipient_id,omitempty"`
+	Kind        string             `json:"kind,omitempty" bson:"kind,omitempty"`
+	Day         string             `json:"day,omitempty" bson:"day,omitempty"`
 }
 
+// NotificationReflectionReminder is the Kind of the daily reminder sent to
+// learners who haven't reflected yet.
+const NotificationReflectionReminder = "reflection_reminder"
+
 type NotificationRepository interface {
 	Create(notification *Notification) error
 	GetByID(id primitive.ObjectID) (*Notification, error)
+	// GetAll returns the broadcasts, not the notifications sent to one user.
 	GetAll() ([]Notification, error)
-	GetActive() ([]Notification, error)
+	// GetActive returns the active broadcasts plus the active notifications
+	// addressed to userID (none if it is zero).
+	GetActive(userID primitive.ObjectID) ([]Notification, error)
 	Update(id primitive.ObjectID, updates map[string]interface{}) error
 	Delete(id primitive.ObjectID) error
 	MarkAsRead(id primitive.ObjectID, userID primitive.ObjectID) error
+	// CreateForRecipient inserts n unless its recipient already has one of
+	// the same Kind and Day, and reports whether it did.
+	CreateForRecipient(n *Notification) (bool, error)
+	// Withdraw deactivates the recipients' notifications of kind and day.
+	Withdraw(recipientIDs []primitive.ObjectID, kind, day string) (int64, error)
 }
@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrInvalidResetToken = errors.New("reset token is invalid, expired or already used")
+var ErrWrongPassword = errors.New("current password is incorrect")
+var ErrWeakPassword = errors.New("password must be at least 8 characters")
+var ErrPasswordUnchanged = errors.New("new password must differ from the current one")
+
+// PasswordResetToken is a single-use token mailed to a user who forgot their
+// password. Only the SHA-256 of the raw token is stored.
+type PasswordResetToken struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"_id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	TokenHash string             `bson:"token_hash" json:"-"`
+	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at"`
+	UsedAt    *time.Time         `bson:"used_at,omitempty" json:"used_at,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+type PasswordResetRepository interface {
+	Insert(ctx context.Context, token *PasswordResetToken) error
+	// Consume marks a live token used and returns it; a used or expired
+	// token yields ErrInvalidResetToken.
+	Consume(ctx context.Context, hash string) (*PasswordResetToken, error)
+	InvalidateForUser(ctx context.Context, userID primitive.ObjectID) error
+}
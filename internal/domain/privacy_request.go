@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrPrivacyRequestOpen = errors.New("an erasure request for this user is already in progress")
+var ErrUserErased = errors.New("user data has already been erased")
+
+type PrivacyRequestType string
+
+const (
+	PrivacyExport  PrivacyRequestType = "export"
+	PrivacyErasure PrivacyRequestType = "erasure"
+)
+
+type PrivacyRequestStatus string
+
+const (
+	PrivacyPending   PrivacyRequestStatus = "pending"
+	PrivacyRunning   PrivacyRequestStatus = "running"
+	PrivacyCompleted PrivacyRequestStatus = "completed"
+	PrivacyFailed    PrivacyRequestStatus = "failed"
+)
+
+// PrivacyRequest records a PDPA data subject request. Exports complete as
+// they are downloaded; erasures are queued and run by the privacy job.
+// Summary counts the documents touched per collection.
+type PrivacyRequest struct {
+	ID          primitive.ObjectID   `bson:"_id,omitempty" json:"_id"`
+	UserID      primitive.ObjectID   `bson:"user_id" json:"user_id"`
+	Type        PrivacyRequestType   `bson:"type" json:"type"`
+	Status      PrivacyRequestStatus `bson:"status" json:"status"`
+	RequestedBy primitive.ObjectID   `bson:"requested_by" json:"requested_by"`
+	RequestedAt time.Time            `bson:"requested_at" json:"requested_at"`
+	CompletedAt *time.Time           `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+	Summary     map[string]int64     `bson:"summary,omitempty" json:"summary,omitempty"`
+	Error       string               `bson:"error,omitempty" json:"error,omitempty"`
+}
+
+type PrivacyRequestRepository interface {
+	Insert(ctx context.Context, req *PrivacyRequest) error
+	// HasOpen reports whether userID has a pending or running request of t.
+	HasOpen(ctx context.Context, userID primitive.ObjectID, t PrivacyRequestType) (bool, error)
+	// ClaimNext marks the oldest pending request of t as running and returns
+	// it, or nil when the queue is empty.
+	ClaimNext(ctx context.Context, t PrivacyRequestType) (*PrivacyRequest, error)
+	Complete(ctx context.Context, id primitive.ObjectID, summary map[string]int64, at time.Time) error
+	Fail(ctx context.Context, id primitive.ObjectID, reason string, at time.Time) error
+	// FindAll returns requests newest first. A zero userID matches every user.
+	FindAll(ctx context.Context, userID primitive.ObjectID, skip, limit int64) ([]PrivacyRequest, int64, error)
+}
@@ -0,0 +1,140 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"html"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrReflectionNotFound = errors.New("reflection not found")
+var ErrReflectionExists = errors.New("user has already created a reflection today")
+var ErrInvalidBarometerZone = errors.New("invalid barometer zone")
+var ErrReflectionEditClosed = errors.New("reflection can no longer be edited")
+var ErrReflectionEditConflict = errors.New("reflection was changed meanwhile")
+
+// BarometerZone is how a learner felt about the day. Reflections store the
+// canonical spelling below; ParseBarometerZone accepts the variants older
+// clients sent.
+type BarometerZone string
+
+const (
+	ZoneComfort            BarometerZone = "Comfort Zone"
+	ZoneStretchEnjoying    BarometerZone = "Stretch Zone - Enjoying the Challenges"
+	ZoneStretchOverwhelmed BarometerZone = "Stretch Zone - Overwhelmed"
+	ZonePanic              BarometerZone = "Panic Zone"
+)
+
+// BarometerZones lists the zones from most to least comfortable.
+var BarometerZones = []BarometerZone{ZoneComfort, ZoneStretchEnjoying, ZoneStretchOverwhelmed, ZonePanic}
+
+// ParseBarometerZone maps any capitalisation or spacing of a zone name to the
+// canonical zone.
+func ParseBarometerZone(s string) (BarometerZone, bool) {
+	key := zoneKey(s)
+	for _, z := range BarometerZones {
+		if zoneKey(string(z)) == key {
+			return z, true
+		}
+	}
+	return "", false
+}
+
+func zoneKey(s string) string {
+	s = strings.ReplaceAll(strings.ToLower(s), "-", " - ")
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Valid reports whether z is spelled canonically.
+func (z BarometerZone) Valid() bool {
+	for _, c := range BarometerZones {
+		if z == c {
+			return true
+		}
+	}
+	return false
+}
+
+// SessionKey is how session names are compared: unescaped, lowercased and
+// with runs of spaces collapsed, so "SQL  Joins" and "sql joins" are the
+// same session.
+func SessionKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(html.UnescapeString(name)), " "))
+}
+
+// DayKey is the Bangkok calendar day a reflection belongs to. Old reflections
+// were stored without Day, so it falls back to their date.
+func (r *Reflection) DayKey() string {
+	if r.Day != "" {
+		return r.Day
+	}
+	loc, err := time.LoadLocation("Asia/Bangkok")
+	if err != nil {
+		loc = time.FixedZone("ICT", 7*60*60)
+	}
+	return r.Date.In(loc).Format("2006-01-02")
+}
+
+// ReflectionRepository stores reflections in their own collection, one per
+// user and day. Reflections used to live in an array on the user document;
+// while that array is still read (dual-read, during rollout), the per-user
+// methods merge it in, preferring the collection's copy of a reflection.
+type ReflectionRepository interface {
+	// Insert returns ErrReflectionExists if the user already has a
+	// reflection on r.Day.
+	Insert(ctx context.Context, r *Reflection) error
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]Reflection, error)
+	ExistsOnDay(ctx context.Context, userID primitive.ObjectID, day string) (bool, error)
+	// FindDays returns the days each user reflected on.
+	FindDays(ctx context.Context, userIDs []primitive.ObjectID) (map[primitive.ObjectID][]string, error)
+	// FindByID falls back to the legacy array while dual-read is on, copying
+	// the reflection into the collection so it can be written to.
+	FindByID(ctx context.Context, userID, reflectionID primitive.ObjectID) (*Reflection, error)
+	// UpdateContent saves r's content, UpdatedAt and EditedAfterFeedback and
+	// appends its last revision. It returns ErrReflectionEditConflict unless
+	// the stored UpdatedAt still equals readUpdatedAt, i.e. no other edit
+	// got in first.
+	UpdateContent(ctx context.Context, r *Reflection, readUpdatedAt *time.Time) error
+	// MarkStaffReply records a staff reply in the reflection's feedback
+	// thread at at, which makes it unread for the learner.
+	MarkStaffReply(ctx context.Context, userID, reflectionID primitive.ObjectID, at time.Time) error
+	MarkFeedbackRead(ctx context.Context, userID, reflectionID primitive.ObjectID, at time.Time) error
+	// FindUnreadFeedback lists the user's reflections with unread replies.
+	FindUnreadFeedback(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error)
+	// EachLegacyFeedback calls fn for every reflection that still has an
+	// admin_feedback string.
+	EachLegacyFeedback(ctx context.Context, fn func(r *Reflection) error) error
+	// ClearLegacyFeedback drops the admin_feedback string once it is in the
+	// thread, marking the reply as given and read at at.
+	ClearLegacyFeedback(ctx context.Context, reflectionID primitive.ObjectID, at time.Time) error
+	// ReassignCohort re-tags the user's reflections from fromDay onward.
+	ReassignCohort(ctx context.Context, userID primitive.ObjectID, fromDay string, cohort int) (int64, error)
+
+	// EachEmbedded calls fn for every user that still has reflections in
+	// the legacy array; with pendingOnly, only for those whose array has
+	// grown since MarkCopied.
+	EachEmbedded(ctx context.Context, pendingOnly bool, fn func(userID primitive.ObjectID, cohort int, reflections []Reflection) error) error
+	// HasPendingEmbedded reports whether any user's legacy array has grown
+	// since MarkCopied, i.e. whether a copy has anything to do.
+	HasPendingEmbedded(ctx context.Context) (bool, error)
+	// MarkCopied records that the first count reflections of the user's
+	// legacy array are in the collection (or conflict with it).
+	MarkCopied(ctx context.Context, userID primitive.ObjectID, count int) error
+	// Copy upserts reflections by ID. Ones clashing with another reflection
+	// of the same user and day are skipped and counted in conflicts.
+	Copy(ctx context.Context, reflections []Reflection) (copied, conflicts int64, err error)
+	// PruneEmbedded removes from the user's legacy array the reflections
+	// already in the collection.
+	PruneEmbedded(ctx context.Context, userID primitive.ObjectID) (int64, error)
+	// DistinctZones lists the barometer values stored in the collection and
+	// in legacy arrays.
+	DistinctZones(ctx context.Context) ([]string, error)
+	// RenameZone rewrites a stored barometer value in both places.
+	RenameZone(ctx context.Context, from string, to BarometerZone) (int64, error)
+	// BackfillSearchText sets search_text on reflections written before
+	// search existed.
+	BackfillSearchText(ctx context.Context) (int64, error)
+}
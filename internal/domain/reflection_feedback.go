@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrFeedbackNotFound = errors.New("feedback entry not found")
+var ErrFeedbackNotAuthor = errors.New("only the author can change this feedback entry")
+var ErrFeedbackEmpty = errors.New("feedback cannot be empty")
+var ErrFeedbackTooLong = errors.New("feedback is too long")
+
+// FeedbackEntry is one message in the feedback thread of a reflection, from
+// a staff member or the learner who wrote it.
+type FeedbackEntry struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"_id"`
+	ReflectionID primitive.ObjectID `bson:"reflection_id" json:"reflection_id"`
+	// LearnerID is the reflection's author.
+	LearnerID  primitive.ObjectID `bson:"learner_id" json:"learner_id"`
+	AuthorID   primitive.ObjectID `bson:"author_id,omitempty" json:"author_id,omitempty"`
+	AuthorName string             `bson:"author_name" json:"author_name"`
+	// AuthorRole is the author's role when writing, e.g. "coach" or "user".
+	AuthorRole string     `bson:"author_role" json:"author_role"`
+	Body       string     `bson:"body" json:"body"`
+	CreatedAt  time.Time  `bson:"created_at" json:"created_at"`
+	EditedAt   *time.Time `bson:"edited_at,omitempty" json:"edited_at,omitempty"`
+}
+
+// FromLearner reports whether the reflection's author wrote the entry.
+func (e *FeedbackEntry) FromLearner() bool {
+	return e.AuthorID == e.LearnerID
+}
+
+// HasUnreadFeedback reports whether staff replied since the learner last
+// opened the thread.
+func (r *Reflection) HasUnreadFeedback() bool {
+	if r.LastStaffReplyAt == nil {
+		return false
+	}
+	return r.FeedbackReadAt == nil || r.LastStaffReplyAt.After(*r.FeedbackReadAt)
+}
+
+type FeedbackRepository interface {
+	Insert(ctx context.Context, e *FeedbackEntry) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*FeedbackEntry, error)
+	// FindByReflection returns the thread oldest first.
+	FindByReflection(ctx context.Context, reflectionID primitive.ObjectID) ([]FeedbackEntry, error)
+	UpdateBody(ctx context.Context, id primitive.ObjectID, body string, at time.Time) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	// InsertLegacy stores e unless an entry with its ID already exists, and
+	// reports whether it did.
+	InsertLegacy(ctx context.Context, e *FeedbackEntry) (bool, error)
+}
@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"errors"
+	"html"
+	"strings"
+	"unicode"
+)
+
+var ErrEmptySearch = errors.New("search text has no words to look for")
+
+// SearchTerms splits text into the terms the reflection search index holds.
+// Words in scripts that separate them with spaces are lowercased as they
+// are. Thai is written without spaces and MongoDB's text index can't split
+// it, so each run of Thai becomes its overlapping pairs of characters (with
+// their vowel and tone marks): a Thai word or phrase is found when all of its
+// pairs are. Text is unescaped first, as reflections are stored HTML-escaped.
+func SearchTerms(text string) []string {
+	var terms []string
+	var word []rune
+	var thai []string
+	flush := func() {
+		if len(word) > 0 {
+			terms = append(terms, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+		switch {
+		case len(thai) == 1:
+			terms = append(terms, thai[0])
+		case len(thai) > 1:
+			for i := 0; i+1 < len(thai); i++ {
+				terms = append(terms, thai[i]+thai[i+1])
+			}
+		}
+		thai = thai[:0]
+	}
+
+	for _, r := range html.UnescapeString(text) {
+		switch {
+		case unicode.Is(unicode.Thai, r):
+			if len(word) > 0 {
+				flush()
+			}
+			// Marks above and below join the character before them.
+			if unicode.Is(unicode.Mn, r) && len(thai) > 0 {
+				thai[len(thai)-1] += string(r)
+			} else {
+				thai = append(thai, string(r))
+			}
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r):
+			if len(thai) > 0 {
+				flush()
+			}
+			word = append(word, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return terms
+}
+
+// BuildSearchText returns what the search index stores for r: the terms of
+// its session names and what went well and what to improve, space-separated.
+func (r *Reflection) BuildSearchText() string {
+	var terms []string
+	for _, s := range []SessionDetails{r.ReflectionData.TechSessions, r.ReflectionData.NonTechSessions} {
+		for _, name := range s.SessionName {
+			terms = append(terms, SearchTerms(name)...)
+		}
+		terms = append(terms, SearchTerms(s.Happy)...)
+		terms = append(terms, SearchTerms(s.Improve)...)
+	}
+	return strings.Join(terms, " ")
+}
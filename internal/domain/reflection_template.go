@@ -0,0 +1,216 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrTemplateNotFound = errors.New("reflection template not found")
+var ErrInvalidTemplate = errors.New("invalid reflection template")
+var ErrInvalidAnswers = errors.New("invalid reflection answers")
+
+// QuestionType is how a template question is answered.
+type QuestionType string
+
+const (
+	QuestionText        QuestionType = "text"
+	QuestionScale       QuestionType = "scale"
+	QuestionMultiSelect QuestionType = "multi_select"
+	// QuestionBarometer picks one of BarometerZones. Every template has
+	// exactly one, so the barometer reports work for all cohorts.
+	QuestionBarometer QuestionType = "barometer"
+)
+
+func (t QuestionType) Valid() bool {
+	switch t {
+	case QuestionText, QuestionScale, QuestionMultiSelect, QuestionBarometer:
+		return true
+	}
+	return false
+}
+
+// maxAnswerLength caps text answers, in characters.
+const maxAnswerLength = 5000
+
+type TemplateQuestion struct {
+	// ID identifies the question within the template, e.g. "tech_happy".
+	ID       string       `bson:"id" json:"id"`
+	Type     QuestionType `bson:"type" json:"type"`
+	Prompt   string       `bson:"prompt" json:"prompt"`
+	Required bool         `bson:"required" json:"required"`
+	// Options are the choices of a multi_select question.
+	Options []string `bson:"options,omitempty" json:"options,omitempty"`
+	// Min and Max bound a scale question.
+	Min int `bson:"min,omitempty" json:"min,omitempty"`
+	Max int `bson:"max,omitempty" json:"max,omitempty"`
+}
+
+// ReflectionTemplate is the set of questions a cohort's learners answer in
+// their daily reflection. Saving a template adds a new version; at most one
+// version per cohort is active. Cohorts without an active template use the
+// standard form (ReflectionContent).
+type ReflectionTemplate struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"_id"`
+	CohortNumber int                `bson:"cohort_number" json:"cohort_number"`
+	Version      int                `bson:"version" json:"version"`
+	Active       bool               `bson:"active" json:"active"`
+	Questions    []TemplateQuestion `bson:"questions" json:"questions"`
+	CreatedBy    primitive.ObjectID `bson:"created_by,omitempty" json:"created_by,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// ReflectionAnswer is the answer to one template question. Text holds text
+// and barometer answers, Value a scale answer and Choices a multi_select one.
+type ReflectionAnswer struct {
+	QuestionID string   `bson:"question_id" json:"question_id"`
+	Text       string   `bson:"text,omitempty" json:"text,omitempty"`
+	Value      *int     `bson:"value,omitempty" json:"value,omitempty"`
+	Choices    []string `bson:"choices,omitempty" json:"choices,omitempty"`
+}
+
+// Check reports whether the questions make a usable template.
+func (t *ReflectionTemplate) Check() error {
+	if len(t.Questions) == 0 {
+		return fmt.Errorf("%w: no questions", ErrInvalidTemplate)
+	}
+	seen := map[string]bool{}
+	barometers := 0
+	for i := range t.Questions {
+		q := &t.Questions[i]
+		q.ID = strings.TrimSpace(q.ID)
+		q.Prompt = strings.TrimSpace(q.Prompt)
+		switch {
+		case q.ID == "":
+			return fmt.Errorf("%w: question %d has no id", ErrInvalidTemplate, i+1)
+		case seen[q.ID]:
+			return fmt.Errorf("%w: duplicate question id %q", ErrInvalidTemplate, q.ID)
+		case q.Prompt == "":
+			return fmt.Errorf("%w: question %q has no prompt", ErrInvalidTemplate, q.ID)
+		case !q.Type.Valid():
+			return fmt.Errorf("%w: question %q has unknown type %q", ErrInvalidTemplate, q.ID, q.Type)
+		}
+		seen[q.ID] = true
+
+		switch q.Type {
+		case QuestionScale:
+			if q.Min >= q.Max {
+				return fmt.Errorf("%w: scale question %q needs min below max", ErrInvalidTemplate, q.ID)
+			}
+		case QuestionMultiSelect:
+			if len(q.Options) == 0 {
+				return fmt.Errorf("%w: question %q has no options", ErrInvalidTemplate, q.ID)
+			}
+		case QuestionBarometer:
+			barometers++
+			q.Required = true
+		}
+		if q.Type != QuestionMultiSelect {
+			q.Options = nil
+		}
+		if q.Type != QuestionScale {
+			q.Min, q.Max = 0, 0
+		}
+	}
+	if barometers != 1 {
+		return fmt.Errorf("%w: need exactly one barometer question", ErrInvalidTemplate)
+	}
+	return nil
+}
+
+// Validate checks answers against the template and returns them in question
+// order, trimmed and with the barometer zone canonicalised. Empty
+// answers to optional questions are dropped.
+func (t *ReflectionTemplate) Validate(answers []ReflectionAnswer) ([]ReflectionAnswer, BarometerZone, error) {
+	byID := make(map[string]ReflectionAnswer, len(answers))
+	for _, a := range answers {
+		if _, dup := byID[a.QuestionID]; dup {
+			return nil, "", fmt.Errorf("%w: question %q answered twice", ErrInvalidAnswers, a.QuestionID)
+		}
+		byID[a.QuestionID] = a
+	}
+
+	var zone BarometerZone
+	valid := make([]ReflectionAnswer, 0, len(t.Questions))
+	for _, q := range t.Questions {
+		a, ok := byID[q.ID]
+		delete(byID, q.ID)
+		out := ReflectionAnswer{QuestionID: q.ID}
+
+		switch q.Type {
+		case QuestionText:
+			text := strings.TrimSpace(a.Text)
+			if len([]rune(text)) > maxAnswerLength {
+				return nil, "", fmt.Errorf("%w: answer to %q is too long", ErrInvalidAnswers, q.ID)
+			}
+			out.Text = text
+			ok = text != ""
+		case QuestionScale:
+			if a.Value != nil && (*a.Value < q.Min || *a.Value > q.Max) {
+				return nil, "", fmt.Errorf("%w: answer to %q must be between %d and %d", ErrInvalidAnswers, q.ID, q.Min, q.Max)
+			}
+			out.Value = a.Value
+			ok = a.Value != nil
+		case QuestionMultiSelect:
+			for _, choice := range a.Choices {
+				if !containsString(q.Options, choice) {
+					return nil, "", fmt.Errorf("%w: %q is not an option of %q", ErrInvalidAnswers, choice, q.ID)
+				}
+				if !containsString(out.Choices, choice) {
+					out.Choices = append(out.Choices, choice)
+				}
+			}
+			ok = len(out.Choices) > 0
+		case QuestionBarometer:
+			if ok = a.Text != ""; ok {
+				if zone, ok = ParseBarometerZone(a.Text); !ok {
+					return nil, "", ErrInvalidBarometerZone
+				}
+				out.Text = string(zone)
+			}
+		}
+
+		if !ok {
+			if q.Required {
+				if q.Type == QuestionBarometer {
+					return nil, "", ErrInvalidBarometerZone
+				}
+				return nil, "", fmt.Errorf("%w: %q is required", ErrInvalidAnswers, q.ID)
+			}
+			continue
+		}
+		valid = append(valid, out)
+	}
+
+	for id := range byID {
+		return nil, "", fmt.Errorf("%w: unknown question %q", ErrInvalidAnswers, id)
+	}
+	return valid, zone, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+type ReflectionTemplateRepository interface {
+	// Insert stores t as the cohort's next version.
+	Insert(ctx context.Context, t *ReflectionTemplate) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*ReflectionTemplate, error)
+	// FindByCohort lists a cohort's versions, newest first.
+	FindByCohort(ctx context.Context, cohort int) ([]ReflectionTemplate, error)
+	// FindActive returns ErrTemplateNotFound if the cohort uses the standard
+	// form.
+	FindActive(ctx context.Context, cohort int) (*ReflectionTemplate, error)
+	// Activate makes id the cohort's only active version; a zero id
+	// deactivates them all.
+	Activate(ctx context.Context, cohort int, id primitive.ObjectID) error
+}
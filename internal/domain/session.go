@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrInvalidRefreshToken = errors.New("refresh token is invalid or expired")
+var ErrUserDisabled = errors.New("user account is disabled")
+var ErrImpersonationNotAllowed = errors.New("only learner accounts can be impersonated")
+
+// RefreshToken is one link in a rotating refresh chain. Only the SHA-256 of
+// the raw token is stored. Every token minted from the same login shares a
+// FamilyID so presenting an already-rotated token can revoke the whole chain.
+type RefreshToken struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"_id"`
+	UserID     primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	TokenHash  string              `bson:"token_hash" json:"-"`
+	FamilyID   primitive.ObjectID  `bson:"family_id" json:"family_id"`
+	UserAgent  string              `bson:"user_agent,omitempty" json:"user_agent,omitempty"`
+	IP         string              `bson:"ip,omitempty" json:"ip,omitempty"`
+	ExpiresAt  time.Time           `bson:"expires_at" json:"expires_at"`
+	CreatedAt  time.Time           `bson:"created_at" json:"created_at"`
+	RevokedAt  *time.Time          `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	ReplacedBy *primitive.ObjectID `bson:"replaced_by,omitempty" json:"replaced_by,omitempty"`
+}
+
+// TokenPair is what login and refresh hand back to the client.
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int    `json:"expiresIn"`
+}
+
+type RefreshTokenRepository interface {
+	Insert(ctx context.Context, token *RefreshToken) error
+	FindByHash(ctx context.Context, hash string) (*RefreshToken, error)
+	// Rotate revokes id only if it is still live, so two concurrent refreshes
+	// with the same token cannot both succeed.
+	Rotate(ctx context.Context, id, replacedBy primitive.ObjectID) (bool, error)
+	RevokeByHash(ctx context.Context, hash string) error
+	RevokeFamily(ctx context.Context, familyID primitive.ObjectID) error
+	RevokeAllForUser(ctx context.Context, userID primitive.ObjectID) (int64, error)
+}
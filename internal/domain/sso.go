@@ -0,0 +1,9 @@
+package domain
+
+import "errors"
+
+var ErrSSODisabled = errors.New("single sign-on is not configured")
+var ErrSSOInvalidState = errors.New("sign-in request expired or was tampered with")
+var ErrSSOInvalidToken = errors.New("identity provider returned an invalid token")
+var ErrSSOEmailUnverified = errors.New("email address is not verified by the identity provider")
+var ErrSSODomainNotAllowed = errors.New("email domain is not allowed to sign in")
@@ -0,0 +1,18 @@
+package domain
+
+import "errors"
+
+var ErrInvalidTOTPCode = errors.New("invalid two-factor code")
+var ErrTOTPNotEnrolled = errors.New("two-factor authentication is not set up")
+var ErrTOTPAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+var ErrTOTPRequired = errors.New("two-factor authentication is required for this account")
+
+// NeedsTOTPSetup reports whether the user must enroll in two-factor
+// authentication before using anything else: an admin required it for them,
+// or it is enforced for every admin account.
+func (s UserAuthState) NeedsTOTPSetup(enforceForAdmins bool) bool {
+	if s.TOTPEnabled {
+		return false
+	}
+	return s.TOTPRequired || (enforceForAdmins && s.Role == "admin")
+}
@@ -37,10 +37,41 @@ type Reflection struct {
 	ID             primitive.ObjectID `bson:"_id,omitempty" json:"_id"`
 	Day            string             `bson:"day" json:"day"`
 	UserID         primitive.ObjectID `bson:"user_id" json:"user_id"`
+	CohortNumber   int                `bson:"cohort_number,omitempty" json:"cohort_number,omitempty"`
 	Date           time.Time          `bson:"date" json:"date"`
 	CreatedAt      time.Time          `bson:"createdAt" json:"createdAt"`
 	ReflectionData ReflectionContent  `bson:"reflection" json:"reflection"`
-	AdminFeedback  string             `bson:"admin_feedback,omitempty" json:"admin_feedback,omitempty"`
+	// Reflections written against a cohort's template carry its answers;
+	// ReflectionData then only holds the barometer.
+	TemplateID      primitive.ObjectID `bson:"template_id,omitempty" json:"template_id,omitempty"`
+	TemplateVersion int                `bson:"template_version,omitempty" json:"template_version,omitempty"`
+	Answers         []ReflectionAnswer `bson:"answers,omitempty" json:"answers,omitempty"`
+	// AdminFeedback is the single feedback string reflections had before
+	// feedback threads; MigrateFeedback moves it into the thread.
+	AdminFeedback string `bson:"admin_feedback,omitempty" json:"admin_feedback,omitempty"`
+	// LastStaffReplyAt and FeedbackReadAt drive the learner's unread
+	// indicator (see HasUnreadFeedback).
+	LastStaffReplyAt *time.Time `bson:"last_staff_reply_at,omitempty" json:"last_staff_reply_at,omitempty"`
+	FeedbackReadAt   *time.Time `bson:"feedback_read_at,omitempty" json:"feedback_read_at,omitempty"`
+	UnreadFeedback   bool       `bson:"-" json:"unread_feedback,omitempty"`
+	// UpdatedAt is set when the learner edits the reflection; Revisions
+	// holds what it said before each edit, oldest first.
+	UpdatedAt *time.Time           `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	Revisions []ReflectionRevision `bson:"revisions,omitempty" json:"revisions,omitempty"`
+	// EditedAfterFeedback flags reflections changed after a coach replied,
+	// so the feedback may no longer match what is shown.
+	EditedAfterFeedback bool `bson:"edited_after_feedback,omitempty" json:"edited_after_feedback,omitempty"`
+	// SearchText holds BuildSearchText for the text index; the repository
+	// keeps it current.
+	SearchText string `bson:"search_text" json:"-"`
+}
+
+// ReflectionRevision is a reflection's content before an edit.
+type ReflectionRevision struct {
+	ReflectionData ReflectionContent  `bson:"reflection" json:"reflection"`
+	Answers        []ReflectionAnswer `bson:"answers,omitempty" json:"answers,omitempty"`
+	// ReplacedAt is when the edit that superseded this content was made.
+	ReplacedAt time.Time `bson:"replaced_at" json:"replaced_at"`
 }
 
 func (r Reflection) MarshalJSON() ([]byte, error) {
@@ -60,7 +91,7 @@ func (r Reflection) MarshalJSON() ([]byte, error) {
 type ReflectionContent struct {
 	TechSessions    SessionDetails `bson:"tech_sessions" json:"tech_sessions"`
 	NonTechSessions SessionDetails `bson:"non_tech_sessions" json:"non_tech_sessions"`
-	Barometer       string         `bson:"barometer" json:"barometer"`
+	Barometer       BarometerZone  `bson:"barometer" json:"barometer"`
 }
 
 type SessionDetails struct {
@@ -88,15 +119,22 @@ type User struct {
 	LastName         string             `bson:"last_name" json:"last_name"`
 	Email            string             `bson:"email" json:"email"`
 	CohortNumber     int                `bson:"cohort_number" json:"cohort_number"`
-	Reflections      []Reflection       `bson:"reflections" json:"reflections"`
+	// Reflections is the legacy embedded array; reflections now live in their
+	// own collection (see ReflectionRepository).
+	Reflections      []Reflection       `bson:"reflections,omitempty" json:"reflections"`
 	Password         string             `bson:"password,omitempty" json:"password,omitempty"`
 	Role             string             `bson:"role" json:"role"`
 	ProjectGroup     string             `bson:"project_group" json:"project_group"`
 	GenmateGroup     string             `bson:"genmate_group" json:"genmate_group"`
+	// ProjectGroupID and GenmateGroupID point into groups; the strings above
+	// mirror the group names.
+	ProjectGroupID   *primitive.ObjectID `bson:"project_group_id,omitempty" json:"project_group_id,omitempty"`
+	GenmateGroupID   *primitive.ObjectID `bson:"genmate_group_id,omitempty" json:"genmate_group_id,omitempty"`
 	ZoomName         string             `bson:"zoom_name" json:"zoom_name"`
 	Badges           []Badge            `bson:"badges,omitempty" json:"badges,omitempty"`
 	SalesforceID     string             `bson:"salesforce_id,omitempty" json:"salesforce_id,omitempty"`
 	AttendanceStatus string             `bson:"attendance_status,omitempty" json:"attendance_status,omitempty"`
+	StatusHistory    []StatusTransition `bson:"status_history,omitempty" json:"status_history,omitempty"`
 	ProfileComments  []ProfileComment   `bson:"profile_comments,omitempty" json:"profile_comments,omitempty"`
 	ProfileReactions []Reaction         `bson:"profile_reactions,omitempty" json:"profile_reactions,omitempty"`
 	PlantReactions   []Reaction         `bson:"plant_reactions,omitempty" json:"plant_reactions,omitempty"`
@@ -111,9 +149,42 @@ type User struct {
 	SelectedStem     string             `bson:"selected_stem,omitempty" json:"selected_stem,omitempty"`
 	Deleted          bool               `bson:"deleted,omitempty" json:"deleted,omitempty"`
 	DeletedAt        *time.Time        `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	Disabled         bool               `bson:"disabled,omitempty" json:"disabled,omitempty"`
 	FertilizerBalance int                  `bson:"fertilizer_balance,omitempty" json:"fertilizer_balance,omitempty"`
 	GrowthPoints      int                  `bson:"growth_points,omitempty" json:"growth_points,omitempty"`
 	FertilizerLog     []FertilizerLogEntry `bson:"fertilizer_log,omitempty" json:"fertilizer_log,omitempty"`
+	TokenVersion      int                  `bson:"token_version,omitempty" json:"-"`
+	MustChangePassword bool                `bson:"must_change_password,omitempty" json:"must_change_password,omitempty"`
+	// StaffCohorts are the cohorts a coach or TA is assigned to manage.
+	StaffCohorts []int `bson:"staff_cohorts,omitempty" json:"staff_cohorts,omitempty"`
+	// Two-factor authentication (RFC 6238 TOTP). The pending secret holds an
+	// enrollment until the first code confirms it; recovery codes are hashed
+	// and single-use; TOTPLastStep stops a code being replayed.
+	TOTPEnabled       bool     `bson:"totp_enabled,omitempty" json:"totp_enabled,omitempty"`
+	TOTPRequired      bool     `bson:"totp_required,omitempty" json:"totp_required,omitempty"`
+	TOTPSecret        string   `bson:"totp_secret,omitempty" json:"-"`
+	TOTPPendingSecret string   `bson:"totp_pending_secret,omitempty" json:"-"`
+	TOTPLastStep      int64    `bson:"totp_last_step,omitempty" json:"-"`
+	RecoveryCodes     []string `bson:"recovery_codes,omitempty" json:"-"`
+	// ErasedAt is set once a PDPA erasure has anonymised the account.
+	ErasedAt *time.Time `bson:"erased_at,omitempty" json:"erased_at,omitempty"`
+}
+
+// UserAuthState is the slice of a user document the auth middleware needs on
+// every request, loaded with a projection so reflections never come along.
+type UserAuthState struct {
+	ID           primitive.ObjectID `bson:"_id"`
+	Role         string             `bson:"role"`
+	CohortNumber int                `bson:"cohort_number"`
+	Deleted      bool               `bson:"deleted,omitempty"`
+	Disabled     bool               `bson:"disabled,omitempty"`
+	TokenVersion int                `bson:"token_version,omitempty"`
+	StaffCohorts []int              `bson:"staff_cohorts,omitempty"`
+	// MustChangePassword is set on accounts created with an admin-issued
+	// password until the learner picks their own.
+	MustChangePassword bool `bson:"must_change_password,omitempty"`
+	TOTPEnabled        bool `bson:"totp_enabled,omitempty"`
+	TOTPRequired       bool `bson:"totp_required,omitempty"`
 }
 
 // UserSafe is a restricted version of User for non-admin users
@@ -126,6 +197,8 @@ type UserSafe struct {
 	CohortNumber  int                  `json:"cohort_number"`
 	ProjectGroup  string               `json:"project_group"`
 	GenmateGroup  string               `json:"genmate_group"`
+	ProjectGroupID *primitive.ObjectID `json:"project_group_id,omitempty"`
+	GenmateGroupID *primitive.ObjectID `json:"genmate_group_id,omitempty"`
 	ZoomName      string               `json:"zoom_name"`
 	Badges        []Badge              `json:"badges,omitempty"`
 	Bio           string               `json:"bio,omitempty"`
@@ -155,6 +228,8 @@ func (u *User) ToSafe() UserSafe {
 		CohortNumber:  u.CohortNumber,
 		ProjectGroup:  u.ProjectGroup,
 		GenmateGroup:  u.GenmateGroup,
+		ProjectGroupID: u.ProjectGroupID,
+		GenmateGroupID: u.GenmateGroupID,
 		ZoomName:      u.ZoomName,
 		Badges:          u.Badges,
 		Bio:             u.Bio,
@@ -208,13 +283,13 @@ type WeeklySummary struct {
 }
 
 type StudentInfo struct {
-	UserID    string    `bson:"user_id" json:"user_id"`
-	FirstName string    `bson:"first_name" json:"first_name"`
-	LastName  string    `bson:"last_name" json:"last_name"`
-	ZoomName  string    `bson:"zoom_name" json:"zoom_name"`
-	JsdNumber string    `bson:"jsd_number" json:"jsd_number"`
-	Barometer string    `bson:"barometer" json:"barometer"`
-	Date      time.Time `bson:"date" json:"date"`
+	UserID    string        `bson:"user_id" json:"user_id"`
+	FirstName string        `bson:"first_name" json:"first_name"`
+	LastName  string        `bson:"last_name" json:"last_name"`
+	ZoomName  string        `bson:"zoom_name" json:"zoom_name"`
+	JsdNumber string        `bson:"jsd_number" json:"jsd_number"`
+	Barometer BarometerZone `bson:"barometer" json:"barometer"`
+	Date      time.Time     `bson:"date" json:"date"`
 }
 
 type UserFilter struct {
@@ -223,20 +298,42 @@ type UserFilter struct {
 	Email                   string
 	Search                  string
 	ExcludeAttendanceStatus string // Comma-separated statuses to exclude, e.g., "dropout,dismissed"
+	GroupID                 primitive.ObjectID // Members of this project or genmate group
+	IDs                     []primitive.ObjectID // Only these users, when non-nil
+	StaffCohort             int                  // Coaches and TAs assigned to this cohort
 }
 
 type UserRepository interface {
 	FindByID(ctx interface{}, id primitive.ObjectID) (*User, error)
 	FindByEmail(ctx interface{}, email string) (*User, error)
+	// FindByEmailsOrJSDNumbers returns the users holding any of the given
+	// emails or JSD numbers, including deleted ones.
+	FindByEmailsOrJSDNumbers(ctx interface{}, emails, jsdNumbers []string) ([]User, error)
+	FindAuthState(ctx interface{}, id primitive.ObjectID) (*UserAuthState, error)
 	FindAll(ctx interface{}, filter UserFilter, opts interface{}) ([]User, int, error)
+	// FindRoster is FindAll loading only identity, cohort, group and learner
+	// status fields, for attendance overviews, stats and exports.
+	FindRoster(ctx interface{}, filter UserFilter, opts interface{}) ([]User, int, error)
+	// FindSummaries is FindRoster plus profile and plant fields, with the
+	// fertilizer log cut to its kind and related date. Profile comments and
+	// reactions are left out, except plant reactions.
+	FindSummaries(ctx interface{}, filter UserFilter, opts interface{}) ([]User, int, error)
 	Create(ctx interface{}, user *User) error
 	Update(ctx interface{}, id primitive.ObjectID, update interface{}) error
+	IncrementTokenVersion(ctx interface{}, id primitive.ObjectID) error
+	// ConsumeTOTPStep records step as used, failing if it (or a later one)
+	// already was.
+	ConsumeTOTPStep(ctx interface{}, id primitive.ObjectID, step int64) (bool, error)
+	// ConsumeRecoveryCode removes a recovery code hash, reporting whether it
+	// was there.
+	ConsumeRecoveryCode(ctx interface{}, id primitive.ObjectID, hash string) (bool, error)
 	AddBadge(ctx interface{}, userID primitive.ObjectID, badge Badge) error
 	GrantFertilizer(ctx interface{}, userID primitive.ObjectID, amount int, note, grantedBy string) error
+	// AddStatusTransition appends to status_history and stores the status in
+	// effect today as attendance_status ("" for active).
+	AddStatusTransition(ctx interface{}, userID primitive.ObjectID, transition StatusTransition, currentStatus string) error
 	UseFertilizerProtect(ctx interface{}, userID primitive.ObjectID, dateStr string) error
 	UseFertilizerFeed(ctx interface{}, userID primitive.ObjectID, quantity, points int) error
-	UpdateReflectionFeedback(ctx interface{}, userID, reflectionID primitive.ObjectID, feedback string) error
-	CreateReflection(ctx interface{}, userID primitive.ObjectID, reflection Reflection) error
 	AddProfileComment(ctx interface{}, userID primitive.ObjectID, comment ProfileComment) error
 	DeleteProfileComment(ctx interface{}, userID primitive.ObjectID, commentID primitive.ObjectID) error
 	AddProfileReaction(ctx interface{}, userID primitive.ObjectID, reaction Reaction) error
@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrCaseNotFound = errors.New("wellbeing case not found")
+var ErrCaseClosed = errors.New("wellbeing case is already resolved")
+var ErrInvalidCaseStatus = errors.New("status must be open, contacted or resolved")
+var ErrInvalidCaseAssignee = errors.New("cases can only be assigned to staff")
+
+// WellbeingRule says which run of barometer readings opens a wellbeing case.
+// With Consecutive, the learner's last Count reflections must all be in
+// Zones (days they didn't reflect, like weekends, don't break the run);
+// otherwise any Count of them will do. Either way they must fall within
+// WithinDays calendar days, counting the latest one.
+type WellbeingRule struct {
+	ID          string          `json:"id"`
+	Description string          `json:"description"`
+	Zones       []BarometerZone `json:"zones"`
+	Count       int             `json:"count"`
+	Consecutive bool            `json:"consecutive"`
+	WithinDays  int             `json:"within_days"`
+}
+
+// DefaultWellbeingRules are used unless WELLBEING_RULES overrides them.
+var DefaultWellbeingRules = []WellbeingRule{
+	{
+		ID:          "panic_streak",
+		Description: "Panic Zone on two reflections in a row",
+		Zones:       []BarometerZone{ZonePanic},
+		Count:       2,
+		Consecutive: true,
+		// Friday then Monday still counts.
+		WithinDays: 4,
+	},
+	{
+		ID:          "overwhelmed_week",
+		Description: "Overwhelmed three times in a week",
+		Zones:       []BarometerZone{ZoneStretchOverwhelmed},
+		Count:       3,
+		WithinDays:  7,
+	},
+}
+
+type CaseStatus string
+
+const (
+	CaseOpen      CaseStatus = "open"
+	CaseContacted CaseStatus = "contacted"
+	CaseResolved  CaseStatus = "resolved"
+)
+
+func (s CaseStatus) Valid() bool {
+	return s == CaseOpen || s == CaseContacted || s == CaseResolved
+}
+
+// WellbeingCase is an alert about one learner, raised by a rule and followed
+// up by a coach. While a case is unresolved, further readings that match the
+// same rule are added to it instead of opening another.
+type WellbeingCase struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"_id"`
+	UserID       primitive.ObjectID `bson:"user_id" json:"user_id"`
+	UserName     string             `bson:"user_name" json:"user_name"`
+	CohortNumber int                `bson:"cohort_number" json:"cohort_number"`
+	RuleID       string             `bson:"rule_id" json:"rule_id"`
+	Reason       string             `bson:"reason" json:"reason"`
+	// ReflectionIDs are the reflections that matched the rule.
+	ReflectionIDs []primitive.ObjectID `bson:"reflection_ids" json:"reflection_ids"`
+	Status        CaseStatus           `bson:"status" json:"status"`
+	// Active is true until the case is resolved; at most one active case
+	// exists per learner and rule.
+	Active       bool               `bson:"active" json:"-"`
+	AssignedTo   primitive.ObjectID `bson:"assigned_to,omitempty" json:"assigned_to,omitempty"`
+	AssignedName string             `bson:"assigned_name,omitempty" json:"assigned_name,omitempty"`
+	History      []CaseEvent        `bson:"history" json:"history"`
+	OpenedAt     time.Time          `bson:"opened_at" json:"opened_at"`
+	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+	ResolvedAt   *time.Time         `bson:"resolved_at,omitempty" json:"resolved_at,omitempty"`
+}
+
+// CaseEvent is one step in a case: opened, a new matching reading, a status
+// change or a reassignment.
+type CaseEvent struct {
+	Status CaseStatus         `bson:"status" json:"status"`
+	Note   string             `bson:"note,omitempty" json:"note,omitempty"`
+	By     primitive.ObjectID `bson:"by,omitempty" json:"by,omitempty"`
+	At     time.Time          `bson:"at" json:"at"`
+}
+
+type WellbeingCaseFilter struct {
+	Cohort     int
+	Status     CaseStatus
+	AssignedTo primitive.ObjectID
+	UserID     primitive.ObjectID
+}
+
+type WellbeingCaseRepository interface {
+	// Open inserts c, or, if the learner already has an active case for the
+	// rule, adds c's reflections and history to it. It reports whether a new
+	// case was opened.
+	Open(ctx context.Context, c *WellbeingCase) (bool, error)
+	FindByID(ctx context.Context, id primitive.ObjectID) (*WellbeingCase, error)
+	// FindAll returns matching cases, open and most recently updated first.
+	FindAll(ctx context.Context, filter WellbeingCaseFilter, skip, limit int64) ([]WellbeingCase, int64, error)
+	// Update saves status, assignment and resolution fields and appends event.
+	Update(ctx context.Context, c *WellbeingCase, event CaseEvent) error
+}
@@ -1,7 +1,14 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
+	"log"
+
+	"gofiber-baro/internal/domain"
+	"gofiber-baro/internal/service/group"
 	"gofiber-baro/internal/service/reflection"
+	"gofiber-baro/internal/service/session"
 	"gofiber-baro/internal/service/user"
 	"gofiber-baro/pkg/middleware"
 	"gofiber-baro/pkg/utils"
@@ -16,6 +23,11 @@ type AdminHandler struct {
 	fertilizerService *user.FertilizerService
 	reflectionService *reflection.Service
 	barometerService  *reflection.BarometerService
+	transferService   *user.TransferService
+	sessionService    *session.Service
+	loginGuard        *session.LoginGuard
+	totpService       *user.TOTPService
+	groupService      *group.Service
 }
 
 func NewAdminHandler(
@@ -24,6 +36,11 @@ func NewAdminHandler(
 	fertilizerService *user.FertilizerService,
 	reflectionService *reflection.Service,
 	barometerService *reflection.BarometerService,
+	transferService *user.TransferService,
+	sessionService *session.Service,
+	loginGuard *session.LoginGuard,
+	totpService *user.TOTPService,
+	groupService *group.Service,
 ) *AdminHandler {
 	return &AdminHandler{
 		userService:       userService,
@@ -31,6 +48,11 @@ func NewAdminHandler(
 		fertilizerService: fertilizerService,
 		reflectionService: reflectionService,
 		barometerService:  barometerService,
+		transferService:   transferService,
+		sessionService:    sessionService,
+		loginGuard:        loginGuard,
+		totpService:       totpService,
+		groupService:      groupService,
 	}
 }
 
@@ -77,7 +99,7 @@ func (h *AdminHandler) GetUserWithReflections(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusBadRequest, "User ID is required")
 	}
 
-	user, err := h.userService.GetUserByID(id)
+	user, err := h.userService.GetUserWithReflections(id)
 	if err != nil {
 		return utils.SendError(c, fiber.StatusNotFound, "User not found")
 	}
@@ -127,6 +149,9 @@ func (h *AdminHandler) AwardBadge(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error awarding badge")
 	}
 
+	entry := auditUser(c, "AWARD_BADGE", userID, fmt.Sprintf("Awarded badge %q", body.Name))
+	entry.After = map[string]interface{}{"type": body.Type, "name": body.Name}
+
 	return utils.SendResponse(c, fiber.StatusOK, "Badge awarded successfully", nil)
 }
 
@@ -171,6 +196,11 @@ func (h *AdminHandler) BulkAwardBadge(c *fiber.Ctx) error {
 		successCount++
 	}
 
+	entry := auditEntry(c)
+	entry.Action = "BULK_AWARD_BADGE"
+	entry.Details = fmt.Sprintf("Awarded badge %q to %d users (%d failed)", body.Name, successCount, failCount)
+	entry.After = map[string]interface{}{"user_ids": body.UserIDs, "type": body.Type, "name": body.Name}
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"success":      true,
 		"message":      "Bulk badge award completed",
@@ -215,6 +245,9 @@ func (h *AdminHandler) GrantFertilizer(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error granting fertilizer")
 	}
 
+	entry := auditUser(c, "GRANT_FERTILIZER", userID, fmt.Sprintf("Granted %d fertilizer", body.Amount))
+	entry.After = map[string]interface{}{"amount": body.Amount, "note": body.Note}
+
 	return utils.SendResponse(c, fiber.StatusOK, "Fertilizer granted successfully", nil)
 }
 
@@ -261,6 +294,11 @@ func (h *AdminHandler) BulkGrantFertilizer(c *fiber.Ctx) error {
 		successCount++
 	}
 
+	entry := auditEntry(c)
+	entry.Action = "BULK_GRANT_FERTILIZER"
+	entry.Details = fmt.Sprintf("Granted %d fertilizer to %d users (%d failed)", body.Amount, successCount, failCount)
+	entry.After = map[string]interface{}{"user_ids": body.UserIDs, "amount": body.Amount, "note": body.Note}
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"success":      true,
 		"message":      "Bulk fertilizer grant completed",
@@ -274,7 +312,7 @@ func (h *AdminHandler) GetAllReflections(c *fiber.Ctx) error {
 	page := c.QueryInt("page", 1)
 	limit := c.QueryInt("limit", 20)
 
-	reflections, total, err := h.reflectionService.GetAllReflectionsWithUserInfo(page, limit)
+	reflections, total, err := h.reflectionService.GetAllReflectionsWithUserInfo(page, limit, c.QueryInt("cohort", 0))
 	if err != nil {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching reflections")
 	}
@@ -291,7 +329,7 @@ func (h *AdminHandler) GetAllReflectionsWithUserInfo(c *fiber.Ctx) error {
 	page := c.QueryInt("page", 1)
 	limit := c.QueryInt("limit", 20)
 
-	reflections, total, err := h.reflectionService.GetAllReflectionsWithUserInfo(page, limit)
+	reflections, total, err := h.reflectionService.GetAllReflectionsWithUserInfo(page, limit, c.QueryInt("cohort", 0))
 	if err != nil {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching reflections")
 	}
@@ -304,13 +342,191 @@ func (h *AdminHandler) GetAllReflectionsWithUserInfo(c *fiber.Ctx) error {
 	})
 }
 
-func (h *AdminHandler) GetUserBarometerData(c *fiber.Ctx) error {
-	users, _, err := h.userService.GetAllUsers(0, "", "", "", "", 0, 1, 500)
+// SearchReflections finds a cohort's reflections mentioning every word of q
+// in their session names or what went well and what to improve, with
+// highlighted snippets. from and to are inclusive YYYY-MM-DD days.
+// GET /admin/reflections/search?cohort=7&q=docker&from=2025-01-01&to=2025-01-31&zone=Panic%20Zone&group_id=...
+func (h *AdminHandler) SearchReflections(c *fiber.Ctx) error {
+	query := reflection.SearchQuery{
+		Text:   c.Query("q"),
+		Cohort: c.QueryInt("cohort", 0),
+		From:   c.Query("from"),
+		To:     c.Query("to"),
+		Page:   c.QueryInt("page", 1),
+		Limit:  c.QueryInt("limit", 20),
+	}
+	for _, day := range []string{query.From, query.To} {
+		if _, err := utils.ParseDate(day); day != "" && err != nil {
+			return utils.SendError(c, fiber.StatusBadRequest, "from and to must be YYYY-MM-DD")
+		}
+	}
+	if v := c.Query("zone"); v != "" {
+		zone, ok := domain.ParseBarometerZone(v)
+		if !ok {
+			return utils.SendError(c, fiber.StatusBadRequest, domain.ErrInvalidBarometerZone.Error())
+		}
+		query.Zone = zone
+	}
+	if v := c.Query("group_id"); v != "" {
+		oid, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			return utils.SendError(c, fiber.StatusBadRequest, "Invalid group_id")
+		}
+		query.GroupID = oid
+	}
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 || query.Limit > 100 {
+		query.Limit = 20
+	}
+
+	results, total, err := h.reflectionService.Search(query)
+	if err == domain.ErrEmptySearch {
+		return utils.SendError(c, fiber.StatusBadRequest, "q must contain a word to search for")
+	}
 	if err != nil {
-		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching users")
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error searching reflections")
+	}
+
+	return utils.SendResponse(c, fiber.StatusOK, "Reflections found", fiber.Map{
+		"results": results,
+		"total":   total,
+		"page":    query.Page,
+		"limit":   query.Limit,
+	})
+}
+
+// GetSessionAnalytics groups a cohort's reflections by session: how many
+// learners answered, how they felt on the days it ran and the words that
+// come up most in what went well and what to improve. session narrows it to
+// sessions whose name contains it; from and to are inclusive YYYY-MM-DD days.
+// GET /admin/reflections/sessions?cohort=7&from=2025-01-01&to=2025-01-31&session=sql
+func (h *AdminHandler) GetSessionAnalytics(c *fiber.Ctx) error {
+	query, err := sessionQuery(c)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, err.Error())
+	}
+	query.Session = c.Query("session")
+
+	sessions, err := h.reflectionService.Sessions(query)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching session analytics")
+	}
+	return utils.SendResponse(c, fiber.StatusOK, "Session analytics retrieved", fiber.Map{
+		"sessions": sessions,
+		"count":    len(sessions),
+	})
+}
+
+// GetSessionAnswers lists the individual answers for one session, matched by
+// its name in any spelling.
+// GET /admin/reflections/sessions/answers?cohort=7&session=SQL%20Joins&page=1&limit=50
+func (h *AdminHandler) GetSessionAnswers(c *fiber.Ctx) error {
+	query, err := sessionQuery(c)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, err.Error())
+	}
+	session := c.Query("session")
+	if domain.SessionKey(session) == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "session is required")
+	}
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 50)
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+
+	answers, total, err := h.reflectionService.SessionAnswers(query, session, page, limit)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching session answers")
+	}
+	return utils.SendResponse(c, fiber.StatusOK, "Session answers retrieved", fiber.Map{
+		"session": session,
+		"items":   answers,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+func sessionQuery(c *fiber.Ctx) (reflection.SessionQuery, error) {
+	query := reflection.SessionQuery{
+		Cohort: c.QueryInt("cohort", 0),
+		From:   c.Query("from"),
+		To:     c.Query("to"),
+	}
+	if query.Cohort <= 0 {
+		return query, errors.New("cohort is required")
+	}
+	for _, day := range []string{query.From, query.To} {
+		if _, err := utils.ParseDate(day); day != "" && err != nil {
+			return query, errors.New("from and to must be YYYY-MM-DD")
+		}
+	}
+	return query, nil
+}
+
+// ExportReflections downloads a cohort's reflections as CSV or XLSX, one row
+// per reflection. anonymize=true replaces names with "Learner 1", "Learner
+// 2"... and leaves out Zoom names and JSD numbers.
+// GET /admin/reflections/export?cohort=7&from=2025-01-01&to=2025-01-31&zone=Panic%20Zone&group_id=...&format=xlsx&anonymize=true
+func (h *AdminHandler) ExportReflections(c *fiber.Ctx) error {
+	req := reflection.ExportRequest{
+		Cohort:    c.QueryInt("cohort", 0),
+		From:      c.Query("from"),
+		To:        c.Query("to"),
+		Format:    c.Query("format", "csv"),
+		Anonymize: c.QueryBool("anonymize", false),
+	}
+	if req.Cohort <= 0 {
+		return utils.SendError(c, fiber.StatusBadRequest, "cohort is required")
+	}
+	for _, day := range []string{req.From, req.To} {
+		if _, err := utils.ParseDate(day); day != "" && err != nil {
+			return utils.SendError(c, fiber.StatusBadRequest, "from and to must be YYYY-MM-DD")
+		}
+	}
+	if req.Format != "csv" && req.Format != "xlsx" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid format. Use 'csv' or 'xlsx'")
+	}
+	if v := c.Query("zone"); v != "" {
+		zone, ok := domain.ParseBarometerZone(v)
+		if !ok {
+			return utils.SendError(c, fiber.StatusBadRequest, domain.ErrInvalidBarometerZone.Error())
+		}
+		req.Zone = zone
+	}
+	if v := c.Query("group_id"); v != "" {
+		oid, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			return utils.SendError(c, fiber.StatusBadRequest, "Invalid group_id")
+		}
+		req.GroupID = oid
+	}
+
+	data, ext, err := h.reflectionService.Export(req)
+	if err != nil {
+		log.Printf("[ERROR] ExportReflections: %v", err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error generating export")
+	}
+
+	filename := fmt.Sprintf("reflections_cohort%d_%s.%s", req.Cohort, utils.GetThailandDate(), ext)
+	switch ext {
+	case "xlsx":
+		c.Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	default:
+		c.Set("Content-Type", "text/csv; charset=utf-8")
 	}
+	c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	return c.Send(data)
+}
 
-	data, err := h.barometerService.GetUserBarometerData(users)
+func (h *AdminHandler) GetUserBarometerData(c *fiber.Ctx) error {
+	data, err := h.barometerService.GetUserBarometerData(c.QueryInt("cohort", 0))
 	if err != nil {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching barometer data")
 	}
@@ -331,7 +547,7 @@ func (h *AdminHandler) GetAllUsersBarometerData(c *fiber.Ctx) error {
 }
 
 func (h *AdminHandler) GetEmojiZoneTableData(c *fiber.Ctx) error {
-	users, _, err := h.userService.GetAllUsers(0, "", "", "", "", 0, 1, 500)
+	users, err := h.userService.GetRoster(0, "", 500)
 	if err != nil {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching users")
 	}
@@ -362,39 +578,6 @@ func (h *AdminHandler) GetWeeklySummary(c *fiber.Ctx) error {
 	})
 }
 
-func (h *AdminHandler) UpdateReflectionFeedback(c *fiber.Ctx) error {
-	type RequestBody struct {
-		UserID       string `json:"user_id"`
-		ReflectionID string `json:"reflection_id"`
-		Feedback     string `json:"feedback"`
-	}
-
-	var body RequestBody
-	if err := c.BodyParser(&body); err != nil {
-		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
-	}
-
-	if body.UserID == "" || body.ReflectionID == "" {
-		return utils.SendError(c, fiber.StatusBadRequest, "User ID and reflection ID are required")
-	}
-
-	userID, err := primitive.ObjectIDFromHex(body.UserID)
-	if err != nil {
-		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID")
-	}
-
-	reflectionID, err := primitive.ObjectIDFromHex(body.ReflectionID)
-	if err != nil {
-		return utils.SendError(c, fiber.StatusBadRequest, "Invalid reflection ID")
-	}
-
-	if err := h.userService.UpdateReflectionFeedback(userID, reflectionID, body.Feedback); err != nil {
-		return utils.SendError(c, fiber.StatusInternalServerError, "Error updating feedback")
-	}
-
-	return utils.SendResponse(c, fiber.StatusOK, "Feedback updated successfully", nil)
-}
-
 // ponytail: no CSV upload, no individual validation per field beyond required
 func (h *AdminHandler) BulkRegisterUsers(c *fiber.Ctx) error {
 	type UserEntry struct {
@@ -446,6 +629,10 @@ func (h *AdminHandler) BulkRegisterUsers(c *fiber.Ctx) error {
 	if err != nil {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Bulk registration failed")
 	}
+	// Turn the group names given for new users into group memberships.
+	if _, err := h.groupService.MigrateLegacyNames(); err != nil {
+		log.Printf("[ERROR] BulkRegisterUsers: link groups: %v", err)
+	}
 
 	successCount := 0
 	failCount := 0
@@ -457,6 +644,12 @@ func (h *AdminHandler) BulkRegisterUsers(c *fiber.Ctx) error {
 		}
 	}
 
+	entry := auditEntry(c)
+	entry.Action = "BULK_REGISTER_USERS"
+	entry.TargetType = "cohort"
+	entry.TargetName = fmt.Sprintf("Cohort %d", body.CohortNumber)
+	entry.Details = fmt.Sprintf("Registered %d users (%d failed)", successCount, failCount)
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"success":      true,
 		"message":      "Bulk registration completed",
@@ -472,9 +665,18 @@ func (h *AdminHandler) DeleteUser(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusBadRequest, "User ID is required")
 	}
 
+	target, err := h.userService.GetUserByID(id)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusNotFound, "User not found")
+	}
+
 	if err := h.userService.SoftDeleteUser(id); err != nil {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error deleting user")
 	}
+	_, _ = h.sessionService.LogoutAll(target.ID)
+
+	entry := auditUser(c, "DELETE_USER", target.ID, "Deleted "+target.Email)
+	entry.Before = map[string]interface{}{"email": target.Email, "role": target.Role, "cohort_number": target.CohortNumber}
 
 	return utils.SendResponse(c, fiber.StatusOK, "User deleted successfully", nil)
 }
@@ -522,6 +724,11 @@ func (h *AdminHandler) UpdatePlantOverride(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusBadRequest, "Unknown stem style")
 	}
 
+	target, err := h.userService.GetUserByID(id)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusNotFound, "User not found")
+	}
+
 	update := map[string]interface{}{
 		"selected_palette": body.Palette,
 		"selected_species": body.Species,
@@ -535,5 +742,333 @@ func (h *AdminHandler) UpdatePlantOverride(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error updating plant override")
 	}
 
+	entry := auditUser(c, "UPDATE_PLANT_OVERRIDE", target.ID, "Updated plant look")
+	entry.Before = map[string]interface{}{
+		"selected_palette": target.SelectedPalette,
+		"selected_species": target.SelectedSpecies,
+		"selected_pot":     target.SelectedPot,
+		"selected_leaf":    target.SelectedLeaf,
+		"selected_flower":  target.SelectedFlower,
+		"selected_stem":    target.SelectedStem,
+	}
+	entry.After = update
+
 	return utils.SendResponse(c, fiber.StatusOK, "Plant updated", nil)
 }
+
+// TransferCohort moves a learner to another cohort from an effective date,
+// re-tagging their attendance and leave from that date and revoking their
+// current tokens.
+// POST /admin/users/:id/cohort-transfer
+func (h *AdminHandler) TransferCohort(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	var body struct {
+		CohortNumber  int    `json:"cohort_number"`
+		EffectiveDate string `json:"effective_date"`
+		Reason        string `json:"reason"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	transferredBy := ""
+	if claims, ok := c.Locals("user").(*middleware.Claims); ok {
+		transferredBy = claims.UserID
+	}
+
+	result, err := h.transferService.Transfer(userID, body.CohortNumber, body.EffectiveDate, body.Reason, transferredBy)
+	if err != nil {
+		switch err {
+		case domain.ErrUserNotFound:
+			return utils.SendError(c, fiber.StatusNotFound, "User not found")
+		case domain.ErrInvalidCohort, domain.ErrSameCohort, domain.ErrInvalidEffectiveDate:
+			return utils.SendError(c, fiber.StatusBadRequest, err.Error())
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error transferring user")
+	}
+
+	entry := auditUser(c, "TRANSFER_COHORT", userID, fmt.Sprintf("Cohort %d → %d from %s", result.FromCohort, result.ToCohort, result.EffectiveDate))
+	entry.Before = map[string]interface{}{"cohort_number": result.FromCohort}
+	entry.After = map[string]interface{}{"cohort_number": result.ToCohort, "effective_date": result.EffectiveDate, "reason": body.Reason}
+
+	return utils.SendResponse(c, fiber.StatusOK, "User transferred", result)
+}
+
+// GetCohortHistory lists a learner's cohort memberships, oldest first.
+// GET /admin/users/:id/cohort-history
+func (h *AdminHandler) GetCohortHistory(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	history, err := h.transferService.History(userID)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching cohort history")
+	}
+
+	return utils.SendResponse(c, fiber.StatusOK, "Cohort history retrieved", history)
+}
+
+// RevokeUserSessions signs a user out of every device.
+// POST /admin/users/:id/revoke-sessions
+func (h *AdminHandler) RevokeUserSessions(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	revoked, err := h.sessionService.LogoutAll(userID)
+	if err != nil {
+		if err == domain.ErrUserNotFound {
+			return utils.SendError(c, fiber.StatusNotFound, "User not found")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error revoking sessions")
+	}
+
+	auditUser(c, "REVOKE_SESSIONS", userID, fmt.Sprintf("Revoked %d sessions", revoked))
+
+	return utils.SendResponse(c, fiber.StatusOK, "Sessions revoked", fiber.Map{"revoked": revoked})
+}
+
+// ImpersonateUser issues a read-only token for viewing the app as a learner,
+// e.g. to see the garden or board exactly as they report it. The token
+// expires after ImpersonationTokenTTL and every mutating request made with
+// it is rejected.
+// POST /admin/users/:id/impersonate
+func (h *AdminHandler) ImpersonateUser(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	token, err := h.sessionService.Impersonate(callerID(c), userID)
+	if err != nil {
+		switch err {
+		case domain.ErrUserNotFound:
+			return utils.SendError(c, fiber.StatusNotFound, "User not found")
+		case domain.ErrUserDisabled, domain.ErrImpersonationNotAllowed:
+			return utils.SendError(c, fiber.StatusConflict, err.Error())
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error issuing impersonation token")
+	}
+
+	expiresIn := int(utils.ImpersonationTokenTTL.Seconds())
+	auditUser(c, "IMPERSONATE_USER", userID, fmt.Sprintf("Started a %d-minute read-only view as this user", expiresIn/60))
+
+	return utils.SendResponse(c, fiber.StatusOK, "Impersonation token issued", fiber.Map{
+		"token":     token,
+		"expiresIn": expiresIn,
+		"readOnly":  true,
+	})
+}
+
+// SetUserDisabled blocks or restores a user's access. Disabling also revokes
+// their sessions.
+// PATCH /admin/users/:id/disabled  { "disabled": true }
+func (h *AdminHandler) SetUserDisabled(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	var body struct {
+		Disabled bool `json:"disabled"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	target, err := h.userService.GetUserByID(userID.Hex())
+	if err != nil {
+		return utils.SendError(c, fiber.StatusNotFound, "User not found")
+	}
+
+	if err := h.userService.SetDisabled(userID, body.Disabled); err != nil {
+		if err == domain.ErrUserNotFound {
+			return utils.SendError(c, fiber.StatusNotFound, "User not found")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error updating user")
+	}
+	if body.Disabled {
+		if _, err := h.sessionService.LogoutAll(userID); err != nil {
+			return utils.SendError(c, fiber.StatusInternalServerError, "Error revoking sessions")
+		}
+	}
+
+	action := "ENABLE_USER"
+	if body.Disabled {
+		action = "DISABLE_USER"
+	}
+	entry := auditUser(c, action, userID, "")
+	entry.Before = map[string]interface{}{"disabled": target.Disabled}
+	entry.After = map[string]interface{}{"disabled": body.Disabled}
+
+	return utils.SendResponse(c, fiber.StatusOK, "User updated", nil)
+}
+
+// SetUserRole assigns a role and, for coaches and TAs, the cohorts they
+// manage.
+// PUT /admin/users/:id/role  { "role": "ta", "staff_cohorts": [12, 13] }
+func (h *AdminHandler) SetUserRole(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	var body struct {
+		Role         string `json:"role"`
+		StaffCohorts []int  `json:"staff_cohorts"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if !middleware.IsValidRole(body.Role) {
+		return utils.SendError(c, fiber.StatusBadRequest, "Unknown role")
+	}
+	if middleware.IsScopedRole(body.Role) {
+		if len(body.StaffCohorts) == 0 {
+			return utils.SendError(c, fiber.StatusBadRequest, "Coaches and TAs need at least one cohort")
+		}
+		for _, n := range body.StaffCohorts {
+			if n <= 0 {
+				return utils.SendError(c, fiber.StatusBadRequest, "Invalid cohort number")
+			}
+		}
+	} else {
+		body.StaffCohorts = nil
+	}
+
+	target, err := h.userService.GetUserByID(userID.Hex())
+	if err != nil {
+		return utils.SendError(c, fiber.StatusNotFound, "User not found")
+	}
+
+	if err := h.userService.SetRole(userID, body.Role, body.StaffCohorts); err != nil {
+		if err == domain.ErrUserNotFound {
+			return utils.SendError(c, fiber.StatusNotFound, "User not found")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error updating role")
+	}
+
+	entry := auditUser(c, "SET_ROLE", userID, fmt.Sprintf("Role %s → %s", target.Role, body.Role))
+	entry.Before = map[string]interface{}{"role": target.Role, "staff_cohorts": target.StaffCohorts}
+	entry.After = map[string]interface{}{"role": body.Role, "staff_cohorts": body.StaffCohorts}
+
+	return utils.SendResponse(c, fiber.StatusOK, "Role updated", nil)
+}
+
+// UnlockUser lifts a login lockout before it expires.
+// POST /admin/users/:id/unlock
+func (h *AdminHandler) UnlockUser(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	var unlockedBy primitive.ObjectID
+	if claims, ok := c.Locals("user").(*middleware.Claims); ok {
+		unlockedBy, _ = primitive.ObjectIDFromHex(claims.UserID)
+	}
+
+	if err := h.loginGuard.Unlock(userID, unlockedBy); err != nil {
+		if err == domain.ErrUserNotFound {
+			return utils.SendError(c, fiber.StatusNotFound, "User not found")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error unlocking user")
+	}
+
+	auditUser(c, "UNLOCK_ACCOUNT", userID, "Cleared failed logins and lockout")
+
+	return utils.SendResponse(c, fiber.StatusOK, "User unlocked", nil)
+}
+
+// GetLockouts lists login lockout events newest first.
+// GET /admin/lockouts?user_id=&page=1&limit=50
+func (h *AdminHandler) GetLockouts(c *fiber.Ctx) error {
+	var userID primitive.ObjectID
+	if v := c.Query("user_id"); v != "" {
+		oid, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			return utils.SendError(c, fiber.StatusBadRequest, "Invalid user_id")
+		}
+		userID = oid
+	}
+
+	page := c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+
+	events, total, err := h.loginGuard.Lockouts(userID, page, limit)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching lockouts")
+	}
+
+	return utils.SendResponse(c, fiber.StatusOK, "Lockouts retrieved", fiber.Map{
+		"lockouts": events,
+		"total":    total,
+		"page":     page,
+		"limit":    limit,
+	})
+}
+
+// SetTOTPRequired makes two-factor mandatory (or optional again) for a user.
+// Their sessions are revoked; if they haven't enrolled yet, their next login
+// only reaches the enrollment endpoints.
+// PATCH /admin/users/:id/2fa  { "required": true }
+func (h *AdminHandler) SetTOTPRequired(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	var body struct {
+		Required *bool `json:"required"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.Required == nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "required (true/false) is required")
+	}
+
+	if err := h.totpService.SetRequired(userID, *body.Required); err != nil {
+		if err == domain.ErrUserNotFound {
+			return utils.SendError(c, fiber.StatusNotFound, "User not found")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error updating two-factor requirement")
+	}
+
+	entry := auditUser(c, "SET_TOTP_REQUIRED", userID, fmt.Sprintf("Two-factor required: %t", *body.Required))
+	entry.After = map[string]interface{}{"totp_required": *body.Required}
+
+	return utils.SendResponse(c, fiber.StatusOK, "Two-factor requirement updated", nil)
+}
+
+// ResetTOTP removes a user's two-factor setup so they can enroll again, e.g.
+// after losing their phone and recovery codes.
+// DELETE /admin/users/:id/2fa
+func (h *AdminHandler) ResetTOTP(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	if err := h.totpService.Reset(userID); err != nil {
+		if err == domain.ErrUserNotFound {
+			return utils.SendError(c, fiber.StatusNotFound, "User not found")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error resetting two-factor authentication")
+	}
+
+	auditUser(c, "RESET_TOTP", userID, "Removed two-factor enrollment and recovery codes")
+
+	return utils.SendResponse(c, fiber.StatusOK, "Two-factor authentication reset", nil)
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"strings"
+	"time"
+
+	"gofiber-baro/internal/domain"
+	"gofiber-baro/internal/service/apikey"
+	middleware "gofiber-baro/pkg/middleware"
+	"gofiber-baro/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type APIKeyHandler struct {
+	apiKeyService *apikey.Service
+}
+
+func NewAPIKeyHandler(apiKeyService *apikey.Service) *APIKeyHandler {
+	return &APIKeyHandler{apiKeyService: apiKeyService}
+}
+
+// CreateAPIKey issues a key for an integration. The key is in the response
+// only this once.
+// POST /admin/api-keys  { "name": "Salesforce sync", "permissions": ["attendance:export"], "expires_at": "2026-12-31T00:00:00Z" }
+func (h *APIKeyHandler) CreateAPIKey(c *fiber.Ctx) error {
+	var body struct {
+		Name        string     `json:"name"`
+		Permissions []string   `json:"permissions"`
+		ExpiresAt   *time.Time `json:"expires_at"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	body.Name = strings.TrimSpace(body.Name)
+	if body.Name == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Name is required")
+	}
+	if len(body.Permissions) == 0 {
+		return utils.SendError(c, fiber.StatusBadRequest, "At least one permission is required")
+	}
+	for _, p := range body.Permissions {
+		if !middleware.IsGrantablePermission(middleware.Permission(p)) {
+			return utils.SendError(c, fiber.StatusBadRequest, "Unknown or non-grantable permission: "+p)
+		}
+	}
+	if body.ExpiresAt != nil && !body.ExpiresAt.After(time.Now()) {
+		return utils.SendError(c, fiber.StatusBadRequest, "expires_at must be in the future")
+	}
+
+	var createdBy primitive.ObjectID
+	if claims, ok := c.Locals("user").(*middleware.Claims); ok {
+		createdBy, _ = primitive.ObjectIDFromHex(claims.UserID)
+	}
+
+	raw, key, err := h.apiKeyService.Create(body.Name, body.Permissions, body.ExpiresAt, createdBy)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error creating API key")
+	}
+
+	entry := auditEntry(c)
+	entry.Action = "CREATE_API_KEY"
+	entry.TargetType = "api_key"
+	entry.TargetID = key.ID
+	entry.TargetName = key.Name
+	entry.After = map[string]interface{}{"permissions": key.Permissions, "expires_at": key.ExpiresAt}
+
+	return utils.SendResponse(c, fiber.StatusCreated, "API key created. Copy it now; it will not be shown again", fiber.Map{
+		"key":    raw,
+		"apiKey": key,
+	})
+}
+
+// GetAPIKeys lists every key, including revoked and expired ones.
+// GET /admin/api-keys
+func (h *APIKeyHandler) GetAPIKeys(c *fiber.Ctx) error {
+	keys, err := h.apiKeyService.List()
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching API keys")
+	}
+	return utils.SendResponse(c, fiber.StatusOK, "API keys retrieved", keys)
+}
+
+// RevokeAPIKey stops a key working immediately.
+// DELETE /admin/api-keys/:id
+func (h *APIKeyHandler) RevokeAPIKey(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid API key ID")
+	}
+
+	if err := h.apiKeyService.Revoke(id); err != nil {
+		if err == domain.ErrAPIKeyNotFound {
+			return utils.SendError(c, fiber.StatusNotFound, "API key not found or already revoked")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error revoking API key")
+	}
+
+	entry := auditEntry(c)
+	entry.Action = "REVOKE_API_KEY"
+	entry.TargetType = "api_key"
+	entry.TargetID = id
+
+	return utils.SendResponse(c, fiber.StatusOK, "API key revoked", nil)
+}
+
+// GetAPIKeyUsage lists the requests made with a key, newest first.
+// GET /admin/api-keys/:id/usage?page=1&limit=50
+func (h *APIKeyHandler) GetAPIKeyUsage(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid API key ID")
+	}
+
+	page := c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+
+	usage, total, err := h.apiKeyService.Usage(id, page, limit)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching API key usage")
+	}
+
+	return utils.SendResponse(c, fiber.StatusOK, "API key usage retrieved", fiber.Map{
+		"usage": usage,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
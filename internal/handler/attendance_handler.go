@@ -73,6 +73,12 @@ func (h *AttendanceHandler) GenerateAttendanceCode(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error generating code")
 	}
 
+	entry := auditEntry(c)
+	entry.Action = "GENERATE_ATTENDANCE_CODE"
+	entry.TargetType = "cohort"
+	entry.TargetName = fmt.Sprintf("Cohort %d", body.Cohort)
+	entry.Details = fmt.Sprintf("Generated %s code for cohort %d", body.Session, body.Cohort)
+
 	return utils.SendResponse(c, fiber.StatusOK, "Code generated successfully", code)
 }
 
@@ -84,15 +90,13 @@ func (h *AttendanceHandler) GetActiveAttendanceCode(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusBadRequest, "Cohort and session are required")
 	}
 
-	// Security: If not admin, verify user is in the requested cohort
-	userID := c.Locals("userID")
-	userRole := ""
-	if user, ok := c.Locals("user").(*middleware.Claims); ok {
-		userRole = user.Role
-	}
-
-	if userRole != "admin" {
-		userData, err := h.userService.GetUserByID(userID.(string))
+	// Security: unless staff over this cohort, verify user is in the requested cohort
+	if !middleware.Authorize(c, middleware.PermAttendanceManage, cohort) {
+		userID, ok := middleware.UserID(c)
+		if !ok {
+			return sendNoUser(c)
+		}
+		userData, err := h.userService.GetUserByID(userID)
 		if err != nil {
 			return utils.SendError(c, fiber.StatusUnauthorized, "User data not found")
 		}
@@ -114,9 +118,9 @@ func (h *AttendanceHandler) GetActiveAttendanceCode(c *fiber.Ctx) error {
 }
 
 func (h *AttendanceHandler) SubmitAttendance(c *fiber.Ctx) error {
-	userID := c.Locals("userID")
-	if userID == nil {
-		return utils.SendError(c, fiber.StatusUnauthorized, "Unauthorized")
+	userID, ok := middleware.UserID(c)
+	if !ok {
+		return sendNoUser(c)
 	}
 
 	type RequestBody struct {
@@ -129,7 +133,7 @@ func (h *AttendanceHandler) SubmitAttendance(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	oid, err := primitive.ObjectIDFromHex(userID.(string))
+	oid, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID")
 	}
@@ -154,12 +158,12 @@ func (h *AttendanceHandler) SubmitAttendance(c *fiber.Ctx) error {
 }
 
 func (h *AttendanceHandler) GetMyAttendanceStatus(c *fiber.Ctx) error {
-	userID := c.Locals("userID")
-	if userID == nil {
-		return utils.SendError(c, fiber.StatusUnauthorized, "Unauthorized")
+	userID, ok := middleware.UserID(c)
+	if !ok {
+		return sendNoUser(c)
 	}
 
-	oid, err := primitive.ObjectIDFromHex(userID.(string))
+	oid, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID")
 	}
@@ -219,6 +223,8 @@ func (h *AttendanceHandler) ManualMarkAttendance(c *fiber.Ctx) error {
 		markedBy = id
 	}
 
+	previous, _ := h.submissionService.FindRecord(oid, body.Date, body.Session)
+
 	record, err := h.submissionService.ManualMarkAttendance(oid, body.Date, body.Session, status, markedBy)
 	if err != nil {
 		log.Printf("[ERROR] ManualMarkAttendance failed for user %s: %v", body.UserID, err)
@@ -228,6 +234,12 @@ func (h *AttendanceHandler) ManualMarkAttendance(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error marking attendance: "+err.Error())
 	}
 
+	entry := auditUser(c, "MANUAL_MARK_ATTENDANCE", oid, fmt.Sprintf("Marked %s %s as %s", body.Date, body.Session, body.Status))
+	if previous != nil {
+		entry.Before = map[string]interface{}{"status": previous.Status, "marked_by": previous.MarkedBy}
+	}
+	entry.After = map[string]interface{}{"status": status, "marked_by": domain.MarkedByAdmin}
+
 	return utils.SendResponse(c, fiber.StatusOK, "Attendance marked successfully", record)
 }
 
@@ -302,6 +314,11 @@ func (h *AttendanceHandler) BulkMarkAttendance(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error marking attendance")
 	}
 
+	entry := auditEntry(c)
+	entry.Action = "BULK_MARK_ATTENDANCE"
+	entry.Details = fmt.Sprintf("Marked %d learners %s for %s %s", len(records), body.Status, body.Date, body.Session)
+	entry.After = map[string]interface{}{"user_ids": body.UserIDs, "status": status}
+
 	return utils.SendResponse(c, fiber.StatusOK, "Attendance marked successfully", fiber.Map{
 		"marked_count": len(records),
 		"records":      records,
@@ -346,7 +363,12 @@ func (h *AttendanceHandler) GetAttendanceStats(c *fiber.Ctx) error {
 		startDate = utils.GetThailandTime().AddDate(0, 0, -30).Format("2006-01-02")
 	}
 
-	stats, err := h.statsService.GetAttendanceStats(cohort, startDate, endDate)
+	groupID, err := groupQuery(c)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid group_id")
+	}
+
+	stats, err := h.statsService.GetAttendanceStats(cohort, groupID, startDate, endDate)
 	if err != nil {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching stats")
 	}
@@ -428,6 +450,14 @@ func (h *AttendanceHandler) LockSession(c *fiber.Ctx) error {
 		status = "locked"
 	}
 
+	entry := auditEntry(c)
+	entry.Action = "LOCK_SESSION"
+	entry.TargetType = "cohort"
+	entry.TargetName = fmt.Sprintf("Cohort %d", body.Cohort)
+	entry.Details = fmt.Sprintf("Attendance %s for %s %s", status, body.Date, body.Session)
+	entry.Before = map[string]interface{}{"locked": !body.Locked}
+	entry.After = map[string]interface{}{"locked": body.Locked}
+
 	return utils.SendResponse(c, fiber.StatusOK, "Attendance "+status+" successfully", nil)
 }
 
@@ -451,6 +481,9 @@ func (h *AttendanceHandler) DeleteAttendanceRecord(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error deleting record")
 	}
 
+	entry := auditUser(c, "DELETE_ATTENDANCE_RECORD", record.UserID, fmt.Sprintf("Deleted %s %s record", record.Date, record.Session))
+	entry.Before = map[string]interface{}{"record_id": record.ID.Hex(), "status": record.Status}
+
 	return utils.SendResponse(c, fiber.StatusOK, "Attendance record deleted", record)
 }
 
@@ -459,7 +492,12 @@ func (h *AttendanceHandler) GetDailyAttendanceStats(c *fiber.Ctx) error {
 	startDate := c.Query("start_date", "")
 	endDate := c.Query("end_date", "")
 
-	stats, err := h.statsService.GetDailyAttendanceStatsByDateRange(cohort, startDate, endDate)
+	groupID, err := groupQuery(c)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid group_id")
+	}
+
+	stats, err := h.statsService.GetDailyAttendanceStatsByDateRange(cohort, groupID, startDate, endDate)
 	if err != nil {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching daily stats")
 	}
@@ -468,12 +506,12 @@ func (h *AttendanceHandler) GetDailyAttendanceStats(c *fiber.Ctx) error {
 }
 
 func (h *AttendanceHandler) GetMyAttendanceHistory(c *fiber.Ctx) error {
-	userID := c.Locals("userID")
-	if userID == nil {
-		return utils.SendError(c, fiber.StatusUnauthorized, "Unauthorized")
+	userID, ok := middleware.UserID(c)
+	if !ok {
+		return sendNoUser(c)
 	}
 
-	oid, err := primitive.ObjectIDFromHex(userID.(string))
+	oid, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID")
 	}
@@ -494,14 +532,14 @@ func (h *AttendanceHandler) GetMyAttendanceHistory(c *fiber.Ctx) error {
 }
 
 func (h *AttendanceHandler) GetMyDailyStats(c *fiber.Ctx) error {
-	userID := c.Locals("userID")
-	if userID == nil {
-		return utils.SendError(c, fiber.StatusUnauthorized, "Unauthorized")
+	userID, ok := middleware.UserID(c)
+	if !ok {
+		return sendNoUser(c)
 	}
 
 	days := c.QueryInt("days", 7)
 
-	oid, err := primitive.ObjectIDFromHex(userID.(string))
+	oid, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID")
 	}
@@ -515,7 +553,7 @@ func (h *AttendanceHandler) GetMyDailyStats(c *fiber.Ctx) error {
 	startDate := utils.GetThailandTime().AddDate(0, 0, -days).Format("2006-01-02")
 	endDate := utils.GetThailandTime().Format("2006-01-02")
 
-	stats, err := h.statsService.GetDailyAttendanceStatsByDateRange(user.CohortNumber, startDate, endDate)
+	stats, err := h.statsService.GetDailyAttendanceStatsByDateRange(user.CohortNumber, primitive.NilObjectID, startDate, endDate)
 	if err != nil {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching daily stats")
 	}
@@ -557,6 +595,10 @@ func (h *AttendanceHandler) ExportAttendance(c *fiber.Ctx) error {
 	structure := c.Query("structure", "daily")
 	splitAMPM := c.Query("split_am_pm", "false") == "true"
 	statusFilter := c.Query("status_filter", "all")
+	groupID, err := groupQuery(c)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid group_id")
+	}
 
 	if cohort == 0 {
 		return utils.SendError(c, fiber.StatusBadRequest, "cohort is required")
@@ -568,6 +610,10 @@ func (h *AttendanceHandler) ExportAttendance(c *fiber.Ctx) error {
 		endDate = utils.GetThailandTime().Format("2006-01-02")
 	}
 
+	if statusFilter != "all" && !domain.LearnerStatus(statusFilter).IsValid() {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid status_filter. Use 'all' or one of active, on-hold, dropout, dismissed, graduated")
+	}
+
 	expFormat := attendance.ExportFormat(format)
 	if expFormat != attendance.ExportFormatCSV && expFormat != attendance.ExportFormatXLSX {
 		return utils.SendError(c, fiber.StatusBadRequest, "Invalid format. Use 'csv' or 'xlsx'")
@@ -588,6 +634,7 @@ func (h *AttendanceHandler) ExportAttendance(c *fiber.Ctx) error {
 		Structure:    expStructure,
 		SplitAMPM:    splitAMPM,
 		StatusFilter: statusFilter,
+		GroupID:      groupID,
 	}
 
 	data, ext, err := h.exportService.Export(req)
@@ -625,24 +672,39 @@ func (h *AttendanceHandler) UpdateSalesforceID(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
 	}
 
+	target, err := h.userService.GetUserByID(id)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusNotFound, "User not found")
+	}
+
 	if err := h.userService.UpdateUser(id, map[string]interface{}{"salesforce_id": body.SalesforceID}); err != nil {
 		log.Printf("[ERROR] UpdateSalesforceID for user %s: %v", id, err)
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error updating Salesforce ID")
 	}
 
+	entry := auditUser(c, "UPDATE_SALESFORCE_ID", target.ID, "Updated Salesforce ID")
+	entry.Before = map[string]interface{}{"salesforce_id": target.SalesforceID}
+	entry.After = map[string]interface{}{"salesforce_id": body.SalesforceID}
+
 	return utils.SendResponse(c, fiber.StatusOK, "Salesforce ID updated", nil)
 }
 
-// UpdateAttendanceStatus lets an admin set or clear the attendance status for a user.
-// PATCH /admin/users/:id/attendance-status  { "attendance_status": "dropout" }
+// UpdateAttendanceStatus records a dated learner status change. Stats and
+// exports count the learner up to effective_date and drop them after it.
+// PATCH /admin/users/:id/attendance-status
+//
+//	{ "attendance_status": "dropout", "effective_date": "2025-03-01", "reason": "..." }
 func (h *AttendanceHandler) UpdateAttendanceStatus(c *fiber.Ctx) error {
 	id := c.Params("id")
-	if id == "" {
-		return utils.SendError(c, fiber.StatusBadRequest, "User ID is required")
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID")
 	}
 
 	type RequestBody struct {
 		AttendanceStatus string `json:"attendance_status"`
+		EffectiveDate    string `json:"effective_date"`
+		Reason           string `json:"reason"`
 	}
 
 	var body RequestBody
@@ -650,25 +712,31 @@ func (h *AttendanceHandler) UpdateAttendanceStatus(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	validStatuses := map[domain.AttendanceStatus]bool{
-		domain.AttendanceStatus("active"): true,
-		domain.StatusDropout:              true,
-		domain.StatusDismissed:            true,
-	}
-
-	if !validStatuses[domain.AttendanceStatus(body.AttendanceStatus)] {
-		return utils.SendError(c, fiber.StatusBadRequest, "Invalid attendance status. Must be 'active', 'dropout', or 'dismissed'")
+	changedBy := ""
+	if claims, ok := c.Locals("user").(*middleware.Claims); ok {
+		changedBy = claims.UserID
 	}
 
-	statusToSave := body.AttendanceStatus
-	if body.AttendanceStatus == "active" {
-		statusToSave = ""
+	var before domain.LearnerStatus
+	if target, err := h.userService.GetUserByID(id); err == nil {
+		before = target.StatusOn(body.EffectiveDate)
 	}
 
-	if err := h.userService.UpdateUser(id, map[string]interface{}{"attendance_status": statusToSave}); err != nil {
+	transition, err := h.userService.ChangeLearnerStatus(oid, domain.LearnerStatus(body.AttendanceStatus), body.EffectiveDate, body.Reason, changedBy)
+	if err != nil {
+		switch err {
+		case domain.ErrInvalidLearnerStatus, domain.ErrInvalidEffectiveDate:
+			return utils.SendError(c, fiber.StatusBadRequest, err.Error())
+		case domain.ErrUserNotFound:
+			return utils.SendError(c, fiber.StatusNotFound, "User not found")
+		}
 		log.Printf("[ERROR] UpdateAttendanceStatus for user %s: %v", id, err)
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error updating attendance status")
 	}
 
-	return utils.SendResponse(c, fiber.StatusOK, "Attendance status updated", nil)
+	entry := auditUser(c, "CHANGE_LEARNER_STATUS", oid, fmt.Sprintf("Status %s from %s", body.AttendanceStatus, body.EffectiveDate))
+	entry.Before = map[string]interface{}{"status": before}
+	entry.After = map[string]interface{}{"status": transition.Status, "effective_date": transition.EffectiveDate, "reason": transition.Reason}
+
+	return utils.SendResponse(c, fiber.StatusOK, "Attendance status updated", transition)
 }
@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gofiber-baro/internal/domain"
+	"gofiber-baro/internal/service/audit"
+	middleware "gofiber-baro/pkg/middleware"
+	"gofiber-baro/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AuditHandler struct {
+	auditService *audit.Service
+}
+
+func NewAuditHandler(auditService *audit.Service) *AuditHandler {
+	return &AuditHandler{auditService: auditService}
+}
+
+const auditLocalsKey = "auditEntry"
+
+// auditEntry returns the pending audit entry for this request. Handlers fill
+// in the action, target and before/after values; Trail adds the actor and IP
+// and stores it once the handler has succeeded.
+func auditEntry(c *fiber.Ctx) *domain.AuditLog {
+	if entry, ok := c.Locals(auditLocalsKey).(*domain.AuditLog); ok {
+		return entry
+	}
+	entry := &domain.AuditLog{}
+	c.Locals(auditLocalsKey, entry)
+	return entry
+}
+
+// auditUser is the common case: an action on one user.
+func auditUser(c *fiber.Ctx, action string, userID primitive.ObjectID, details string) *domain.AuditLog {
+	entry := auditEntry(c)
+	entry.Action = action
+	entry.TargetType = "user"
+	entry.TargetID = userID
+	entry.Details = details
+	return entry
+}
+
+// changedFields picks the keys of update out of doc's BSON form, giving the
+// old values for an audit entry's Before. Passwords are never copied.
+func changedFields(doc interface{}, update map[string]interface{}) (before, after map[string]interface{}) {
+	var current bson.M
+	if raw, err := bson.Marshal(doc); err == nil {
+		_ = bson.Unmarshal(raw, &current)
+	}
+	before = map[string]interface{}{}
+	after = map[string]interface{}{}
+	for key, value := range update {
+		if key == "password" {
+			before[key], after[key] = "[redacted]", "[redacted]"
+			continue
+		}
+		before[key] = current[key]
+		after[key] = value
+	}
+	return before, after
+}
+
+// Trail records every successful mutating request that passes through it.
+// Routes whose handler didn't describe the change still get an entry, named
+// after the method and route, so no admin write goes unlogged. A failed
+// insert is logged but doesn't fail the request that already succeeded.
+func (h *AuditHandler) Trail(c *fiber.Ctx) error {
+	switch c.Method() {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return c.Next()
+	}
+
+	if err := c.Next(); err != nil {
+		return err
+	}
+	if c.Response().StatusCode() >= fiber.StatusBadRequest {
+		return nil
+	}
+
+	entry := auditEntry(c)
+	if entry.Action == "" {
+		entry.Action = c.Method() + " " + c.Route().Path
+	}
+	if entry.TargetID.IsZero() {
+		if oid, err := primitive.ObjectIDFromHex(c.Params("id")); err == nil {
+			entry.TargetID = oid
+		}
+	}
+	if claims, ok := c.Locals("user").(*middleware.Claims); ok {
+		entry.ActorID, _ = primitive.ObjectIDFromHex(claims.UserID)
+		if claims.APIKeyID != "" {
+			entry.ActorName = "API key " + claims.APIKeyID
+		}
+	}
+	entry.IPAddress = c.IP()
+
+	if err := h.auditService.Record(c.Context(), entry); err != nil {
+		log.Printf("[ERROR] audit: record %s by %s: %v", entry.Action, entry.ActorID.Hex(), err)
+	}
+	return nil
+}
+
+// GetAuditLogs lists audit entries newest first, or downloads them as CSV.
+// GET /admin/audit-logs?action=&actor_id=&target_id=&from=YYYY-MM-DD&to=YYYY-MM-DD&page=1&limit=50&format=csv
+func (h *AuditHandler) GetAuditLogs(c *fiber.Ctx) error {
+	filter := domain.AuditLogFilter{Action: strings.TrimSpace(c.Query("action"))}
+
+	if v := c.Query("actor_id"); v != "" {
+		oid, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			return utils.SendError(c, fiber.StatusBadRequest, "Invalid actor_id")
+		}
+		filter.ActorID = oid
+	}
+	if v := c.Query("target_id"); v != "" {
+		oid, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			return utils.SendError(c, fiber.StatusBadRequest, "Invalid target_id")
+		}
+		filter.TargetID = oid
+	}
+	// Dates are Thailand calendar days; "to" is inclusive.
+	if v := c.Query("from"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, utils.GetThailandTime().Location())
+		if err != nil {
+			return utils.SendError(c, fiber.StatusBadRequest, "Invalid from date. Use YYYY-MM-DD")
+		}
+		filter.From = t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, utils.GetThailandTime().Location())
+		if err != nil {
+			return utils.SendError(c, fiber.StatusBadRequest, "Invalid to date. Use YYYY-MM-DD")
+		}
+		filter.To = t.AddDate(0, 0, 1)
+	}
+
+	if c.Query("format") == "csv" {
+		data, err := h.auditService.ExportCSV(filter)
+		if err != nil {
+			log.Printf("[ERROR] GetAuditLogs export: %v", err)
+			return utils.SendError(c, fiber.StatusInternalServerError, "Error exporting audit logs")
+		}
+		filename := fmt.Sprintf("audit_logs_%s.csv", utils.GetThailandTime().Format("2006-01-02"))
+		c.Set("Content-Type", "text/csv; charset=utf-8")
+		c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+		return c.Send(data)
+	}
+
+	page := c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 {
+		limit = 50
+	}
+	if limit > 500 {
+		limit = 500
+	}
+
+	logs, total, err := h.auditService.List(filter, page, limit)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching audit logs")
+	}
+
+	return utils.SendResponse(c, fiber.StatusOK, "Audit logs retrieved", fiber.Map{
+		"logs":  logs,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
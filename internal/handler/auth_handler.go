@@ -0,0 +1,291 @@
+package handler
+
+import (
+	"log"
+
+	"gofiber-baro/internal/domain"
+	"gofiber-baro/internal/service/session"
+	"gofiber-baro/internal/service/user"
+	middleware "gofiber-baro/pkg/middleware"
+	"gofiber-baro/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AuthHandler struct {
+	sessionService  *session.Service
+	passwordService *user.PasswordService
+	userService     *user.Service
+	totpService     *user.TOTPService
+}
+
+func NewAuthHandler(sessionService *session.Service, passwordService *user.PasswordService, userService *user.Service, totpService *user.TOTPService) *AuthHandler {
+	return &AuthHandler{sessionService: sessionService, passwordService: passwordService, userService: userService, totpService: totpService}
+}
+
+type refreshTokenBody struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// Refresh trades a refresh token for a new access/refresh pair. The old
+// refresh token stops working.
+// POST /auth/refresh  { "refreshToken": "..." }
+func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
+	var body refreshTokenBody
+	if err := c.BodyParser(&body); err != nil || body.RefreshToken == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Refresh token is required")
+	}
+
+	tokens, err := h.sessionService.Refresh(body.RefreshToken, c.Get(fiber.HeaderUserAgent), c.IP())
+	if err != nil {
+		if err == domain.ErrInvalidRefreshToken {
+			return utils.SendError(c, fiber.StatusUnauthorized, "Invalid or expired refresh token")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Could not refresh token")
+	}
+
+	return utils.SendResponse(c, fiber.StatusOK, "Token refreshed", tokens)
+}
+
+// Logout ends the session on this device.
+// POST /auth/logout  { "refreshToken": "..." }
+func (h *AuthHandler) Logout(c *fiber.Ctx) error {
+	var body refreshTokenBody
+	if err := c.BodyParser(&body); err != nil || body.RefreshToken == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Refresh token is required")
+	}
+
+	if err := h.sessionService.Logout(body.RefreshToken); err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error logging out")
+	}
+
+	return utils.SendResponse(c, fiber.StatusOK, "Logged out", nil)
+}
+
+// LogoutAll signs the caller out of every device.
+// POST /auth/logout-all
+func (h *AuthHandler) LogoutAll(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*middleware.Claims)
+	if !ok {
+		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid token claims")
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid token claims")
+	}
+
+	revoked, err := h.sessionService.LogoutAll(userID)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error logging out")
+	}
+
+	return utils.SendResponse(c, fiber.StatusOK, "Logged out of all devices", fiber.Map{"revoked": revoked})
+}
+
+// ChangePassword sets a new password for the caller, signs out every other
+// device and returns a fresh token pair for this one. It is the only route a
+// learner with a forced password change can use.
+// POST /auth/change-password  { "current_password": "...", "new_password": "..." }
+func (h *AuthHandler) ChangePassword(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*middleware.Claims)
+	if !ok {
+		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid token claims")
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid token claims")
+	}
+
+	var body struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := h.passwordService.ChangePassword(userID, body.CurrentPassword, body.NewPassword); err != nil {
+		switch err {
+		case domain.ErrWrongPassword:
+			return utils.SendError(c, fiber.StatusUnauthorized, err.Error())
+		case domain.ErrWeakPassword, domain.ErrPasswordUnchanged:
+			return utils.SendError(c, fiber.StatusBadRequest, err.Error())
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error changing password")
+	}
+
+	if _, err := h.sessionService.LogoutAll(userID); err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error revoking sessions")
+	}
+	u, err := h.userService.GetUserByID(claims.UserID)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error changing password")
+	}
+	tokens, err := h.sessionService.Login(u, c.Get(fiber.HeaderUserAgent), c.IP())
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Could not generate token")
+	}
+
+	return utils.SendResponse(c, fiber.StatusOK, "Password changed", tokens)
+}
+
+// ForgotPassword mails a reset link. The response is identical whether or not
+// the email is registered.
+// POST /auth/forgot-password  { "email": "..." }
+func (h *AuthHandler) ForgotPassword(c *fiber.Ctx) error {
+	var body struct {
+		Email string `json:"email"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.Email == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Email is required")
+	}
+
+	if err := h.passwordService.RequestReset(body.Email); err != nil {
+		log.Printf("[ERROR] ForgotPassword: %v", err)
+	}
+
+	return utils.SendResponse(c, fiber.StatusOK, "If that email is registered, a reset link is on its way", nil)
+}
+
+// ResetPassword sets a new password from a mailed reset token and signs the
+// user out everywhere.
+// POST /auth/reset-password  { "token": "...", "new_password": "..." }
+func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
+	var body struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"new_password"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.Token == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Reset token is required")
+	}
+
+	userID, err := h.passwordService.ResetPassword(body.Token, body.NewPassword)
+	if err != nil {
+		switch err {
+		case domain.ErrInvalidResetToken, domain.ErrWeakPassword:
+			return utils.SendError(c, fiber.StatusBadRequest, err.Error())
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error resetting password")
+	}
+
+	if _, err := h.sessionService.LogoutAll(userID); err != nil {
+		log.Printf("[ERROR] ResetPassword: revoke sessions for %s: %v", userID.Hex(), err)
+	}
+
+	return utils.SendResponse(c, fiber.StatusOK, "Password reset. Please log in with your new password.", nil)
+}
+
+// EnrollTOTP starts two-factor enrollment and returns the secret, both raw
+// and as an otpauth:// URI for a QR code. Calling it again replaces a
+// pending secret that was never confirmed.
+// POST /auth/2fa/enroll
+func (h *AuthHandler) EnrollTOTP(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*middleware.Claims)
+	if !ok {
+		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid token claims")
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid token claims")
+	}
+
+	secret, uri, err := h.totpService.Enroll(userID)
+	if err != nil {
+		if err == domain.ErrTOTPAlreadyEnabled {
+			return utils.SendError(c, fiber.StatusConflict, err.Error())
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error starting two-factor enrollment")
+	}
+
+	return utils.SendResponse(c, fiber.StatusOK, "Scan the code with your authenticator app, then verify", fiber.Map{
+		"secret": secret,
+		"uri":    uri,
+	})
+}
+
+// VerifyTOTP confirms enrollment with a first code. The recovery codes in the
+// response are shown only once. Other devices are signed out and this one
+// gets a fresh token pair, which also lifts a pending setup requirement.
+// POST /auth/2fa/verify  { "code": "123456" }
+func (h *AuthHandler) VerifyTOTP(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*middleware.Claims)
+	if !ok {
+		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid token claims")
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid token claims")
+	}
+
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.Code == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Code is required")
+	}
+
+	codes, err := h.totpService.Confirm(userID, body.Code)
+	if err != nil {
+		switch err {
+		case domain.ErrInvalidTOTPCode, domain.ErrTOTPNotEnrolled:
+			return utils.SendError(c, fiber.StatusBadRequest, err.Error())
+		case domain.ErrTOTPAlreadyEnabled:
+			return utils.SendError(c, fiber.StatusConflict, err.Error())
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error enabling two-factor authentication")
+	}
+
+	if _, err := h.sessionService.LogoutAll(userID); err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error revoking sessions")
+	}
+	u, err := h.userService.GetUserByID(claims.UserID)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error enabling two-factor authentication")
+	}
+	tokens, err := h.sessionService.Login(u, c.Get(fiber.HeaderUserAgent), c.IP())
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Could not generate token")
+	}
+
+	return utils.SendResponse(c, fiber.StatusOK, "Two-factor authentication enabled", fiber.Map{
+		"recoveryCodes": codes,
+		"tokens":        tokens,
+	})
+}
+
+// DisableTOTP turns two-factor off. It needs the password and a current code
+// (or recovery code), and is refused where two-factor is required.
+// POST /auth/2fa/disable  { "password": "...", "code": "123456" }
+func (h *AuthHandler) DisableTOTP(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*middleware.Claims)
+	if !ok {
+		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid token claims")
+	}
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid token claims")
+	}
+
+	var body struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	if err := c.BodyParser(&body); err != nil || body.Password == "" || body.Code == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Password and code are required")
+	}
+
+	if err := h.totpService.Disable(userID, body.Password, body.Code); err != nil {
+		switch err {
+		case domain.ErrWrongPassword, domain.ErrInvalidTOTPCode:
+			return utils.SendError(c, fiber.StatusUnauthorized, err.Error())
+		case domain.ErrTOTPNotEnrolled:
+			return utils.SendError(c, fiber.StatusBadRequest, err.Error())
+		case domain.ErrTOTPRequired:
+			return utils.SendError(c, fiber.StatusForbidden, err.Error())
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error disabling two-factor authentication")
+	}
+
+	return utils.SendResponse(c, fiber.StatusOK, "Two-factor authentication disabled", nil)
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+
+	"gofiber-baro/internal/domain"
+	"gofiber-baro/internal/service/user"
+	"gofiber-baro/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type BulkImportHandler struct {
+	userService       *user.Service
+	bulkImportService *user.BulkImportService
+}
+
+func NewBulkImportHandler(userService *user.Service, bulkImportService *user.BulkImportService) *BulkImportHandler {
+	return &BulkImportHandler{userService: userService, bulkImportService: bulkImportService}
+}
+
+func sendBulkImportError(c *fiber.Ctx, err error, fallback string) error {
+	switch err {
+	case domain.ErrBulkImportNotFound:
+		return utils.SendError(c, fiber.StatusNotFound, "Bulk upload not found")
+	case domain.ErrBulkImportGone:
+		return utils.SendError(c, fiber.StatusGone, err.Error())
+	case domain.ErrBulkImportRunning, domain.ErrBulkImportFailed, domain.ErrBulkResultsCollected:
+		return utils.SendError(c, fiber.StatusConflict, err.Error())
+	}
+	return utils.SendError(c, fiber.StatusInternalServerError, fallback)
+}
+
+// UploadUsers registers learners from a CSV or XLSX sheet (multipart field
+// "file", plus "cohort_number" and an optional shared "password"). With
+// ?dry_run=true it only reports each row's problems. Otherwise the valid
+// rows are created in the background; poll the returned import and download
+// its results sheet once it completes.
+// POST /admin/users/bulk-upload?dry_run=true
+func (h *BulkImportHandler) UploadUsers(c *fiber.Ctx) error {
+	cohortNumber, err := strconv.Atoi(c.FormValue("cohort_number"))
+	if err != nil || cohortNumber <= 0 {
+		return utils.SendError(c, fiber.StatusBadRequest, "cohort_number is required")
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "A CSV or XLSX file is required")
+	}
+	if fileHeader.Size > 2<<20 {
+		return utils.SendError(c, fiber.StatusBadRequest, "File too large")
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Could not read file")
+	}
+	defer file.Close()
+
+	rows, err := user.ParseBulkSheet(fileHeader.Filename, file)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	if c.QueryBool("dry_run") {
+		checks, err := h.userService.CheckBulkRows(rows)
+		if err != nil {
+			return utils.SendError(c, fiber.StatusInternalServerError, "Error checking rows")
+		}
+		valid := 0
+		for _, check := range checks {
+			if check.Valid {
+				valid++
+			}
+		}
+
+		entry := auditEntry(c)
+		entry.Action = "PREVIEW_BULK_UPLOAD"
+		entry.TargetType = "cohort"
+		entry.TargetName = fmt.Sprintf("Cohort %d", cohortNumber)
+		entry.Details = fmt.Sprintf("Checked %s: %d of %d rows valid", fileHeader.Filename, valid, len(checks))
+
+		return utils.SendResponse(c, fiber.StatusOK, "Dry run completed", fiber.Map{
+			"rows":    checks,
+			"total":   len(checks),
+			"valid":   valid,
+			"invalid": len(checks) - valid,
+		})
+	}
+
+	imp := h.bulkImportService.Start(fileHeader.Filename, rows, cohortNumber, c.FormValue("password"), callerID(c))
+
+	entry := auditEntry(c)
+	entry.Action = "BULK_UPLOAD_USERS"
+	entry.TargetType = "cohort"
+	entry.TargetName = fmt.Sprintf("Cohort %d", cohortNumber)
+	entry.Details = fmt.Sprintf("Started import %s of %d rows from %s", imp.ID, imp.Total, fileHeader.Filename)
+
+	return utils.SendResponse(c, fiber.StatusAccepted, "Bulk upload started", imp)
+}
+
+// GetBulkUpload reports an import's progress and counts.
+// GET /admin/users/bulk-upload/:id
+func (h *BulkImportHandler) GetBulkUpload(c *fiber.Ctx) error {
+	imp, err := h.bulkImportService.Get(c.Params("id"))
+	if err != nil {
+		return sendBulkImportError(c, err, "Error fetching bulk upload")
+	}
+	return utils.SendResponse(c, fiber.StatusOK, "Bulk upload retrieved", imp)
+}
+
+// DownloadBulkUploadResults sends the results sheet, one row per uploaded
+// row with its status and any generated password. It can be downloaded only
+// once, so the passwords exist nowhere else afterwards.
+// GET /admin/users/bulk-upload/:id/results
+func (h *BulkImportHandler) DownloadBulkUploadResults(c *fiber.Ctx) error {
+	id := c.Params("id")
+	data, err := h.bulkImportService.CollectResults(id)
+	if err != nil {
+		return sendBulkImportError(c, err, "Error fetching results")
+	}
+
+	c.Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="bulk_upload_%s_credentials.xlsx"`, id))
+	c.Set("Cache-Control", "no-store")
+	return c.Send(data)
+}
@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"html"
+
+	"gofiber-baro/internal/domain"
+	"gofiber-baro/internal/service/reflection"
+	middleware "gofiber-baro/pkg/middleware"
+	"gofiber-baro/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FeedbackHandler serves the feedback thread of a reflection. The same
+// handlers back the learner routes under /users and the audited staff
+// routes under /admin/users.
+type FeedbackHandler struct {
+	feedbackService *reflection.FeedbackService
+}
+
+func NewFeedbackHandler(feedbackService *reflection.FeedbackService) *FeedbackHandler {
+	return &FeedbackHandler{feedbackService: feedbackService}
+}
+
+func sendFeedbackError(c *fiber.Ctx, err error, fallback string) error {
+	switch err {
+	case domain.ErrReflectionNotFound:
+		return utils.SendError(c, fiber.StatusNotFound, "Reflection not found")
+	case domain.ErrFeedbackNotFound:
+		return utils.SendError(c, fiber.StatusNotFound, "Feedback entry not found")
+	case domain.ErrFeedbackNotAuthor:
+		return utils.SendError(c, fiber.StatusForbidden, err.Error())
+	case domain.ErrFeedbackEmpty, domain.ErrFeedbackTooLong:
+		return utils.SendError(c, fiber.StatusBadRequest, err.Error())
+	}
+	return utils.SendError(c, fiber.StatusInternalServerError, fallback)
+}
+
+// feedbackAuthor is the caller as a thread participant. Admins moderate.
+func feedbackAuthor(c *fiber.Ctx) reflection.FeedbackAuthor {
+	var author reflection.FeedbackAuthor
+	if claims, ok := c.Locals("user").(*middleware.Claims); ok {
+		author.ID, _ = primitive.ObjectIDFromHex(claims.UserID)
+		author.Role = claims.Role
+		author.Moderator = claims.Role == middleware.RoleAdmin
+		author.Impersonator = claims.Impersonator
+	}
+	return author
+}
+
+// threadParams reads the learner and reflection IDs of /:id/reflections/:reflectionId.
+func threadParams(c *fiber.Ctx, userParam string) (learnerID, reflectionID primitive.ObjectID, err error) {
+	if learnerID, err = primitive.ObjectIDFromHex(c.Params(userParam)); err != nil {
+		return
+	}
+	reflectionID, err = primitive.ObjectIDFromHex(c.Params("reflectionId"))
+	return
+}
+
+func auditFeedback(c *fiber.Ctx, action string, entry *domain.FeedbackEntry, details string) *domain.AuditLog {
+	audit := auditUser(c, action, entry.LearnerID, details+" on reflection "+entry.ReflectionID.Hex())
+	audit.After = map[string]interface{}{"entry_id": entry.ID.Hex(), "body": entry.Body}
+	return audit
+}
+
+// GetThread returns a reflection's feedback thread, oldest first. The
+// learner reading it clears their unread indicator.
+// GET /users/:id/reflections/:reflectionId/feedback
+func (h *FeedbackHandler) GetThread(c *fiber.Ctx) error {
+	learnerID, reflectionID, err := threadParams(c, "id")
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user or reflection ID")
+	}
+	entries, err := h.feedbackService.Thread(learnerID, reflectionID, feedbackAuthor(c))
+	if err != nil {
+		return sendFeedbackError(c, err, "Error fetching feedback")
+	}
+	return utils.SendResponse(c, fiber.StatusOK, "Feedback retrieved", entries)
+}
+
+// PostFeedback adds a message to the thread, from staff or the learner.
+// POST /users/:id/reflections/:reflectionId/feedback  { "body": "..." }
+func (h *FeedbackHandler) PostFeedback(c *fiber.Ctx) error {
+	learnerID, reflectionID, err := threadParams(c, "id")
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user or reflection ID")
+	}
+	var body struct {
+		Body string `json:"body"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	entry, err := h.feedbackService.Post(learnerID, reflectionID, feedbackAuthor(c), html.EscapeString(body.Body))
+	if err != nil {
+		return sendFeedbackError(c, err, "Error posting feedback")
+	}
+
+	auditFeedback(c, "POST_REFLECTION_FEEDBACK", entry, "Posted feedback")
+
+	return utils.SendResponse(c, fiber.StatusCreated, "Feedback posted", entry)
+}
+
+// EditFeedback changes the caller's own message.
+// PATCH /users/:id/reflections/:reflectionId/feedback/:entryId  { "body": "..." }
+func (h *FeedbackHandler) EditFeedback(c *fiber.Ctx) error {
+	learnerID, reflectionID, err := threadParams(c, "id")
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user or reflection ID")
+	}
+	entryID, err := primitive.ObjectIDFromHex(c.Params("entryId"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid feedback entry ID")
+	}
+	var body struct {
+		Body string `json:"body"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	entry, err := h.feedbackService.Edit(learnerID, reflectionID, entryID, feedbackAuthor(c), html.EscapeString(body.Body))
+	if err != nil {
+		return sendFeedbackError(c, err, "Error editing feedback")
+	}
+
+	auditFeedback(c, "EDIT_REFLECTION_FEEDBACK", entry, "Edited feedback")
+
+	return utils.SendResponse(c, fiber.StatusOK, "Feedback updated", entry)
+}
+
+// DeleteFeedback removes a message: the caller's own, or any for admins.
+// DELETE /users/:id/reflections/:reflectionId/feedback/:entryId
+func (h *FeedbackHandler) DeleteFeedback(c *fiber.Ctx) error {
+	learnerID, reflectionID, err := threadParams(c, "id")
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user or reflection ID")
+	}
+	entryID, err := primitive.ObjectIDFromHex(c.Params("entryId"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid feedback entry ID")
+	}
+
+	entry, err := h.feedbackService.Delete(learnerID, reflectionID, entryID, feedbackAuthor(c))
+	if err != nil {
+		return sendFeedbackError(c, err, "Error deleting feedback")
+	}
+
+	audit := auditFeedback(c, "DELETE_REFLECTION_FEEDBACK", entry, "Deleted feedback")
+	audit.Before, audit.After = audit.After, nil
+
+	return utils.SendResponse(c, fiber.StatusOK, "Feedback deleted", nil)
+}
+
+// GetUnreadFeedback lists the learner's reflections with staff replies they
+// have not read.
+// GET /users/:id/feedback/unread
+func (h *FeedbackHandler) GetUnreadFeedback(c *fiber.Ctx) error {
+	learnerID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+	ids, err := h.feedbackService.Unread(learnerID)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching unread feedback")
+	}
+	return utils.SendResponse(c, fiber.StatusOK, "Unread feedback retrieved", fiber.Map{
+		"count":          len(ids),
+		"reflection_ids": ids,
+	})
+}
+
+// UpdateReflectionFeedback is the single-string feedback endpoint older
+// admin clients still call. It now adds the text to the thread instead of
+// overwriting the previous feedback.
+// PUT /admin/users/:userId/reflections/:reflectionId/feedback  { "feedback": "..." }
+func (h *FeedbackHandler) UpdateReflectionFeedback(c *fiber.Ctx) error {
+	learnerID, reflectionID, err := threadParams(c, "userId")
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user or reflection ID")
+	}
+	var body struct {
+		Feedback string `json:"feedback"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	entry, err := h.feedbackService.Post(learnerID, reflectionID, feedbackAuthor(c), html.EscapeString(body.Feedback))
+	if err != nil {
+		return sendFeedbackError(c, err, "Error updating feedback")
+	}
+
+	auditFeedback(c, "UPDATE_REFLECTION_FEEDBACK", entry, "Left feedback")
+
+	return utils.SendResponse(c, fiber.StatusOK, "Feedback updated successfully", nil)
+}
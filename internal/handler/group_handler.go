@@ -0,0 +1,292 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"gofiber-baro/internal/domain"
+	"gofiber-baro/internal/service/group"
+	"gofiber-baro/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type GroupHandler struct {
+	groupService *group.Service
+}
+
+func NewGroupHandler(groupService *group.Service) *GroupHandler {
+	return &GroupHandler{groupService: groupService}
+}
+
+// groupQuery reads the optional group_id filter shared by the garden, stats
+// and export endpoints. It is zero when absent.
+func groupQuery(c *fiber.Ctx) (primitive.ObjectID, error) {
+	v := c.Query("group_id")
+	if v == "" {
+		return primitive.NilObjectID, nil
+	}
+	return primitive.ObjectIDFromHex(v)
+}
+
+type groupBody struct {
+	CohortNumber int              `json:"cohort_number"`
+	Kind         domain.GroupKind `json:"kind"`
+	Name         *string          `json:"name"`
+	Mentor       *string          `json:"mentor"`
+	ChannelURL   *string          `json:"channel_url"`
+	Description  *string          `json:"description"`
+}
+
+func (b groupBody) input() group.GroupInput {
+	return group.GroupInput{Name: b.Name, Mentor: b.Mentor, ChannelURL: b.ChannelURL, Description: b.Description}
+}
+
+func sendGroupError(c *fiber.Ctx, err error, fallback string) error {
+	switch err {
+	case domain.ErrGroupNotFound:
+		return utils.SendError(c, fiber.StatusNotFound, "Group not found")
+	case domain.ErrGroupExists:
+		return utils.SendError(c, fiber.StatusConflict, err.Error())
+	case domain.ErrInvalidGroupKind:
+		return utils.SendError(c, fiber.StatusBadRequest, err.Error())
+	}
+	return utils.SendError(c, fiber.StatusInternalServerError, fallback)
+}
+
+func auditGroup(c *fiber.Ctx, action string, g *domain.Group, details string) *domain.AuditLog {
+	entry := auditEntry(c)
+	entry.Action = action
+	entry.TargetType = "group"
+	entry.TargetID = g.ID
+	entry.TargetName = fmt.Sprintf("Cohort %d %s group %s", g.CohortNumber, g.Kind, g.Name)
+	entry.Details = details
+	return entry
+}
+
+// groupFields is the audited view of a group's metadata.
+func groupFields(g *domain.Group) map[string]interface{} {
+	return map[string]interface{}{
+		"name":        g.Name,
+		"mentor":      g.Mentor,
+		"channel_url": g.ChannelURL,
+		"description": g.Description,
+	}
+}
+
+// GetGroups lists a cohort's groups with member counts.
+// GET /admin/groups?cohort=7&kind=genmate
+func (h *GroupHandler) GetGroups(c *fiber.Ctx) error {
+	cohort := c.QueryInt("cohort", 0)
+	if cohort <= 0 {
+		return utils.SendError(c, fiber.StatusBadRequest, "cohort is required")
+	}
+	kind := domain.GroupKind(c.Query("kind"))
+	if kind != "" && !kind.Valid() {
+		return utils.SendError(c, fiber.StatusBadRequest, domain.ErrInvalidGroupKind.Error())
+	}
+
+	groups, err := h.groupService.List(cohort, kind)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching groups")
+	}
+	return utils.SendResponse(c, fiber.StatusOK, "Groups retrieved", groups)
+}
+
+// GetGroup returns one group with its member count.
+// GET /admin/groups/:id
+func (h *GroupHandler) GetGroup(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid group ID")
+	}
+	g, err := h.groupService.Get(id)
+	if err != nil {
+		return sendGroupError(c, err, "Error fetching group")
+	}
+	return utils.SendResponse(c, fiber.StatusOK, "Group retrieved", g)
+}
+
+// CreateGroup adds a project or genmate group to a cohort.
+// POST /admin/groups  { "cohort_number": 7, "kind": "genmate", "name": "Sunflowers", "mentor": "...", "channel_url": "..." }
+func (h *GroupHandler) CreateGroup(c *fiber.Ctx) error {
+	var body groupBody
+	if err := c.BodyParser(&body); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if body.CohortNumber <= 0 {
+		return utils.SendError(c, fiber.StatusBadRequest, "cohort_number is required")
+	}
+	if body.Name == nil || strings.TrimSpace(*body.Name) == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "name is required")
+	}
+
+	g, err := h.groupService.Create(body.CohortNumber, body.Kind, body.input())
+	if err != nil {
+		return sendGroupError(c, err, "Error creating group")
+	}
+
+	entry := auditGroup(c, "CREATE_GROUP", g, "Created group")
+	entry.After = groupFields(g)
+
+	return utils.SendResponse(c, fiber.StatusCreated, "Group created", g)
+}
+
+// UpdateGroup edits a group's name, mentor, channel link or description.
+// The cohort and kind cannot change.
+// PATCH /admin/groups/:id
+func (h *GroupHandler) UpdateGroup(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid group ID")
+	}
+	var body groupBody
+	if err := c.BodyParser(&body); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if body.Name != nil && strings.TrimSpace(*body.Name) == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "name cannot be empty")
+	}
+
+	before, err := h.groupService.Get(id)
+	if err != nil {
+		return sendGroupError(c, err, "Error fetching group")
+	}
+	g, err := h.groupService.Update(id, body.input())
+	if err != nil {
+		return sendGroupError(c, err, "Error updating group")
+	}
+
+	entry := auditGroup(c, "UPDATE_GROUP", g, "Updated group details")
+	entry.Before, entry.After = groupFields(before), groupFields(g)
+
+	return utils.SendResponse(c, fiber.StatusOK, "Group updated", g)
+}
+
+// DeleteGroup removes a group; its members are left without a group of
+// that kind.
+// DELETE /admin/groups/:id
+func (h *GroupHandler) DeleteGroup(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid group ID")
+	}
+	g, err := h.groupService.Delete(id)
+	if err != nil {
+		return sendGroupError(c, err, "Error deleting group")
+	}
+
+	entry := auditGroup(c, "DELETE_GROUP", g, "Deleted group")
+	entry.Before = groupFields(g)
+
+	return utils.SendResponse(c, fiber.StatusOK, "Group deleted", nil)
+}
+
+// GetGroupMembers lists a group's members.
+// GET /admin/groups/:id/members
+func (h *GroupHandler) GetGroupMembers(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid group ID")
+	}
+	users, err := h.groupService.Members(id)
+	if err != nil {
+		return sendGroupError(c, err, "Error fetching members")
+	}
+	members := make([]domain.UserSafe, len(users))
+	for i := range users {
+		members[i] = users[i].ToSafe()
+	}
+	return utils.SendResponse(c, fiber.StatusOK, "Members retrieved", members)
+}
+
+func parseUserIDs(raw []string) ([]primitive.ObjectID, error) {
+	ids := make([]primitive.ObjectID, 0, len(raw))
+	for _, s := range raw {
+		oid, err := primitive.ObjectIDFromHex(s)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, oid)
+	}
+	return ids, nil
+}
+
+// AddGroupMembers assigns one or many learners to a group, moving them out
+// of their previous group of the same kind. Users from other cohorts are
+// skipped.
+// POST /admin/groups/:id/members  { "user_ids": ["..."] }
+func (h *GroupHandler) AddGroupMembers(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid group ID")
+	}
+	var body struct {
+		UserIDs []string `json:"user_ids"`
+	}
+	if err := c.BodyParser(&body); err != nil || len(body.UserIDs) == 0 {
+		return utils.SendError(c, fiber.StatusBadRequest, "user_ids is required")
+	}
+	userIDs, err := parseUserIDs(body.UserIDs)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	g, assigned, skipped, err := h.groupService.AssignMembers(id, userIDs)
+	if err != nil {
+		return sendGroupError(c, err, "Error assigning members")
+	}
+
+	entry := auditGroup(c, "ASSIGN_GROUP_MEMBERS", g, fmt.Sprintf("Assigned %d users (%d skipped)", assigned, skipped))
+	entry.After = map[string]interface{}{"user_ids": body.UserIDs}
+
+	return utils.SendResponse(c, fiber.StatusOK, "Members assigned", fiber.Map{"assigned": assigned, "skipped": skipped})
+}
+
+// RemoveGroupMembers takes learners out of a group.
+// DELETE /admin/groups/:id/members  { "user_ids": ["..."] }
+func (h *GroupHandler) RemoveGroupMembers(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid group ID")
+	}
+	var body struct {
+		UserIDs []string `json:"user_ids"`
+	}
+	if err := c.BodyParser(&body); err != nil || len(body.UserIDs) == 0 {
+		return utils.SendError(c, fiber.StatusBadRequest, "user_ids is required")
+	}
+	userIDs, err := parseUserIDs(body.UserIDs)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	g, removed, err := h.groupService.RemoveMembers(id, userIDs)
+	if err != nil {
+		return sendGroupError(c, err, "Error removing members")
+	}
+
+	entry := auditGroup(c, "REMOVE_GROUP_MEMBERS", g, fmt.Sprintf("Removed %d users", removed))
+	entry.Before = map[string]interface{}{"user_ids": body.UserIDs}
+
+	return utils.SendResponse(c, fiber.StatusOK, "Members removed", fiber.Map{"removed": removed})
+}
+
+// MigrateGroups creates groups from users' free-text project_group and
+// genmate_group values and links the users. It also runs at startup and is
+// safe to repeat.
+// POST /admin/groups/migrate
+func (h *GroupHandler) MigrateGroups(c *fiber.Ctx) error {
+	result, err := h.groupService.MigrateLegacyNames()
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error migrating groups")
+	}
+
+	entry := auditEntry(c)
+	entry.Action = "MIGRATE_GROUPS"
+	entry.TargetType = "group"
+	entry.Details = fmt.Sprintf("Created %d groups, linked %d users", result.GroupsCreated, result.UsersLinked)
+
+	return utils.SendResponse(c, fiber.StatusOK, "Groups migrated", result)
+}
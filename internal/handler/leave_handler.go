@@ -1,9 +1,12 @@
 package handler
 
 import (
+	"fmt"
+
 	"gofiber-baro/internal/domain"
 	"gofiber-baro/internal/service/leave"
 	"gofiber-baro/internal/service/user"
+	"gofiber-baro/pkg/middleware"
 	"gofiber-baro/pkg/utils"
 
 	"github.com/gofiber/fiber/v2"
@@ -23,12 +26,12 @@ func NewLeaveHandler(leaveService *leave.Service, userService *user.Service) *Le
 }
 
 func (h *LeaveHandler) GetMyLeaveRequests(c *fiber.Ctx) error {
-	userID := c.Locals("userID")
-	if userID == nil {
-		return utils.SendError(c, fiber.StatusUnauthorized, "Unauthorized")
+	userID, ok := middleware.UserID(c)
+	if !ok {
+		return sendNoUser(c)
 	}
 
-	oid, err := primitive.ObjectIDFromHex(userID.(string))
+	oid, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID")
 	}
@@ -42,9 +45,9 @@ func (h *LeaveHandler) GetMyLeaveRequests(c *fiber.Ctx) error {
 }
 
 func (h *LeaveHandler) CreateLeaveRequest(c *fiber.Ctx) error {
-	userID := c.Locals("userID")
-	if userID == nil {
-		return utils.SendError(c, fiber.StatusUnauthorized, "Unauthorized")
+	userID, ok := middleware.UserID(c)
+	if !ok {
+		return sendNoUser(c)
 	}
 
 	type RequestBody struct {
@@ -63,7 +66,7 @@ func (h *LeaveHandler) CreateLeaveRequest(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusBadRequest, "Type, date, and reason are required")
 	}
 
-	oid, err := primitive.ObjectIDFromHex(userID.(string))
+	oid, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID")
 	}
@@ -81,7 +84,7 @@ func (h *LeaveHandler) CreateLeaveRequest(c *fiber.Ctx) error {
 		body.Date,
 		body.Reason,
 		false,
-		userID.(string),
+		userID,
 	)
 	if err != nil {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error creating leave request")
@@ -135,14 +138,22 @@ func (h *LeaveHandler) UpdateLeaveRequestStatus(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusBadRequest, "Invalid leave request ID")
 	}
 
-	userID := c.Locals("userID")
-	userOID, _ := primitive.ObjectIDFromHex(userID.(string))
+	userID, ok := middleware.UserID(c)
+	if !ok {
+		return sendNoUser(c)
+	}
+	userOID, _ := primitive.ObjectIDFromHex(userID)
 
-	user, err := h.getUserByID(userID.(string))
+	user, err := h.getUserByID(userID)
 	if err != nil {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching user")
 	}
 
+	previous, err := h.leaveService.GetLeaveRequestByID(oid)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusNotFound, "Leave request not found")
+	}
+
 	err = h.leaveService.UpdateLeaveRequestStatus(
 		oid,
 		domain.LeaveRequestStatus(body.Status),
@@ -154,6 +165,15 @@ func (h *LeaveHandler) UpdateLeaveRequestStatus(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error updating leave request")
 	}
 
+	entry := auditEntry(c)
+	entry.Action = "REVIEW_LEAVE_REQUEST"
+	entry.TargetType = "leave_request"
+	entry.TargetID = oid
+	entry.TargetName = previous.FirstName + " " + previous.LastName
+	entry.Details = fmt.Sprintf("Leave request for %s set to %s", previous.Date, body.Status)
+	entry.Before = map[string]interface{}{"status": previous.Status}
+	entry.After = map[string]interface{}{"status": body.Status, "review_notes": body.ReviewNotes}
+
 	return utils.SendResponse(c, fiber.StatusOK, "Leave request updated", nil)
 }
 
@@ -180,7 +200,10 @@ func (h *LeaveHandler) CreateLeaveRequestAdmin(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID")
 	}
 
-	adminID := c.Locals("userID").(string)
+	adminID, ok := middleware.UserID(c)
+	if !ok {
+		return sendNoUser(c)
+	}
 
 	var session *domain.AttendanceSession
 	if body.Session != nil {
@@ -206,6 +229,14 @@ func (h *LeaveHandler) CreateLeaveRequestAdmin(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error creating leave request")
 	}
 
+	entry := auditEntry(c)
+	entry.Action = "CREATE_LEAVE_REQUEST"
+	entry.TargetType = "leave_request"
+	entry.TargetID = request.ID
+	entry.TargetName = request.FirstName + " " + request.LastName
+	entry.Details = fmt.Sprintf("Recorded %s leave for %s", body.Type, body.Date)
+	entry.After = map[string]interface{}{"type": request.Type, "date": request.Date, "status": request.Status}
+
 	return utils.SendResponse(c, fiber.StatusOK, "Leave request created", request)
 }
 
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gofiber-baro/pkg/middleware"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+)
+
+// testKeys accepts every key with the leave permission and remembers the
+// uses recorded.
+type testKeys struct {
+	statuses []int
+}
+
+func (k *testKeys) AuthenticateAPIKey(ctx context.Context, key string) (string, []string, bool, error) {
+	return "key1", []string{string(middleware.PermLeaveManage)}, true, nil
+}
+
+func (k *testKeys) RecordAPIKeyUse(ctx context.Context, keyID, method, route, ip string, status int) {
+	k.statuses = append(k.statuses, status)
+}
+
+// TestLeaveReviewRejectsAPIKey sends an API key to a route that acts as the
+// signed-in user: it must be refused with 403, not panic, and the use must
+// still be recorded.
+func TestLeaveReviewRejectsAPIKey(t *testing.T) {
+	t.Setenv("JWT_SECRET_KEY", "test-secret")
+	keys := &testKeys{}
+	middleware.SetAPIKeyAuthenticator(keys)
+	t.Cleanup(func() { middleware.SetAPIKeyAuthenticator(nil) })
+
+	app := fiber.New()
+	app.Use(recover.New())
+	h := &LeaveHandler{}
+	app.Patch("/admin/leave-requests/:id", middleware.AuthMiddleware, h.UpdateLeaveRequestStatus)
+	app.Post("/admin/leave-requests", middleware.AuthMiddleware, h.CreateLeaveRequestAdmin)
+
+	tests := []struct {
+		method, path, body string
+	}{
+		{fiber.MethodPatch, "/admin/leave-requests/64b7f0c2e4b0a1a2b3c4d5e6", `{"status":"approved"}`},
+		{fiber.MethodPost, "/admin/leave-requests", `{"user_id":"64b7f0c2e4b0a1a2b3c4d5e6","type":"sick","date":"2025-03-03"}`},
+	}
+	for _, tt := range tests {
+		keys.statuses = nil
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Authorization", "Bearer baro_testkey")
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusForbidden {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, resp.StatusCode, fiber.StatusForbidden)
+		}
+		if len(keys.statuses) != 1 || keys.statuses[0] != fiber.StatusForbidden {
+			t.Errorf("%s %s recorded uses %v, want [%d]", tt.method, tt.path, keys.statuses, fiber.StatusForbidden)
+		}
+	}
+}
@@ -92,7 +92,10 @@ func (h *NotificationHandler) GetAllNotifications(c *fiber.Ctx) error {
 }
 
 func (h *NotificationHandler) GetActiveNotifications(c *fiber.Ctx) error {
-	notifications, err := h.notificationService.GetActiveNotifications()
+	userID := c.Locals("userID")
+	userIDStr, _ := userID.(string)
+
+	notifications, err := h.notificationService.GetActiveNotifications(userIDStr)
 	if err != nil {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching notifications: "+err.Error())
 	}
@@ -101,9 +104,6 @@ func (h *NotificationHandler) GetActiveNotifications(c *fiber.Ctx) error {
 		notifications = []domain.Notification{}
 	}
 
-	userID := c.Locals("userID")
-	userIDStr, _ := userID.(string)
-
 	if userIDStr != "" {
 		var unreadNotifications []map[string]interface{}
 		for _, n := range notifications {
@@ -118,6 +118,7 @@ func (h *NotificationHandler) GetActiveNotifications(c *fiber.Ctx) error {
 				"start_date": n.StartDate,
 				"end_date":   n.EndDate,
 				"created_at": n.CreatedAt,
+				"kind":       n.Kind,
 				"is_read": h.notificationService.IsNotificationReadByUser(&n, func() primitive.ObjectID {
 					id, _ := primitive.ObjectIDFromHex(userIDStr)
 					return id
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+
+	"gofiber-baro/internal/domain"
+	"gofiber-baro/internal/service/privacy"
+	middleware "gofiber-baro/pkg/middleware"
+	"gofiber-baro/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PrivacyHandler struct {
+	privacyService *privacy.Service
+}
+
+func NewPrivacyHandler(privacyService *privacy.Service) *PrivacyHandler {
+	return &PrivacyHandler{privacyService: privacyService}
+}
+
+func callerID(c *fiber.Ctx) primitive.ObjectID {
+	var id primitive.ObjectID
+	if claims, ok := c.Locals("user").(*middleware.Claims); ok {
+		id, _ = primitive.ObjectIDFromHex(claims.UserID)
+	}
+	return id
+}
+
+// ExportUserData downloads everything stored about a user as a ZIP of JSON
+// files and their uploaded images, for a PDPA access request.
+// GET /admin/users/:id/export
+func (h *PrivacyHandler) ExportUserData(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	data, err := h.privacyService.Export(userID, callerID(c))
+	if err != nil {
+		if err == domain.ErrUserNotFound {
+			return utils.SendError(c, fiber.StatusNotFound, "User not found")
+		}
+		log.Printf("[ERROR] ExportUserData %s: %v", userID.Hex(), err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error exporting user data")
+	}
+
+	filename := fmt.Sprintf("user_%s_%s.zip", userID.Hex(), utils.GetThailandTime().Format("2006-01-02"))
+	c.Set("Content-Type", "application/zip")
+	c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	return c.Send(data)
+}
+
+// EraseUserData queues a PDPA erasure. The privacy job anonymises the user
+// everywhere shortly after; follow it at GET /admin/privacy-requests.
+// POST /admin/users/:id/erase
+func (h *PrivacyHandler) EraseUserData(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	req, err := h.privacyService.RequestErasure(userID, callerID(c))
+	if err != nil {
+		switch err {
+		case domain.ErrUserNotFound:
+			return utils.SendError(c, fiber.StatusNotFound, "User not found")
+		case domain.ErrUserErased, domain.ErrPrivacyRequestOpen:
+			return utils.SendError(c, fiber.StatusConflict, err.Error())
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error requesting erasure")
+	}
+
+	auditUser(c, "REQUEST_ERASURE", userID, "Queued PDPA erasure")
+
+	return utils.SendResponse(c, fiber.StatusAccepted, "Erasure queued", req)
+}
+
+// GetPrivacyRequests lists export and erasure requests newest first.
+// GET /admin/privacy-requests?user_id=&page=1&limit=50
+func (h *PrivacyHandler) GetPrivacyRequests(c *fiber.Ctx) error {
+	var userID primitive.ObjectID
+	if v := c.Query("user_id"); v != "" {
+		oid, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			return utils.SendError(c, fiber.StatusBadRequest, "Invalid user_id")
+		}
+		userID = oid
+	}
+
+	page := c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+
+	requests, total, err := h.privacyService.List(userID, page, limit)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching privacy requests")
+	}
+
+	return utils.SendResponse(c, fiber.StatusOK, "Privacy requests retrieved", fiber.Map{
+		"requests": requests,
+		"total":    total,
+		"page":     page,
+		"limit":    limit,
+	})
+}
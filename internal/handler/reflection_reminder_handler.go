@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"time"
+
+	"gofiber-baro/internal/service/reflection"
+	"gofiber-baro/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ReflectionReminderHandler struct {
+	reminderService *reflection.ReminderService
+}
+
+func NewReflectionReminderHandler(reminderService *reflection.ReminderService) *ReflectionReminderHandler {
+	return &ReflectionReminderHandler{reminderService: reminderService}
+}
+
+// GetMissingReflections lists a cohort's active learners who haven't
+// reflected on a day (today in Bangkok by default). school_day is false on
+// weekends and holidays, when nobody is expected to.
+// GET /admin/reflections/missing?cohort=7&date=2025-01-31
+func (h *ReflectionReminderHandler) GetMissingReflections(c *fiber.Ctx) error {
+	cohort := c.QueryInt("cohort", 0)
+	if cohort <= 0 {
+		return utils.SendError(c, fiber.StatusBadRequest, "cohort is required")
+	}
+	day := c.Query("date", utils.GetThailandDate())
+	if _, err := time.Parse("2006-01-02", day); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "date must be YYYY-MM-DD")
+	}
+
+	schoolDay, err := h.reminderService.SchoolDay(day)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error checking holidays")
+	}
+	users, err := h.reminderService.Missing(cohort, day)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching reflections")
+	}
+
+	learners := make([]fiber.Map, 0, len(users))
+	for _, u := range users {
+		learners = append(learners, fiber.Map{
+			"_id":           u.ID,
+			"first_name":    u.FirstName,
+			"last_name":     u.LastName,
+			"zoom_name":     u.ZoomName,
+			"email":         u.Email,
+			"jsd_number":    u.JSDNumber,
+			"project_group": u.ProjectGroup,
+			"genmate_group": u.GenmateGroup,
+		})
+	}
+	return utils.SendResponse(c, fiber.StatusOK, "Learners without a reflection retrieved", fiber.Map{
+		"date":       day,
+		"school_day": schoolDay,
+		"count":      len(learners),
+		"learners":   learners,
+	})
+}
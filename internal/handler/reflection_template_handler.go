@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+
+	"gofiber-baro/internal/domain"
+	"gofiber-baro/internal/service/reflection"
+	middleware "gofiber-baro/pkg/middleware"
+	"gofiber-baro/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ReflectionTemplateHandler struct {
+	templateService *reflection.TemplateService
+}
+
+func NewReflectionTemplateHandler(templateService *reflection.TemplateService) *ReflectionTemplateHandler {
+	return &ReflectionTemplateHandler{templateService: templateService}
+}
+
+func sendTemplateError(c *fiber.Ctx, err error, fallback string) error {
+	switch {
+	case errors.Is(err, domain.ErrTemplateNotFound):
+		return utils.SendError(c, fiber.StatusNotFound, "Reflection template not found")
+	case errors.Is(err, domain.ErrInvalidTemplate):
+		return utils.SendError(c, fiber.StatusBadRequest, err.Error())
+	}
+	return utils.SendError(c, fiber.StatusInternalServerError, fallback)
+}
+
+func auditTemplate(c *fiber.Ctx, action string, t *domain.ReflectionTemplate, details string) *domain.AuditLog {
+	entry := auditEntry(c)
+	entry.Action = action
+	entry.TargetType = "reflection_template"
+	entry.TargetID = t.ID
+	entry.TargetName = fmt.Sprintf("Cohort %d template v%d", t.CohortNumber, t.Version)
+	entry.Details = details
+	return entry
+}
+
+// GetTemplates lists a cohort's template versions, newest first.
+// GET /admin/reflection-templates?cohort=7
+func (h *ReflectionTemplateHandler) GetTemplates(c *fiber.Ctx) error {
+	cohort := c.QueryInt("cohort", 0)
+	if cohort <= 0 {
+		return utils.SendError(c, fiber.StatusBadRequest, "cohort is required")
+	}
+	templates, err := h.templateService.List(cohort)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching reflection templates")
+	}
+	return utils.SendResponse(c, fiber.StatusOK, "Reflection templates retrieved", templates)
+}
+
+// GetTemplate returns one template version.
+// GET /admin/reflection-templates/:id
+func (h *ReflectionTemplateHandler) GetTemplate(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid template ID")
+	}
+	t, err := h.templateService.Get(id)
+	if err != nil {
+		return sendTemplateError(c, err, "Error fetching reflection template")
+	}
+	return utils.SendResponse(c, fiber.StatusOK, "Reflection template retrieved", t)
+}
+
+// CreateTemplate saves a new template version for a cohort. It becomes the
+// active one unless "activate" is false.
+// POST /admin/reflection-templates  { "cohort_number": 7, "questions": [{ "id": "mood", "type": "barometer", "prompt": "..." }] }
+func (h *ReflectionTemplateHandler) CreateTemplate(c *fiber.Ctx) error {
+	var body struct {
+		CohortNumber int                       `json:"cohort_number"`
+		Questions    []domain.TemplateQuestion `json:"questions"`
+		Activate     *bool                     `json:"activate"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if body.CohortNumber <= 0 {
+		return utils.SendError(c, fiber.StatusBadRequest, "cohort_number is required")
+	}
+
+	var createdBy primitive.ObjectID
+	if claims, ok := c.Locals("user").(*middleware.Claims); ok {
+		createdBy, _ = primitive.ObjectIDFromHex(claims.UserID)
+	}
+	activate := body.Activate == nil || *body.Activate
+
+	t, err := h.templateService.Publish(body.CohortNumber, body.Questions, createdBy, activate)
+	if err != nil {
+		return sendTemplateError(c, err, "Error saving reflection template")
+	}
+
+	details := "Saved template version"
+	if activate {
+		details = "Saved and activated template version"
+	}
+	entry := auditTemplate(c, "CREATE_REFLECTION_TEMPLATE", t, details)
+	entry.After = map[string]interface{}{"questions": t.Questions, "active": t.Active}
+
+	return utils.SendResponse(c, fiber.StatusCreated, "Reflection template saved", t)
+}
+
+// ActivateTemplate makes a version its cohort's active template.
+// POST /admin/reflection-templates/:id/activate
+func (h *ReflectionTemplateHandler) ActivateTemplate(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid template ID")
+	}
+	t, err := h.templateService.Activate(id)
+	if err != nil {
+		return sendTemplateError(c, err, "Error activating reflection template")
+	}
+
+	auditTemplate(c, "ACTIVATE_REFLECTION_TEMPLATE", t, "Activated template version")
+
+	return utils.SendResponse(c, fiber.StatusOK, "Reflection template activated", t)
+}
+
+// DeactivateTemplates puts a cohort back on the standard reflection form.
+// DELETE /admin/reflection-templates/active?cohort=7
+func (h *ReflectionTemplateHandler) DeactivateTemplates(c *fiber.Ctx) error {
+	cohort := c.QueryInt("cohort", 0)
+	if cohort <= 0 {
+		return utils.SendError(c, fiber.StatusBadRequest, "cohort is required")
+	}
+	if err := h.templateService.Deactivate(cohort); err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error deactivating reflection templates")
+	}
+
+	entry := auditEntry(c)
+	entry.Action = "DEACTIVATE_REFLECTION_TEMPLATE"
+	entry.TargetType = "reflection_template"
+	entry.TargetName = fmt.Sprintf("Cohort %d", cohort)
+	entry.Details = "Switched cohort to the standard reflection form"
+
+	return utils.SendResponse(c, fiber.StatusOK, "Cohort uses the standard reflection form", nil)
+}
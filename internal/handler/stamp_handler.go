@@ -54,7 +54,7 @@ func (h *StampHandler) GetCohort(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusBadRequest, "Invalid cohort")
 	}
 
-	if err := h.enforceCohortAccess(c, cohort); err != nil {
+	if ok, err := h.enforceCohortAccess(c, cohort); !ok {
 		return err
 	}
 
@@ -71,7 +71,7 @@ func (h *StampHandler) GetCohortStamps(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusBadRequest, "Invalid cohort")
 	}
 
-	if err := h.enforceCohortAccess(c, cohort); err != nil {
+	if ok, err := h.enforceCohortAccess(c, cohort); !ok {
 		return err
 	}
 
@@ -87,16 +87,16 @@ func (h *StampHandler) GetCohortStamps(c *fiber.Ctx) error {
 }
 
 func (h *StampHandler) CreateStamp(c *fiber.Ctx) error {
-	userID := c.Locals("userID")
-	if userID == nil {
-		return utils.SendError(c, fiber.StatusUnauthorized, "Unauthorized")
+	userID, ok := middleware.UserID(c)
+	if !ok {
+		return sendNoUser(c)
 	}
 
 	if h.storage == nil {
 		return utils.SendError(c, fiber.StatusServiceUnavailable, "Image storage is not configured")
 	}
 
-	userData, err := h.userService.GetUserByID(userID.(string))
+	userData, err := h.userService.GetUserByID(userID)
 	if err != nil {
 		return utils.SendError(c, fiber.StatusNotFound, "User not found")
 	}
@@ -299,27 +299,24 @@ func (h *StampHandler) DeleteStamp(c *fiber.Ctx) error {
 	return utils.SendResponse(c, fiber.StatusOK, "Stamp deleted", nil)
 }
 
-func (h *StampHandler) enforceCohortAccess(c *fiber.Ctx, cohort int) error {
-	userID := c.Locals("userID")
-	userRole := ""
-	if user, ok := c.Locals("user").(*middleware.Claims); ok {
-		userRole = user.Role
+// enforceCohortAccess reports whether the caller may see cohort; when it
+// may not, the refusal has been sent and err is what the handler returns.
+func (h *StampHandler) enforceCohortAccess(c *fiber.Ctx, cohort int) (bool, error) {
+	if middleware.Authorize(c, middleware.PermUsersRead, cohort) {
+		return true, nil
 	}
 
-	if userRole == "admin" {
-		return nil
+	userID, ok := middleware.UserID(c)
+	if !ok {
+		return false, sendNoUser(c)
 	}
 
-	if userID == nil {
-		return utils.SendError(c, fiber.StatusUnauthorized, "Unauthorized")
-	}
-
-	userData, err := h.userService.GetUserByID(userID.(string))
+	userData, err := h.userService.GetUserByID(userID)
 	if err != nil {
-		return utils.SendError(c, fiber.StatusUnauthorized, "User data not found")
+		return false, utils.SendError(c, fiber.StatusUnauthorized, "User data not found")
 	}
 	if cohort != userData.CohortNumber {
-		return utils.SendError(c, fiber.StatusForbidden, "You cannot access other cohorts")
+		return false, utils.SendError(c, fiber.StatusForbidden, "You cannot access other cohorts")
 	}
-	return nil
+	return true, nil
 }
@@ -31,15 +31,17 @@ func (h *TalkBoardHandler) GetPosts(c *fiber.Ctx) error {
 	ctx := c.Context()
 	cohort := c.QueryInt("cohort", 0)
 
-	// Security: If not admin, enforce user's own cohort
-	userID := c.Locals("userID")
-	userRole := ""
-	if user, ok := c.Locals("user").(*middleware.Claims); ok {
-		userRole = user.Role
-	}
-
-	if userRole != "admin" {
-		userData, err := h.userService.GetUserByID(userID.(string))
+	// Security: learners (and staff outside the requested cohort) get their own cohort
+	claims, _ := c.Locals("user").(*middleware.Claims)
+	isStaff := claims != nil && claims.Can(middleware.PermUsersRead) &&
+		(cohort > 0 || !middleware.IsScopedRole(claims.Role)) && claims.InScope(cohort)
+
+	if !isStaff {
+		userID, ok := middleware.UserID(c)
+		if !ok {
+			return sendNoUser(c)
+		}
+		userData, err := h.userService.GetUserByID(userID)
 		if err != nil {
 			return utils.SendError(c, fiber.StatusUnauthorized, "User data not found")
 		}
@@ -73,14 +75,12 @@ func (h *TalkBoardHandler) GetPost(c *fiber.Ctx) error {
 	}
 
 	// Security: If not admin, check if post belongs to user's cohort
-	userID := c.Locals("userID")
-	userRole := ""
-	if user, ok := c.Locals("user").(*middleware.Claims); ok {
-		userRole = user.Role
-	}
-
-	if userRole != "admin" {
-		userData, err := h.userService.GetUserByID(userID.(string))
+	if !middleware.Authorize(c, middleware.PermUsersRead, post.Cohort) {
+		userID, ok := middleware.UserID(c)
+		if !ok {
+			return sendNoUser(c)
+		}
+		userData, err := h.userService.GetUserByID(userID)
 		if err != nil {
 			return utils.SendError(c, fiber.StatusUnauthorized, "User data not found")
 		}
@@ -94,9 +94,9 @@ func (h *TalkBoardHandler) GetPost(c *fiber.Ctx) error {
 
 func (h *TalkBoardHandler) CreatePost(c *fiber.Ctx) error {
 	ctx := c.Context()
-	userID := c.Locals("userID")
-	if userID == nil {
-		return utils.SendError(c, fiber.StatusUnauthorized, "Unauthorized")
+	userID, ok := middleware.UserID(c)
+	if !ok {
+		return sendNoUser(c)
 	}
 
 	type RequestBody struct {
@@ -112,12 +112,12 @@ func (h *TalkBoardHandler) CreatePost(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusBadRequest, "Content is required")
 	}
 
-	userData, err := h.userService.GetUserByID(userID.(string))
+	userData, err := h.userService.GetUserByID(userID)
 	if err != nil {
 		return utils.SendError(c, fiber.StatusNotFound, "User not found")
 	}
 
-	userOID, _ := primitive.ObjectIDFromHex(userID.(string))
+	userOID, _ := primitive.ObjectIDFromHex(userID)
 
 	post := &domain.Post{
 		UserID:    userOID,
@@ -139,9 +139,9 @@ func (h *TalkBoardHandler) CreatePost(c *fiber.Ctx) error {
 
 func (h *TalkBoardHandler) AddComment(c *fiber.Ctx) error {
 	ctx := c.Context()
-	userID := c.Locals("userID")
-	if userID == nil {
-		return utils.SendError(c, fiber.StatusUnauthorized, "Unauthorized")
+	userID, ok := middleware.UserID(c)
+	if !ok {
+		return sendNoUser(c)
 	}
 
 	postID := c.Params("postId")
@@ -167,27 +167,22 @@ func (h *TalkBoardHandler) AddComment(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusBadRequest, "Content is required")
 	}
 
-	userData, err := h.userService.GetUserByID(userID.(string))
+	userData, err := h.userService.GetUserByID(userID)
 	if err != nil {
 		return utils.SendError(c, fiber.StatusNotFound, "User not found")
 	}
 
-	// Security Check: Verify post exists and belongs to user's cohort if not admin
+	// Security Check: Verify post exists and belongs to user's cohort unless staff
 	post, err := h.repo.FindByID(ctx, postOID)
 	if err != nil {
 		return utils.SendError(c, fiber.StatusNotFound, "Post not found")
 	}
 
-	userRole := ""
-	if user, ok := c.Locals("user").(*middleware.Claims); ok {
-		userRole = user.Role
-	}
-
-	if userRole != "admin" && post.Cohort != userData.CohortNumber {
+	if !middleware.Authorize(c, middleware.PermUsersRead, post.Cohort) && post.Cohort != userData.CohortNumber {
 		return utils.SendError(c, fiber.StatusForbidden, "You cannot comment on posts from other cohorts")
 	}
 
-	userOID, _ := primitive.ObjectIDFromHex(userID.(string))
+	userOID, _ := primitive.ObjectIDFromHex(userID)
 
 	comment := domain.Comment{
 		ID:        primitive.NewObjectID(),
@@ -208,9 +203,9 @@ func (h *TalkBoardHandler) AddComment(c *fiber.Ctx) error {
 
 func (h *TalkBoardHandler) AddReactionToPost(c *fiber.Ctx) error {
 	ctx := c.Context()
-	userID := c.Locals("userID")
-	if userID == nil {
-		return utils.SendError(c, fiber.StatusUnauthorized, "Unauthorized")
+	userID, ok := middleware.UserID(c)
+	if !ok {
+		return sendNoUser(c)
 	}
 
 	postID := c.Params("postId")
@@ -231,14 +226,9 @@ func (h *TalkBoardHandler) AddReactionToPost(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusNotFound, "Post not found")
 	}
 
-	// Security: If not admin, check if post belongs to user's cohort
-	userRole := ""
-	if user, ok := c.Locals("user").(*middleware.Claims); ok {
-		userRole = user.Role
-	}
-
-	if userRole != "admin" {
-		userData, err := h.userService.GetUserByID(userID.(string))
+	// Security: unless staff over this cohort, check if post belongs to user's cohort
+	if !middleware.Authorize(c, middleware.PermUsersRead, post.Cohort) {
+		userData, err := h.userService.GetUserByID(userID)
 		if err != nil {
 			return utils.SendError(c, fiber.StatusUnauthorized, "User data not found")
 		}
@@ -261,9 +251,9 @@ func (h *TalkBoardHandler) AddReactionToPost(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusBadRequest, "Reaction is required")
 	}
 
-	log.Printf("DEBUG: Adding reaction %s to post %s by user %s", body.Reaction, postID, userID.(string))
+	log.Printf("DEBUG: Adding reaction %s to post %s by user %s", body.Reaction, postID, userID)
 
-	userOID, err := primitive.ObjectIDFromHex(userID.(string))
+	userOID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		log.Printf("ERROR: Invalid user ID format: %v, userID: %v", err, userID)
 		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID format")
@@ -307,9 +297,9 @@ func (h *TalkBoardHandler) AddReactionToPost(c *fiber.Ctx) error {
 
 func (h *TalkBoardHandler) RemoveReactionFromPost(c *fiber.Ctx) error {
 	ctx := c.Context()
-	userID := c.Locals("userID")
-	if userID == nil {
-		return utils.SendError(c, fiber.StatusUnauthorized, "Unauthorized")
+	userID, ok := middleware.UserID(c)
+	if !ok {
+		return sendNoUser(c)
 	}
 
 	postID := c.Params("postId")
@@ -322,19 +312,14 @@ func (h *TalkBoardHandler) RemoveReactionFromPost(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusBadRequest, "Invalid post ID")
 	}
 
-	// Security Check: Verify post exists and belongs to user's cohort if not admin
+	// Security Check: Verify post exists and belongs to user's cohort unless staff
 	post, err := h.repo.FindByID(ctx, postOID)
 	if err != nil {
 		return utils.SendError(c, fiber.StatusNotFound, "Post not found")
 	}
 
-	userRole := ""
-	if user, ok := c.Locals("user").(*middleware.Claims); ok {
-		userRole = user.Role
-	}
-
-	if userRole != "admin" {
-		userData, err := h.userService.GetUserByID(userID.(string))
+	if !middleware.Authorize(c, middleware.PermUsersRead, post.Cohort) {
+		userData, err := h.userService.GetUserByID(userID)
 		if err != nil {
 			return utils.SendError(c, fiber.StatusUnauthorized, "User data not found")
 		}
@@ -343,7 +328,7 @@ func (h *TalkBoardHandler) RemoveReactionFromPost(c *fiber.Ctx) error {
 		}
 	}
 
-	userOID, err := primitive.ObjectIDFromHex(userID.(string))
+	userOID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID format")
 	}
@@ -358,9 +343,9 @@ func (h *TalkBoardHandler) RemoveReactionFromPost(c *fiber.Ctx) error {
 }
 
 func (h *TalkBoardHandler) AddReactionToComment(c *fiber.Ctx) error {
-	userID := c.Locals("userID")
-	if userID == nil {
-		return utils.SendError(c, fiber.StatusUnauthorized, "Unauthorized")
+	userID, ok := middleware.UserID(c)
+	if !ok {
+		return sendNoUser(c)
 	}
 
 	postID := c.Params("postId")
@@ -389,13 +374,8 @@ func (h *TalkBoardHandler) AddReactionToComment(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusNotFound, "Post not found")
 	}
 
-	userRole := ""
-	if user, ok := c.Locals("user").(*middleware.Claims); ok {
-		userRole = user.Role
-	}
-
-	if userRole != "admin" {
-		userData, err := h.userService.GetUserByID(userID.(string))
+	if !middleware.Authorize(c, middleware.PermUsersRead, post.Cohort) {
+		userData, err := h.userService.GetUserByID(userID)
 		if err != nil {
 			return utils.SendError(c, fiber.StatusUnauthorized, "User data not found")
 		}
@@ -417,7 +397,7 @@ func (h *TalkBoardHandler) AddReactionToComment(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusBadRequest, "Reaction is required")
 	}
 
-	userOID, err := primitive.ObjectIDFromHex(userID.(string))
+	userOID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID format")
 	}
@@ -443,9 +423,8 @@ func (h *TalkBoardHandler) AddReactionToComment(c *fiber.Ctx) error {
 
 func (h *TalkBoardHandler) DeletePost(c *fiber.Ctx) error {
 	ctx := c.Context()
-	userID := c.Locals("userID")
-	if userID == nil {
-		return utils.SendError(c, fiber.StatusUnauthorized, "Unauthorized")
+	if _, ok := middleware.UserID(c); !ok {
+		return sendNoUser(c)
 	}
 
 	postID := c.Params("postId")
@@ -463,17 +442,15 @@ func (h *TalkBoardHandler) DeletePost(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusNotFound, "Post not found")
 	}
 
-	userRole := ""
 	currentUserID := ""
 	if user, ok := c.Locals("user").(*middleware.Claims); ok {
-		userRole = user.Role
 		currentUserID = user.UserID
 	}
 
-	isAdmin := userRole == "admin"
+	isModerator := middleware.Authorize(c, middleware.PermBoardModerate, post.Cohort)
 	isOwner := post.UserID.Hex() == currentUserID
 
-	if !isAdmin && !isOwner {
+	if !isModerator && !isOwner {
 		return utils.SendError(c, fiber.StatusForbidden, "You can only delete your own posts")
 	}
 
@@ -486,9 +463,8 @@ func (h *TalkBoardHandler) DeletePost(c *fiber.Ctx) error {
 
 func (h *TalkBoardHandler) DeleteComment(c *fiber.Ctx) error {
 	ctx := c.Context()
-	userID := c.Locals("userID")
-	if userID == nil {
-		return utils.SendError(c, fiber.StatusUnauthorized, "Unauthorized")
+	if _, ok := middleware.UserID(c); !ok {
+		return sendNoUser(c)
 	}
 
 	postID := c.Params("postId")
@@ -525,17 +501,15 @@ func (h *TalkBoardHandler) DeleteComment(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusNotFound, "Comment not found")
 	}
 
-	userRole := ""
 	currentUserID := ""
 	if user, ok := c.Locals("user").(*middleware.Claims); ok {
-		userRole = user.Role
 		currentUserID = user.UserID
 	}
 
-	isAdmin := userRole == "admin"
+	isModerator := middleware.Authorize(c, middleware.PermBoardModerate, post.Cohort)
 	isOwner := commentFound.UserID.Hex() == currentUserID
 
-	if !isAdmin && !isOwner {
+	if !isModerator && !isOwner {
 		return utils.SendError(c, fiber.StatusForbidden, "You can only delete your own comments")
 	}
 
@@ -4,8 +4,11 @@ import (
 	"errors"
 	"html"
 	"log"
+	"strconv"
 	"time"
 	"gofiber-baro/internal/domain"
+	"gofiber-baro/internal/service/session"
+	"gofiber-baro/internal/service/sso"
 	"gofiber-baro/internal/service/user"
 	middleware "gofiber-baro/pkg/middleware"
 	"gofiber-baro/pkg/utils"
@@ -18,6 +21,10 @@ import (
 type UserHandler struct {
 	userService       *user.Service
 	fertilizerService *user.FertilizerService
+	sessionService    *session.Service
+	loginGuard        *session.LoginGuard
+	totpService       *user.TOTPService
+	ssoService        *sso.Service
 	db                interface{}
 }
 
@@ -48,8 +55,8 @@ var validPlantLeaves = map[string]bool{"rounded": true, "pointed": true, "wide":
 var validPlantFlowers = map[string]bool{"daisy": true, "tulip": true, "star": true}
 var validPlantStems = map[string]bool{"straight": true, "curved": true, "leaning": true}
 
-func NewUserHandler(userService *user.Service, fertilizerService *user.FertilizerService) *UserHandler {
-	return &UserHandler{userService: userService, fertilizerService: fertilizerService}
+func NewUserHandler(userService *user.Service, fertilizerService *user.FertilizerService, sessionService *session.Service, loginGuard *session.LoginGuard, totpService *user.TOTPService, ssoService *sso.Service) *UserHandler {
+	return &UserHandler{userService: userService, fertilizerService: fertilizerService, sessionService: sessionService, loginGuard: loginGuard, totpService: totpService, ssoService: ssoService}
 }
 
 func (h *UserHandler) LoginUser(c *fiber.Ctx) error {
@@ -66,15 +73,175 @@ func (h *UserHandler) LoginUser(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusBadRequest, "Email and password are required")
 	}
 
-	token, role, userId, err := h.authenticateUser(loginData.Email, loginData.Password)
+	// Throttling is per email, known or not, so it can't be used to probe
+	// which accounts exist.
+	if blocked, err := h.checkLoginThrottle(c, loginData.Email); blocked {
+		return err
+	}
+
+	u, err := h.authenticateUser(loginData.Email, loginData.Password)
+	if err != nil {
+		h.loginGuard.Failure(loginData.Email, c.IP())
+		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid credentials")
+	}
+
+	// With two-factor on, the password only earns a short-lived challenge;
+	// tokens come from /login/2fa. The failure count isn't cleared until
+	// then, so guessing codes is throttled like guessing passwords.
+	if u.TOTPEnabled {
+		return h.sendTOTPChallenge(c, u)
+	}
+
+	h.loginGuard.Success(loginData.Email)
+	return h.completeLogin(c, u)
+}
+
+// OIDCLoginURL starts single sign-on: the client sends the browser to
+// authUrl and keeps state to post back with the code.
+// GET /login/oidc
+func (h *UserHandler) OIDCLoginURL(c *fiber.Ctx) error {
+	authURL, state, err := h.ssoService.AuthURL()
+	if err != nil {
+		if errors.Is(err, domain.ErrSSODisabled) {
+			return utils.SendError(c, fiber.StatusNotFound, err.Error())
+		}
+		log.Printf("[ERROR] OIDCLoginURL: %v", err)
+		return utils.SendError(c, fiber.StatusBadGateway, "Identity provider unavailable")
+	}
+
+	return utils.SendResponse(c, fiber.StatusOK, "Redirect to identity provider", map[string]interface{}{
+		"authUrl": authURL,
+		"state":   state,
+	})
+}
+
+// OIDCLogin finishes single sign-on with the code the identity provider
+// redirected back with. The response matches POST /login, including the
+// two-factor challenge for users who have it on.
+// POST /login/oidc { "code": "...", "state": "..." }
+func (h *UserHandler) OIDCLogin(c *fiber.Ctx) error {
+	var body struct {
+		Code  string `json:"code"`
+		State string `json:"state"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if body.Code == "" || body.State == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Code and state are required")
+	}
+
+	u, err := h.ssoService.Authenticate(body.Code, body.State)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrSSODisabled):
+			return utils.SendError(c, fiber.StatusNotFound, err.Error())
+		case errors.Is(err, domain.ErrSSOInvalidState), errors.Is(err, domain.ErrSSOInvalidToken),
+			errors.Is(err, domain.ErrSSOEmailUnverified):
+			return utils.SendError(c, fiber.StatusUnauthorized, err.Error())
+		case errors.Is(err, domain.ErrSSODomainNotAllowed):
+			return utils.SendError(c, fiber.StatusForbidden, err.Error())
+		case errors.Is(err, domain.ErrUserNotFound):
+			return utils.SendError(c, fiber.StatusForbidden, "No Baro account uses this email")
+		}
+		log.Printf("[ERROR] OIDCLogin: %v", err)
+		return utils.SendError(c, fiber.StatusBadGateway, "Identity provider unavailable")
+	}
+
+	if u.TOTPEnabled {
+		return h.sendTOTPChallenge(c, u)
+	}
+	return h.completeLogin(c, u)
+}
+
+// sendTOTPChallenge answers the first login step for a user with two-factor
+// on: no tokens yet, just a short-lived challenge for /login/2fa.
+func (h *UserHandler) sendTOTPChallenge(c *fiber.Ctx, u *domain.User) error {
+	challenge, err := utils.GenerateLoginChallenge(u.ID)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Could not generate token")
+	}
+	return utils.SendResponse(c, fiber.StatusOK, "Two-factor code required", map[string]interface{}{
+		"twoFactorRequired": true,
+		"challenge":         challenge,
+		"expiresIn":         int(utils.LoginChallengeTTL.Seconds()),
+	})
+}
+
+// LoginTOTP is the second step of a two-factor login: the challenge from
+// LoginUser plus a code from the authenticator app or a recovery code.
+// POST /login/2fa { "challenge": "...", "code": "123456" }
+func (h *UserHandler) LoginTOTP(c *fiber.Ctx) error {
+	var body struct {
+		Challenge string `json:"challenge"`
+		Code      string `json:"code"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if body.Challenge == "" || body.Code == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Challenge and code are required")
+	}
+
+	userID, err := utils.ParseLoginChallenge(body.Challenge)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusUnauthorized, "Login challenge expired. Please sign in again")
+	}
+	u, err := h.userService.GetUserByID(userID)
 	if err != nil {
 		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid credentials")
 	}
 
+	if blocked, err := h.checkLoginThrottle(c, u.Email); blocked {
+		return err
+	}
+
+	if err := h.totpService.Verify(u, body.Code); err != nil {
+		if errors.Is(err, domain.ErrInvalidTOTPCode) || errors.Is(err, domain.ErrTOTPNotEnrolled) {
+			h.loginGuard.Failure(u.Email, c.IP())
+			return utils.SendError(c, fiber.StatusUnauthorized, "Invalid two-factor code")
+		}
+		log.Printf("[ERROR] LoginTOTP: verify %s: %v", u.ID.Hex(), err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Could not verify code")
+	}
+	h.loginGuard.Success(u.Email)
+
+	return h.completeLogin(c, u)
+}
+
+// checkLoginThrottle answers 429 with Retry-After when email is locked out
+// or must wait. blocked reports whether the response has been sent.
+func (h *UserHandler) checkLoginThrottle(c *fiber.Ctx, email string) (blocked bool, err error) {
+	retryAt, err := h.loginGuard.Check(email)
+	switch {
+	case errors.Is(err, domain.ErrAccountLocked), errors.Is(err, domain.ErrLoginThrottled):
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(time.Until(retryAt).Seconds())+1))
+		return true, utils.SendError(c, fiber.StatusTooManyRequests, err.Error())
+	case err != nil:
+		log.Printf("[ERROR] login throttle check: %v", err)
+	}
+	return false, nil
+}
+
+// completeLogin opens a session for an authenticated user.
+func (h *UserHandler) completeLogin(c *fiber.Ctx, u *domain.User) error {
+	tokens, err := h.sessionService.Login(u, c.Get(fiber.HeaderUserAgent), c.IP())
+	if err != nil {
+		if errors.Is(err, domain.ErrUserDisabled) || errors.Is(err, domain.ErrUserNotFound) {
+			return utils.SendError(c, fiber.StatusUnauthorized, "Invalid credentials")
+		}
+		return utils.SendError(c, fiber.StatusInternalServerError, "Could not generate token")
+	}
+
 	return utils.SendResponse(c, fiber.StatusOK, "Login successful", map[string]interface{}{
-		"token":  token,
-		"role":   role,
-		"userId": userId,
+		"token":        tokens.AccessToken,
+		"refreshToken": tokens.RefreshToken,
+		"expiresIn":    tokens.ExpiresIn,
+		"role":         u.Role,
+		"userId":       u.ID.Hex(),
+		// The client should send the user straight to change-password;
+		// every other route answers 403 until then.
+		"mustChangePassword": u.MustChangePassword,
 	})
 }
 
@@ -84,10 +251,14 @@ func (h *UserHandler) VerifyToken(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid token claims")
 	}
 
-	return utils.SendResponse(c, fiber.StatusOK, "Token is valid", map[string]string{
+	data := map[string]string{
 		"role":   claims.Role,
 		"userId": claims.UserID,
-	})
+	}
+	if claims.Impersonator != "" {
+		data["impersonator"] = claims.Impersonator
+	}
+	return utils.SendResponse(c, fiber.StatusOK, "Token is valid", data)
 }
 
 func (h *UserHandler) CreateReflection(c *fiber.Ctx) error {
@@ -97,49 +268,14 @@ func (h *UserHandler) CreateReflection(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID")
 	}
 
-	claims, ok := c.Locals("user").(*middleware.Claims)
-	if !ok {
-		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid token claims")
-	}
-
-	if claims.Role != "admin" && claims.UserID != userID {
-		return utils.SendError(c, fiber.StatusForbidden, "You are not allowed to post reflection for this user")
-	}
+	// Access (self, or staff over this learner's cohort) is enforced by the route.
 
 	var reflection domain.Reflection
 	if err := c.BodyParser(&reflection); err != nil {
 		return utils.SendError(c, fiber.StatusBadRequest, "Invalid reflection data")
 	}
 
-	// Barometer Validation & Sanitization
-	validZones := map[string]bool{
-		"Comfort Zone":                           true,
-		"Stretch Zone - Enjoying the Challenges": true,
-		"Stretch Zone - Overwhelmed":             true,
-		"Panic Zone":                             true,
-		// Also allow lowercase variations as seen in the codebase
-		"Stretch zone - Enjoying the challenges": true,
-		"Stretch zone - Overwhelmed":             true,
-	}
-
-	if !validZones[reflection.ReflectionData.Barometer] {
-		return utils.SendError(c, fiber.StatusBadRequest, "Invalid Barometer zone selected. Please choose from the provided options.")
-	}
-
-	// Sanitize all text inputs to prevent XSS
-	reflection.ReflectionData.Barometer = html.EscapeString(reflection.ReflectionData.Barometer)
-
-	for i, s := range reflection.ReflectionData.TechSessions.SessionName {
-		reflection.ReflectionData.TechSessions.SessionName[i] = html.EscapeString(s)
-	}
-	reflection.ReflectionData.TechSessions.Happy = html.EscapeString(reflection.ReflectionData.TechSessions.Happy)
-	reflection.ReflectionData.TechSessions.Improve = html.EscapeString(reflection.ReflectionData.TechSessions.Improve)
-
-	for i, s := range reflection.ReflectionData.NonTechSessions.SessionName {
-		reflection.ReflectionData.NonTechSessions.SessionName[i] = html.EscapeString(s)
-	}
-	reflection.ReflectionData.NonTechSessions.Happy = html.EscapeString(reflection.ReflectionData.NonTechSessions.Happy)
-	reflection.ReflectionData.NonTechSessions.Improve = html.EscapeString(reflection.ReflectionData.NonTechSessions.Improve)
+	sanitizeReflection(&reflection)
 
 	reflection.UserID = objectID
 	if reflection.Date.IsZero() {
@@ -149,9 +285,15 @@ func (h *UserHandler) CreateReflection(c *fiber.Ctx) error {
 
 	createdReflection, err := h.createReflection(objectID, reflection)
 	if err != nil {
-		if err.Error() == "user has already created a reflection today" {
+		if err == domain.ErrInvalidBarometerZone {
+			return utils.SendError(c, fiber.StatusBadRequest, "Invalid Barometer zone selected. Please choose from the provided options.")
+		}
+		if err == domain.ErrReflectionExists {
 			return utils.SendError(c, fiber.StatusConflict, "You have already submitted a reflection today. Please try again tomorrow.")
 		}
+		if errors.Is(err, domain.ErrInvalidAnswers) {
+			return utils.SendError(c, fiber.StatusBadRequest, err.Error())
+		}
 		log.Printf("CreateReflection error for user %s: %v", objectID.Hex(), err)
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error creating reflection")
 	}
@@ -159,22 +301,79 @@ func (h *UserHandler) CreateReflection(c *fiber.Ctx) error {
 	return utils.SendResponse(c, fiber.StatusCreated, "Reflection successfully created", createdReflection)
 }
 
-func (h *UserHandler) GetUserReflections(c *fiber.Ctx) error {
-	userID := c.Params("id")
-	objectID, err := primitive.ObjectIDFromHex(userID)
+// sanitizeReflection escapes the free text of a submitted reflection to
+// prevent XSS. The barometer zone is checked against domain.BarometerZones
+// by the service.
+func sanitizeReflection(r *domain.Reflection) {
+	for i, s := range r.ReflectionData.TechSessions.SessionName {
+		r.ReflectionData.TechSessions.SessionName[i] = html.EscapeString(s)
+	}
+	r.ReflectionData.TechSessions.Happy = html.EscapeString(r.ReflectionData.TechSessions.Happy)
+	r.ReflectionData.TechSessions.Improve = html.EscapeString(r.ReflectionData.TechSessions.Improve)
+
+	for i, s := range r.ReflectionData.NonTechSessions.SessionName {
+		r.ReflectionData.NonTechSessions.SessionName[i] = html.EscapeString(s)
+	}
+	r.ReflectionData.NonTechSessions.Happy = html.EscapeString(r.ReflectionData.NonTechSessions.Happy)
+	r.ReflectionData.NonTechSessions.Improve = html.EscapeString(r.ReflectionData.NonTechSessions.Improve)
+
+	// Template answers: choices are checked against the template's options
+	// by the service, free text is escaped here.
+	for i := range r.Answers {
+		r.Answers[i].Text = html.EscapeString(r.Answers[i].Text)
+	}
+}
+
+// UpdateReflection lets a learner correct one of their reflections while
+// the edit window is open (by default, the rest of its Bangkok day). The old
+// content is kept as a revision.
+// PUT /users/:id/reflections/:reflectionId
+func (h *UserHandler) UpdateReflection(c *fiber.Ctx) error {
+	userID, err := primitive.ObjectIDFromHex(c.Params("id"))
 	if err != nil {
 		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID")
 	}
+	reflectionID, err := primitive.ObjectIDFromHex(c.Params("reflectionId"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid reflection ID")
+	}
 
-	claims, ok := c.Locals("user").(*middleware.Claims)
-	if !ok {
-		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid token claims")
+	var reflection domain.Reflection
+	if err := c.BodyParser(&reflection); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid reflection data")
+	}
+	sanitizeReflection(&reflection)
+
+	updated, err := h.userService.UpdateReflection(userID, reflectionID, reflection)
+	switch {
+	case err == nil:
+	case err == domain.ErrReflectionNotFound:
+		return utils.SendError(c, fiber.StatusNotFound, "Reflection not found")
+	case err == domain.ErrReflectionEditClosed:
+		return utils.SendError(c, fiber.StatusForbidden, "This reflection can no longer be edited.")
+	case err == domain.ErrReflectionEditConflict:
+		return utils.SendError(c, fiber.StatusConflict, "This reflection was changed in the meantime. Please reload and try again.")
+	case err == domain.ErrInvalidBarometerZone:
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid Barometer zone selected. Please choose from the provided options.")
+	case errors.Is(err, domain.ErrInvalidAnswers):
+		return utils.SendError(c, fiber.StatusBadRequest, err.Error())
+	default:
+		log.Printf("UpdateReflection error for user %s: %v", userID.Hex(), err)
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error updating reflection")
 	}
 
-	if claims.Role != "admin" && claims.UserID != userID {
-		return utils.SendError(c, fiber.StatusForbidden, "You are not allowed to access this user's reflections")
+	return utils.SendResponse(c, fiber.StatusOK, "Reflection updated", updated)
+}
+
+func (h *UserHandler) GetUserReflections(c *fiber.Ctx) error {
+	userID := c.Params("id")
+	objectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID")
 	}
 
+	// Access (self, or staff over this learner's cohort) is enforced by the route.
+
 	reflections, err := h.getReflections(objectID)
 	if err != nil {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error retrieving reflections")
@@ -183,6 +382,28 @@ func (h *UserHandler) GetUserReflections(c *fiber.Ctx) error {
 	return utils.SendResponse(c, fiber.StatusOK, "User reflections retrieved", reflections)
 }
 
+// GetReflectionTemplate returns the questions the learner's cohort answers
+// in a reflection. The data is null when the cohort uses the standard form.
+// GET /users/:id/reflection-template
+func (h *UserHandler) GetReflectionTemplate(c *fiber.Ctx) error {
+	objectID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	template, err := h.userService.GetReflectionTemplate(objectID)
+	if err == domain.ErrUserNotFound {
+		return utils.SendError(c, fiber.StatusNotFound, "User not found")
+	}
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error retrieving reflection template")
+	}
+	if template == nil {
+		return utils.SendResponse(c, fiber.StatusOK, "Cohort uses the standard reflection form", nil)
+	}
+	return utils.SendResponse(c, fiber.StatusOK, "Reflection template retrieved", template)
+}
+
 func (h *UserHandler) GetCohort(c *fiber.Ctx) error {
 	cohort := c.Params("cohort")
 	if cohort == "" {
@@ -213,10 +434,11 @@ func (h *UserHandler) GetUserByID(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusNotFound, "User not found")
 	}
 
-	isAdmin := claims.Role == "admin"
-
-	// Admin sees everything, user sees their own profile with limited data
-	if isAdmin {
+	// Staff over this user's cohort see everything, others get limited data
+	if middleware.Authorize(c, middleware.PermUsersRead, user.CohortNumber) {
+		if user, err = h.userService.GetUserWithReflections(id); err != nil {
+			return utils.SendError(c, fiber.StatusInternalServerError, "Error retrieving reflections")
+		}
 		user.Password = ""
 		return utils.SendResponse(c, fiber.StatusOK, "User retrieved", user)
 	}
@@ -230,13 +452,22 @@ func (h *UserHandler) GetUserByID(c *fiber.Ctx) error {
 	return utils.SendResponse(c, fiber.StatusOK, "User retrieved", user.ToSafe())
 }
 
+// sendNoUser refuses a request that has no signed-in user. API keys act for
+// no user, so routes that act as one answer them with 403.
+func sendNoUser(c *fiber.Ctx) error {
+	if claims, ok := c.Locals("user").(*middleware.Claims); ok && claims.APIKeyID != "" {
+		return utils.SendError(c, fiber.StatusForbidden, "API keys cannot be used on this route")
+	}
+	return utils.SendError(c, fiber.StatusUnauthorized, "Unauthorized")
+}
+
 func (h *UserHandler) GetUserProfile(c *fiber.Ctx) error {
-	userID := c.Locals("userID")
-	if userID == nil {
-		return utils.SendError(c, fiber.StatusUnauthorized, "Unauthorized")
+	userID, ok := middleware.UserID(c)
+	if !ok {
+		return sendNoUser(c)
 	}
 
-	user, err := h.userService.GetUserByID(userID.(string))
+	user, err := h.userService.GetUserWithReflections(userID)
 	if err != nil {
 		return utils.SendError(c, fiber.StatusNotFound, "User not found")
 	}
@@ -252,25 +483,45 @@ func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusBadRequest, "User ID is required")
 	}
 
-	claims, ok := c.Locals("user").(*middleware.Claims)
-	if !ok {
-		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid token claims")
+	// Only users:manage reaches here (see routes); learners use the
+	// personal-details endpoint.
+	var body map[string]interface{}
+	if err := c.BodyParser(&body); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	// Security: ONLY admins can use the general update endpoint
-	if claims.Role != "admin" {
-		return utils.SendError(c, fiber.StatusForbidden, "Only admins can update general user information. Learners should use the personal-details endpoint.")
+	// Cohort changes have to go through the transfer endpoint so history,
+	// attendance and tokens stay consistent.
+	if _, ok := body["cohort_number"]; ok {
+		return utils.SendError(c, fiber.StatusBadRequest, "Use POST /admin/users/:id/cohort-transfer to change a learner's cohort")
+	}
+	// Likewise roles go through PUT /admin/users/:id/role, which needs
+	// roles:manage and revokes stale tokens.
+	for _, key := range []string{"role", "staff_cohorts"} {
+		if _, ok := body[key]; ok {
+			return utils.SendError(c, fiber.StatusBadRequest, "Use PUT /admin/users/:id/role to change a user's role")
+		}
+	}
+	// Group membership goes through the groups endpoints, which keep the ID
+	// and name in step.
+	for _, key := range []string{"project_group", "project_group_id", "genmate_group", "genmate_group_id"} {
+		if _, ok := body[key]; ok {
+			return utils.SendError(c, fiber.StatusBadRequest, "Use POST /admin/groups/:id/members to change a user's group")
+		}
 	}
 
-	var body map[string]interface{}
-	if err := c.BodyParser(&body); err != nil {
-		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	target, err := h.userService.GetUserByID(id)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusNotFound, "User not found")
 	}
 
 	if err := h.userService.UpdateUser(id, body); err != nil {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error updating user")
 	}
 
+	entry := auditUser(c, "UPDATE_USER", target.ID, "Updated user details")
+	entry.Before, entry.After = changedFields(target, body)
+
 	return utils.SendResponse(c, fiber.StatusOK, "User updated successfully", nil)
 }
 
@@ -451,76 +702,61 @@ func (h *UserHandler) AwardBadge(c *fiber.Ctx) error {
 	return utils.SendResponse(c, fiber.StatusOK, "Badge awarded successfully", nil)
 }
 
-func (h *UserHandler) UpdateReflectionFeedback(c *fiber.Ctx) error {
-	type RequestBody struct {
-		UserID       string `json:"user_id"`
-		ReflectionID string `json:"reflection_id"`
-		Feedback     string `json:"feedback"`
-	}
-
-	var body RequestBody
-	if err := c.BodyParser(&body); err != nil {
-		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
-	}
-
-	if body.UserID == "" || body.ReflectionID == "" {
-		return utils.SendError(c, fiber.StatusBadRequest, "User ID and reflection ID are required")
-	}
-
-	userID, err := primitive.ObjectIDFromHex(body.UserID)
-	if err != nil {
-		return utils.SendError(c, fiber.StatusBadRequest, "Invalid user ID")
-	}
-
-	reflectionID, err := primitive.ObjectIDFromHex(body.ReflectionID)
-	if err != nil {
-		return utils.SendError(c, fiber.StatusBadRequest, "Invalid reflection ID")
-	}
-
-	if err := h.userService.UpdateReflectionFeedback(userID, reflectionID, body.Feedback); err != nil {
-		return utils.SendError(c, fiber.StatusInternalServerError, "Error updating feedback")
-	}
-
-	return utils.SendResponse(c, fiber.StatusOK, "Feedback updated successfully", nil)
-}
-
 func (h *UserHandler) GetGenmateGarden(c *fiber.Ctx) error {
 	claims, ok := c.Locals("user").(*middleware.Claims)
 	if !ok {
 		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid token claims")
 	}
 
-	if claims.Cohort <= 0 {
-		return utils.SendResponse(c, fiber.StatusOK, "Genmate garden retrieved", fiber.Map{
-			"users": []interface{}{},
-		})
-	}
-
+	// Learners see their own genmate group; staff may pass ?group_id= for
+	// any group in a cohort they can read.
 	me, err := h.userService.GetUserByID(claims.UserID)
-	if err != nil || me.GenmateGroup == "" {
+	if err != nil {
+		return utils.SendError(c, fiber.StatusNotFound, "User not found")
+	}
+	var groupID primitive.ObjectID
+	if me.GenmateGroupID != nil {
+		groupID = *me.GenmateGroupID
+	}
+	if v := c.Query("group_id"); v != "" {
+		if groupID, err = primitive.ObjectIDFromHex(v); err != nil {
+			return utils.SendError(c, fiber.StatusBadRequest, "Invalid group_id")
+		}
+	}
+	if groupID.IsZero() {
 		return utils.SendResponse(c, fiber.StatusOK, "Genmate garden retrieved", fiber.Map{
 			"users": []interface{}{},
 		})
 	}
+	ownGroup := me.GenmateGroupID != nil && *me.GenmateGroupID == groupID
 
-	users, _, err := h.userService.GetAllUsers(claims.Cohort, "learner", "", "", "first_name", 1, 1, 1000)
+	users, err := h.userService.GetGroupMembers(groupID)
 	if err != nil {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching genmate garden")
 	}
 
-	members := make([]fiber.Map, 0)
+	visible := make([]domain.User, 0, len(users))
+	userIDs := make([]primitive.ObjectID, 0, len(users))
 	for _, u := range users {
-		if u.Deleted || u.GenmateGroup != me.GenmateGroup {
+		if u.Deleted {
 			continue
 		}
+		if !(ownGroup && u.CohortNumber == me.CohortNumber) && !middleware.Authorize(c, middleware.PermUsersRead, u.CohortNumber) {
+			continue
+		}
+		visible = append(visible, u)
+		userIDs = append(userIDs, u.ID)
+	}
+	reflectionDays, err := h.userService.GetReflectionDays(userIDs)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching genmate garden")
+	}
 
-		dates := make([]string, 0, len(u.Reflections))
-		for _, r := range u.Reflections {
-			day := r.Day
-			if day == "" {
-				day = r.Date.Format(time.RFC3339)
-			}
-			dates = append(dates, day)
+	members := make([]fiber.Map, 0, len(visible))
+	for _, u := range visible {
+		dates := reflectionDays[u.ID]
+		if dates == nil {
+			dates = []string{}
 		}
 
 		protectedDates := make([]string, 0)
@@ -536,6 +772,7 @@ func (h *UserHandler) GetGenmateGarden(c *fiber.Ctx) error {
 			"last_name":        u.LastName,
 			"cohort_number":    u.CohortNumber,
 			"genmate_group":    u.GenmateGroup,
+			"genmate_group_id": u.GenmateGroupID,
 			"reflection_dates": dates,
 			"growth_points":    u.GrowthPoints,
 			"protected_dates":  protectedDates,
@@ -561,7 +798,8 @@ func (h *UserHandler) GetAllUsers(c *fiber.Ctx) error {
 	}
 
 	cohort := c.QueryInt("cohort", 0)
-	if claims.Role != "admin" {
+	isStaff := claims.Can(middleware.PermUsersRead) && (cohort > 0 || !middleware.IsScopedRole(claims.Role)) && claims.InScope(cohort)
+	if !isStaff {
 		// Enforce cohort restriction for learners: ignore any cohort query param and use their own
 		cohort = claims.Cohort
 
@@ -589,9 +827,7 @@ func (h *UserHandler) GetAllUsers(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching users")
 	}
 
-	isAdmin := claims.Role == "admin"
-
-	if isAdmin {
+	if isStaff {
 		for i := range users {
 			users[i].Password = ""
 		}
@@ -672,22 +908,14 @@ func (h *UserHandler) DeleteProfileComment(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusBadRequest, "Invalid comment ID")
 	}
 
-	claims, ok := c.Locals("user").(*middleware.Claims)
-	if !ok {
-		return utils.SendError(c, fiber.StatusUnauthorized, "Invalid token claims")
-	}
-
-	// Security: Only admin can delete any comment, or user can delete comment on their own profile?
-	// Usually admin only for moderation, or the user who wrote the comment.
-	// But the user specifically asked for admin UI.
-	if claims.Role != "admin" {
-		return utils.SendError(c, fiber.StatusForbidden, "Only admins can delete profile comments")
-	}
-
+	// Moderation only: board:moderate over the profile owner's cohort is
+	// enforced by the route.
 	if err := h.userService.DeleteProfileComment(targetOID, commentOID); err != nil {
 		return utils.SendError(c, fiber.StatusInternalServerError, "Error deleting profile comment")
 	}
 
+	auditUser(c, "DELETE_PROFILE_COMMENT", targetOID, "Deleted profile comment "+commentOID.Hex())
+
 	return utils.SendResponse(c, fiber.StatusOK, "Comment deleted successfully", nil)
 }
 
@@ -748,16 +976,16 @@ func (h *UserHandler) AddPlantReaction(c *fiber.Ctx) error {
 		return utils.SendError(c, fiber.StatusBadRequest, "Invalid reactor ID")
 	}
 
-	if claims.Role != "admin" {
+	target, err := h.userService.GetUserByID(userID)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusNotFound, "User not found")
+	}
+	if !middleware.Authorize(c, middleware.PermUsersRead, target.CohortNumber) {
 		me, err := h.userService.GetUserByID(claims.UserID)
 		if err != nil {
 			return utils.SendError(c, fiber.StatusForbidden, "You can only cheer your genmates' plants")
 		}
-		target, err := h.userService.GetUserByID(userID)
-		if err != nil {
-			return utils.SendError(c, fiber.StatusNotFound, "User not found")
-		}
-		if me.GenmateGroup == "" || me.GenmateGroup != target.GenmateGroup {
+		if me.GenmateGroupID == nil || target.GenmateGroupID == nil || *me.GenmateGroupID != *target.GenmateGroupID {
 			return utils.SendError(c, fiber.StatusForbidden, "You can only cheer your genmates' plants")
 		}
 	}
@@ -789,22 +1017,20 @@ func (h *UserHandler) GetDomainUserByID(id string) (*domain.User, error) {
 	return h.userService.GetUserByID(id)
 }
 
-func (h *UserHandler) authenticateUser(email, password string) (string, string, string, error) {
+func (h *UserHandler) authenticateUser(email, password string) (*domain.User, error) {
 	user, err := h.userService.GetUserByEmail(email)
 	if err != nil {
-		return "", "", "", errors.New("invalid credentials")
+		// Spend a bcrypt comparison anyway so timing doesn't reveal
+		// whether the email is registered.
+		utils.DummyPasswordCheck(password)
+		return nil, errors.New("invalid credentials")
 	}
 
 	if !utils.CheckPasswordHash(password, user.Password) {
-		return "", "", "", errors.New("invalid credentials")
-	}
-
-	token, err := utils.GenerateJWT(user.ID, user.Role, user.CohortNumber, "")
-	if err != nil {
-		return "", "", "", errors.New("could not generate token")
+		return nil, errors.New("invalid credentials")
 	}
 
-	return token, user.Role, user.ID.Hex(), nil
+	return user, nil
 }
 
 func (h *UserHandler) createReflection(userID primitive.ObjectID, reflection domain.Reflection) (*domain.Reflection, error) {
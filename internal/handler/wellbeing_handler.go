@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"fmt"
+	"html"
+
+	"gofiber-baro/internal/domain"
+	"gofiber-baro/internal/service/wellbeing"
+	middleware "gofiber-baro/pkg/middleware"
+	"gofiber-baro/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WellbeingHandler serves the wellbeing cases raised by sustained panic or
+// overwhelmed barometer readings.
+type WellbeingHandler struct {
+	wellbeingService *wellbeing.Service
+}
+
+func NewWellbeingHandler(wellbeingService *wellbeing.Service) *WellbeingHandler {
+	return &WellbeingHandler{wellbeingService: wellbeingService}
+}
+
+func sendCaseError(c *fiber.Ctx, err error, fallback string) error {
+	switch err {
+	case domain.ErrCaseNotFound:
+		return utils.SendError(c, fiber.StatusNotFound, "Wellbeing case not found")
+	case domain.ErrCaseClosed:
+		return utils.SendError(c, fiber.StatusConflict, err.Error())
+	case domain.ErrInvalidCaseStatus, domain.ErrInvalidCaseAssignee:
+		return utils.SendError(c, fiber.StatusBadRequest, err.Error())
+	}
+	return utils.SendError(c, fiber.StatusInternalServerError, fallback)
+}
+
+// GetCases lists a cohort's wellbeing cases, unresolved first. assigned_to
+// takes a user ID or "me".
+// GET /admin/wellbeing/cases?cohort=7&status=open&assigned_to=me&page=1&limit=50
+func (h *WellbeingHandler) GetCases(c *fiber.Ctx) error {
+	filter := domain.WellbeingCaseFilter{
+		Cohort: c.QueryInt("cohort", 0),
+		Status: domain.CaseStatus(c.Query("status")),
+	}
+	if filter.Status != "" && !filter.Status.Valid() {
+		return utils.SendError(c, fiber.StatusBadRequest, domain.ErrInvalidCaseStatus.Error())
+	}
+	switch v := c.Query("assigned_to"); v {
+	case "":
+	case "me":
+		if claims, ok := c.Locals("user").(*middleware.Claims); ok {
+			filter.AssignedTo, _ = primitive.ObjectIDFromHex(claims.UserID)
+		}
+	default:
+		oid, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			return utils.SendError(c, fiber.StatusBadRequest, "Invalid assigned_to")
+		}
+		filter.AssignedTo = oid
+	}
+	if v := c.Query("user_id"); v != "" {
+		oid, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			return utils.SendError(c, fiber.StatusBadRequest, "Invalid user_id")
+		}
+		filter.UserID = oid
+	}
+
+	page := c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+
+	cases, total, err := h.wellbeingService.List(filter, page, limit)
+	if err != nil {
+		return utils.SendError(c, fiber.StatusInternalServerError, "Error fetching wellbeing cases")
+	}
+	return utils.SendResponse(c, fiber.StatusOK, "Wellbeing cases retrieved", fiber.Map{
+		"cases": cases,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// GetCase returns one case with its history.
+// GET /admin/wellbeing/cases/:id
+func (h *WellbeingHandler) GetCase(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid case ID")
+	}
+	wc, err := h.wellbeingService.Get(id)
+	if err != nil {
+		return sendCaseError(c, err, "Error fetching wellbeing case")
+	}
+	return utils.SendResponse(c, fiber.StatusOK, "Wellbeing case retrieved", wc)
+}
+
+// UpdateCase records a follow-up: a status change, a note, a reassignment
+// or any mix of them.
+// PATCH /admin/wellbeing/cases/:id  { "status": "contacted", "note": "...", "assigned_to": "..." }
+func (h *WellbeingHandler) UpdateCase(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid case ID")
+	}
+	var body struct {
+		Status     domain.CaseStatus `json:"status"`
+		Note       string            `json:"note"`
+		AssignedTo string            `json:"assigned_to"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if body.Status == "" && body.Note == "" && body.AssignedTo == "" {
+		return utils.SendError(c, fiber.StatusBadRequest, "Nothing to update")
+	}
+
+	update := wellbeing.CaseUpdate{Status: body.Status, Note: html.EscapeString(body.Note)}
+	if body.AssignedTo != "" {
+		if update.AssignTo, err = primitive.ObjectIDFromHex(body.AssignedTo); err != nil {
+			return utils.SendError(c, fiber.StatusBadRequest, "Invalid assigned_to")
+		}
+	}
+	if claims, ok := c.Locals("user").(*middleware.Claims); ok {
+		update.By, _ = primitive.ObjectIDFromHex(claims.UserID)
+	}
+
+	before, err := h.wellbeingService.Get(id)
+	if err != nil {
+		return sendCaseError(c, err, "Error updating wellbeing case")
+	}
+	wc, err := h.wellbeingService.Update(id, update)
+	if err != nil {
+		return sendCaseError(c, err, "Error updating wellbeing case")
+	}
+
+	entry := auditEntry(c)
+	entry.Action = "UPDATE_WELLBEING_CASE"
+	entry.TargetType = "wellbeing_case"
+	entry.TargetID = wc.ID
+	entry.TargetName = wc.UserName
+	entry.Details = fmt.Sprintf("Wellbeing case %s for cohort %d", wc.RuleID, wc.CohortNumber)
+	entry.Before = map[string]interface{}{"status": before.Status, "assigned_to": before.AssignedTo.Hex()}
+	entry.After = map[string]interface{}{"status": wc.Status, "assigned_to": wc.AssignedTo.Hex(), "note": update.Note}
+
+	return utils.SendResponse(c, fiber.StatusOK, "Wellbeing case updated", wc)
+}
+
+// GetRules lists the rules that open cases.
+// GET /admin/wellbeing/rules
+func (h *WellbeingHandler) GetRules(c *fiber.Ctx) error {
+	return utils.SendResponse(c, fiber.StatusOK, "Wellbeing rules retrieved", h.wellbeingService.Rules())
+}
@@ -0,0 +1,27 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"gofiber-baro/internal/service/privacy"
+)
+
+// RunPrivacyErasureJob works through queued PDPA erasure requests.
+func RunPrivacyErasureJob(ctx context.Context, svc *privacy.Service, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Println("Privacy erasure job started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Privacy erasure job stopped")
+			return
+		case <-ticker.C:
+			svc.ProcessErasures(ctx)
+		}
+	}
+}
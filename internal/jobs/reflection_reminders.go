@@ -0,0 +1,28 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"gofiber-baro/internal/service/reflection"
+)
+
+// RunReflectionReminderJob reminds learners who haven't reflected today,
+// once the configured reminder time has passed.
+func RunReflectionReminderJob(ctx context.Context, svc *reflection.ReminderService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Println("Reflection reminder job started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Reflection reminder job stopped")
+			return
+		case <-ticker.C:
+			svc.SendReminders(ctx)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// logMailer prints messages instead of sending them. With MAIL_OUTBOX_DIR set
+// each message is also written to its own .eml file there.
+type logMailer struct {
+	dir string
+}
+
+func NewLogMailer() Mailer {
+	return &logMailer{dir: os.Getenv("MAIL_OUTBOX_DIR")}
+}
+
+func (m *logMailer) Send(_ context.Context, to, subject, body string) error {
+	log.Printf("[MAIL] to=%s subject=%q\n%s", to, subject, body)
+	if m.dir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102T150405.000000000"), filepath.Base(to))
+	content := fmt.Sprintf("To: %s\nSubject: %s\n\n%s\n", to, subject, body)
+	return os.WriteFile(filepath.Join(m.dir, name), []byte(content), 0o644)
+}
@@ -0,0 +1,21 @@
+package mailer
+
+import "context"
+
+// Mailer sends plain-text email. Pick an implementation with New.
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// New returns the SMTP mailer when SMTP_HOST is configured and falls back to
+// the log mailer (or MAIL_OUTBOX_DIR files) for local development.
+func New() (Mailer, error) {
+	m, err := NewSMTPMailer()
+	if err == nil {
+		return m, nil
+	}
+	if err != ErrSMTPNotConfigured {
+		return nil, err
+	}
+	return NewLogMailer(), nil
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gofiber-baro/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type apiKeyRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAPIKeyRepository(db *mongo.Database) domain.APIKeyRepository {
+	return &apiKeyRepository{
+		collection: db.Collection("api_keys"),
+	}
+}
+
+func (r *apiKeyRepository) Insert(ctx context.Context, key *domain.APIKey) error {
+	key.ID = primitive.NewObjectID()
+	_, err := r.collection.InsertOne(ctx, key)
+	return err
+}
+
+func (r *apiKeyRepository) FindByHash(ctx context.Context, hash string) (*domain.APIKey, error) {
+	var key domain.APIKey
+	err := r.collection.FindOne(ctx, bson.M{"key_hash": hash}).Decode(&key)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) FindAll(ctx context.Context) ([]domain.APIKey, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	keys := []domain.APIKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	filter := bson.M{"_id": id, "revoked_at": nil}
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revoked_at": at}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"last_used_at": at}})
+	return err
+}
+
+type apiKeyUsageRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAPIKeyUsageRepository(db *mongo.Database) domain.APIKeyUsageRepository {
+	return &apiKeyUsageRepository{
+		collection: db.Collection("api_key_usage"),
+	}
+}
+
+func (r *apiKeyUsageRepository) Insert(ctx context.Context, usage *domain.APIKeyUsage) error {
+	usage.ID = primitive.NewObjectID()
+	_, err := r.collection.InsertOne(ctx, usage)
+	return err
+}
+
+func (r *apiKeyUsageRepository) FindByKey(ctx context.Context, keyID primitive.ObjectID, skip, limit int64) ([]domain.APIKeyUsage, int64, error) {
+	filter := bson.M{"key_id": keyID}
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findOpts := options.Find().SetSort(bson.D{{Key: "used_at", Value: -1}}).SetSkip(skip).SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, filter, findOpts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	usage := []domain.APIKeyUsage{}
+	if err := cursor.All(ctx, &usage); err != nil {
+		return nil, 0, err
+	}
+	return usage, total, nil
+}
@@ -175,6 +175,19 @@ func (r *attendanceRepository) DeleteRecord(ctx interface{}, id primitive.Object
 	return err
 }
 
+func (r *attendanceRepository) ReassignCohort(ctx interface{}, userID primitive.ObjectID, fromDate string, cohort int) (int64, error) {
+	c := ctx.(context.Context)
+	filter := bson.M{
+		"user_id": userID,
+		"date":    bson.M{"$gte": fromDate},
+	}
+	result, err := r.collection.UpdateMany(c, filter, bson.M{"$set": bson.M{"cohort_number": cohort}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
 func (r *attendanceRepository) CountRecords(ctx interface{}, filter domain.AttendanceRecordFilter) (int64, error) {
 	c := ctx.(context.Context)
 	bsonFilter := r.buildFilter(filter)
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gofiber-baro/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type auditLogRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAuditLogRepository(db *mongo.Database) domain.AuditLogRepository {
+	return &auditLogRepository{
+		collection: db.Collection("audit_logs"),
+	}
+}
+
+func (r *auditLogRepository) Insert(ctx context.Context, log *domain.AuditLog) error {
+	log.ID = primitive.NewObjectID()
+	if log.CreatedAt.IsZero() {
+		log.CreatedAt = time.Now()
+	}
+	_, err := r.collection.InsertOne(ctx, log)
+	return err
+}
+
+func (r *auditLogRepository) FindAll(ctx context.Context, filter domain.AuditLogFilter, skip, limit int64) ([]domain.AuditLog, int64, error) {
+	query := bson.M{}
+	if filter.Action != "" {
+		query["action"] = filter.Action
+	}
+	if !filter.ActorID.IsZero() {
+		query["actor_id"] = filter.ActorID
+	}
+	if !filter.TargetID.IsZero() {
+		query["target_id"] = filter.TargetID
+	}
+	createdAt := bson.M{}
+	if !filter.From.IsZero() {
+		createdAt["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		createdAt["$lt"] = filter.To
+	}
+	if len(createdAt) > 0 {
+		query["createdAt"] = createdAt
+	}
+
+	total, err := r.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findOpts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetSkip(skip)
+	if limit > 0 {
+		findOpts.SetLimit(limit)
+	}
+	cursor, err := r.collection.Find(ctx, query, findOpts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	logs := []domain.AuditLog{}
+	if err := cursor.All(ctx, &logs); err != nil {
+		return nil, 0, err
+	}
+	return logs, total, nil
+}
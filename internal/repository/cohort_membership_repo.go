@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gofiber-baro/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type cohortMembershipRepository struct {
+	collection *mongo.Collection
+}
+
+func NewCohortMembershipRepository(db *mongo.Database) domain.CohortMembershipRepository {
+	return &cohortMembershipRepository{
+		collection: db.Collection("cohort_memberships"),
+	}
+}
+
+func (r *cohortMembershipRepository) Insert(ctx context.Context, membership *domain.CohortMembership) error {
+	membership.ID = primitive.NewObjectID()
+	if membership.CreatedAt.IsZero() {
+		membership.CreatedAt = time.Now()
+	}
+	_, err := r.collection.InsertOne(ctx, membership)
+	return err
+}
+
+func (r *cohortMembershipRepository) FindByUserID(ctx context.Context, userID primitive.ObjectID) ([]domain.CohortMembership, error) {
+	findOpts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID}, findOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var memberships []domain.CohortMembership
+	if err := cursor.All(ctx, &memberships); err != nil {
+		return nil, err
+	}
+	return memberships, nil
+}
+
+// CloseOpen ends every open membership for the user at endDate.
+func (r *cohortMembershipRepository) CloseOpen(ctx context.Context, userID primitive.ObjectID, endDate string) (int64, error) {
+	filter := bson.M{
+		"user_id":  userID,
+		"end_date": bson.M{"$in": bson.A{nil, ""}},
+	}
+	result, err := r.collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"end_date": endDate}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gofiber-baro/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type feedbackRepository struct {
+	collection *mongo.Collection
+}
+
+func NewFeedbackRepository(db *mongo.Database) domain.FeedbackRepository {
+	return &feedbackRepository{
+		collection: db.Collection("reflection_feedback"),
+	}
+}
+
+func (r *feedbackRepository) Insert(ctx context.Context, e *domain.FeedbackEntry) error {
+	e.ID = primitive.NewObjectID()
+	_, err := r.collection.InsertOne(ctx, e)
+	return err
+}
+
+func (r *feedbackRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*domain.FeedbackEntry, error) {
+	var e domain.FeedbackEntry
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&e)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrFeedbackNotFound
+		}
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *feedbackRepository) FindByReflection(ctx context.Context, reflectionID primitive.ObjectID) ([]domain.FeedbackEntry, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"reflection_id": reflectionID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []domain.FeedbackEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *feedbackRepository) UpdateBody(ctx context.Context, id primitive.ObjectID, body string, at time.Time) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"body": body, "edited_at": at}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrFeedbackNotFound
+	}
+	return nil
+}
+
+func (r *feedbackRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return domain.ErrFeedbackNotFound
+	}
+	return nil
+}
+
+func (r *feedbackRepository) InsertLegacy(ctx context.Context, e *domain.FeedbackEntry) (bool, error) {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": e.ID},
+		bson.M{"$setOnInsert": e},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.UpsertedCount > 0, nil
+}
@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gofiber-baro/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type groupRepository struct {
+	collection *mongo.Collection
+}
+
+func NewGroupRepository(db *mongo.Database) domain.GroupRepository {
+	return &groupRepository{
+		collection: db.Collection("groups"),
+	}
+}
+
+func (r *groupRepository) Insert(ctx context.Context, group *domain.Group) error {
+	group.ID = primitive.NewObjectID()
+	_, err := r.collection.InsertOne(ctx, group)
+	if mongo.IsDuplicateKeyError(err) {
+		return domain.ErrGroupExists
+	}
+	return err
+}
+
+func (r *groupRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*domain.Group, error) {
+	var group domain.Group
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&group)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrGroupNotFound
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (r *groupRepository) FindByNameKey(ctx context.Context, cohort int, kind domain.GroupKind, nameKey string) (*domain.Group, error) {
+	var group domain.Group
+	filter := bson.M{"cohort_number": cohort, "kind": kind, "name_key": nameKey}
+	err := r.collection.FindOne(ctx, filter).Decode(&group)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrGroupNotFound
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (r *groupRepository) FindByCohort(ctx context.Context, cohort int, kind domain.GroupKind) ([]domain.Group, error) {
+	filter := bson.M{"cohort_number": cohort}
+	if kind != "" {
+		filter["kind"] = kind
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "kind", Value: 1}, {Key: "name_key", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	groups := []domain.Group{}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func (r *groupRepository) Update(ctx context.Context, id primitive.ObjectID, fields map[string]interface{}) error {
+	fields["updated_at"] = time.Now()
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": fields})
+	if mongo.IsDuplicateKeyError(err) {
+		return domain.ErrGroupExists
+	}
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrGroupNotFound
+	}
+	return nil
+}
+
+func (r *groupRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return domain.ErrGroupNotFound
+	}
+	return nil
+}
+
+type groupMembershipRepository struct {
+	users *mongo.Collection
+}
+
+func NewGroupMembershipRepository(db *mongo.Database) domain.GroupMembershipRepository {
+	return &groupMembershipRepository{
+		users: db.Collection("users"),
+	}
+}
+
+func (r *groupMembershipRepository) Assign(ctx context.Context, group *domain.Group, userIDs []primitive.ObjectID) (int64, error) {
+	filter := bson.M{
+		"_id":           bson.M{"$in": userIDs},
+		"cohort_number": group.CohortNumber,
+		"deleted":       bson.M{"$ne": true},
+	}
+	update := bson.M{"$set": bson.M{
+		group.Kind.UserIDField():   group.ID,
+		group.Kind.UserNameField(): group.Name,
+	}}
+	result, err := r.users.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.MatchedCount, nil
+}
+
+func (r *groupMembershipRepository) Unassign(ctx context.Context, group *domain.Group, userIDs []primitive.ObjectID) (int64, error) {
+	filter := bson.M{group.Kind.UserIDField(): group.ID}
+	if userIDs != nil {
+		filter["_id"] = bson.M{"$in": userIDs}
+	}
+	update := bson.M{
+		"$unset": bson.M{group.Kind.UserIDField(): ""},
+		"$set":   bson.M{group.Kind.UserNameField(): ""},
+	}
+	result, err := r.users.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+func (r *groupMembershipRepository) Rename(ctx context.Context, group *domain.Group) error {
+	filter := bson.M{group.Kind.UserIDField(): group.ID}
+	_, err := r.users.UpdateMany(ctx, filter, bson.M{"$set": bson.M{group.Kind.UserNameField(): group.Name}})
+	return err
+}
+
+func (r *groupMembershipRepository) CountMembers(ctx context.Context, groupIDs []primitive.ObjectID) (map[primitive.ObjectID]int, error) {
+	counts := map[primitive.ObjectID]int{}
+	for _, kind := range []domain.GroupKind{domain.GroupKindProject, domain.GroupKindGenmate} {
+		field := kind.UserIDField()
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{field: bson.M{"$in": groupIDs}, "deleted": bson.M{"$ne": true}}}},
+			{{Key: "$group", Value: bson.M{"_id": "$" + field, "count": bson.M{"$sum": 1}}}},
+		}
+		cursor, err := r.users.Aggregate(ctx, pipeline)
+		if err != nil {
+			return nil, err
+		}
+		var rows []struct {
+			ID    primitive.ObjectID `bson:"_id"`
+			Count int                `bson:"count"`
+		}
+		if err := cursor.All(ctx, &rows); err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			counts[row.ID] += row.Count
+		}
+	}
+	return counts, nil
+}
+
+func (r *groupMembershipRepository) LegacyNames(ctx context.Context, kind domain.GroupKind) ([]domain.LegacyGroupName, error) {
+	nameField := kind.UserNameField()
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			nameField:          bson.M{"$nin": bson.A{"", nil}},
+			kind.UserIDField(): nil,
+			"cohort_number":    bson.M{"$gt": 0},
+		}}},
+		{{Key: "$group", Value: bson.M{"_id": bson.M{"cohort_number": "$cohort_number", "name": "$" + nameField}}}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$_id"}}},
+	}
+	cursor, err := r.users.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var names []domain.LegacyGroupName
+	if err := cursor.All(ctx, &names); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+func (r *groupMembershipRepository) AssignLegacy(ctx context.Context, group *domain.Group, names []string) (int64, error) {
+	filter := bson.M{
+		"cohort_number":            group.CohortNumber,
+		group.Kind.UserNameField(): bson.M{"$in": names},
+		group.Kind.UserIDField():   nil,
+	}
+	update := bson.M{"$set": bson.M{
+		group.Kind.UserIDField():   group.ID,
+		group.Kind.UserNameField(): group.Name,
+	}}
+	result, err := r.users.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
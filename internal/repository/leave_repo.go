@@ -79,6 +79,19 @@ func (r *leaveRequestRepository) FindByUserID(ctx interface{}, userID primitive.
 	return requests, nil
 }
 
+func (r *leaveRequestRepository) ReassignCohort(ctx interface{}, userID primitive.ObjectID, fromDate string, cohort int) (int64, error) {
+	c := ctx.(context.Context)
+	filter := bson.M{
+		"user_id": userID,
+		"date":    bson.M{"$gte": fromDate},
+	}
+	result, err := r.collection.UpdateMany(c, filter, bson.M{"$set": bson.M{"cohort_number": cohort}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
 func (r *leaveRequestRepository) UpdateStatus(ctx interface{}, id primitive.ObjectID, status domain.LeaveRequestStatus, reviewedBy primitive.ObjectID, reviewedByName, reviewNotes string) error {
 	c := ctx.(context.Context)
 	now := time.Now()
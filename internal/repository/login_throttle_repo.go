@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gofiber-baro/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type loginThrottleRepository struct {
+	collection *mongo.Collection
+}
+
+func NewLoginThrottleRepository(db *mongo.Database) domain.LoginThrottleRepository {
+	return &loginThrottleRepository{
+		collection: db.Collection("login_throttles"),
+	}
+}
+
+// Find returns nil, nil when the email has no recorded failures.
+func (r *loginThrottleRepository) Find(ctx context.Context, email string) (*domain.LoginThrottle, error) {
+	var throttle domain.LoginThrottle
+	err := r.collection.FindOne(ctx, bson.M{"email": email}).Decode(&throttle)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+func (r *loginThrottleRepository) RecordFailure(ctx context.Context, email string, at time.Time, resetAfter time.Duration) (*domain.LoginThrottle, error) {
+	// Pipeline update so the window check and increment happen atomically.
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"email": email,
+			"failed_count": bson.M{"$cond": bson.A{
+				bson.M{"$gt": bson.A{"$last_failed_at", at.Add(-resetAfter)}},
+				bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$failed_count", 0}}, 1}},
+				1,
+			}},
+			"last_failed_at": at,
+		}}},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var throttle domain.LoginThrottle
+	if err := r.collection.FindOneAndUpdate(ctx, bson.M{"email": email}, update, opts).Decode(&throttle); err != nil {
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+func (r *loginThrottleRepository) SetNextAllowed(ctx context.Context, email string, at time.Time) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"email": email}, bson.M{"$set": bson.M{"next_allowed_at": at}})
+	return err
+}
+
+func (r *loginThrottleRepository) Lock(ctx context.Context, email string, until time.Time) error {
+	update := bson.M{
+		"$set":   bson.M{"locked_until": until, "failed_count": 0},
+		"$unset": bson.M{"next_allowed_at": ""},
+	}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"email": email}, update)
+	return err
+}
+
+func (r *loginThrottleRepository) Reset(ctx context.Context, email string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"email": email})
+	return err
+}
+
+type lockoutEventRepository struct {
+	collection *mongo.Collection
+}
+
+func NewLockoutEventRepository(db *mongo.Database) domain.LockoutEventRepository {
+	return &lockoutEventRepository{
+		collection: db.Collection("lockout_events"),
+	}
+}
+
+func (r *lockoutEventRepository) Insert(ctx context.Context, event *domain.LockoutEvent) error {
+	event.ID = primitive.NewObjectID()
+	_, err := r.collection.InsertOne(ctx, event)
+	return err
+}
+
+func (r *lockoutEventRepository) FindAll(ctx context.Context, userID primitive.ObjectID, skip, limit int64) ([]domain.LockoutEvent, int64, error) {
+	filter := bson.M{}
+	if !userID.IsZero() {
+		filter["user_id"] = userID
+	}
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findOpts := options.Find().SetSort(bson.D{{Key: "locked_at", Value: -1}}).SetSkip(skip).SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, filter, findOpts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	events := []domain.LockoutEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+func (r *lockoutEventRepository) MarkUnlocked(ctx context.Context, email string, by primitive.ObjectID, at time.Time) error {
+	filter := bson.M{
+		"email":        email,
+		"unlocked_at":  nil,
+		"locked_until": bson.M{"$gt": at},
+	}
+	_, err := r.collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"unlocked_at": at, "unlocked_by": by}})
+	return err
+}
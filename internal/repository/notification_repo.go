@@ -49,7 +49,7 @@ func (r *notificationRepository) GetByID(id primitive.ObjectID) (*domain.Notific
 
 func (r *notificationRepository) GetAll() ([]domain.Notification, error) {
 	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
-	cursor, err := r.collection.Find(context.Background(), bson.M{}, opts)
+	cursor, err := r.collection.Find(context.Background(), bson.M{"recipient_id": bson.M{"$exists": false}}, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -62,9 +62,14 @@ func (r *notificationRepository) GetAll() ([]domain.Notification, error) {
 	return notifications, nil
 }
 
-func (r *notificationRepository) GetActive() ([]domain.Notification, error) {
+func (r *notificationRepository) GetActive(userID primitive.ObjectID) ([]domain.Notification, error) {
 	now := time.Now()
+	recipients := bson.A{bson.M{"recipient_id": bson.M{"$exists": false}}}
+	if !userID.IsZero() {
+		recipients = append(recipients, bson.M{"recipient_id": userID})
+	}
 	filter := bson.M{
+		"$or":       recipients,
 		"is_active": true,
 		"start_date": bson.M{
 			"$lte": now,
@@ -107,6 +112,44 @@ func (r *notificationRepository) MarkAsRead(id primitive.ObjectID, userID primit
 			"read_by_users": userID,
 		},
 	}
-	_, err := r.collection.UpdateOne(context.Background(), bson.M{"_id": id}, update)
+	filter := bson.M{
+		"_id": id,
+		"$or": bson.A{bson.M{"recipient_id": bson.M{"$exists": false}}, bson.M{"recipient_id": userID}},
+	}
+	_, err := r.collection.UpdateOne(context.Background(), filter, update)
 	return err
 }
+
+func (r *notificationRepository) CreateForRecipient(notification *domain.Notification) (bool, error) {
+	notification.ID = primitive.NewObjectID()
+	notification.CreatedAt = time.Now()
+	notification.ReadByUsers = []primitive.ObjectID{}
+	filter := bson.M{
+		"recipient_id": notification.RecipientID,
+		"kind":         notification.Kind,
+		"day":          notification.Day,
+	}
+	res, err := r.collection.UpdateOne(context.Background(), filter,
+		bson.M{"$setOnInsert": notification}, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return res.UpsertedCount > 0, nil
+}
+
+func (r *notificationRepository) Withdraw(recipientIDs []primitive.ObjectID, kind, day string) (int64, error) {
+	filter := bson.M{
+		"recipient_id": bson.M{"$in": recipientIDs},
+		"kind":         kind,
+		"day":          day,
+		"is_active":    true,
+	}
+	res, err := r.collection.UpdateMany(context.Background(), filter, bson.M{"$set": bson.M{"is_active": false}})
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gofiber-baro/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type privacyRequestRepository struct {
+	collection *mongo.Collection
+}
+
+func NewPrivacyRequestRepository(db *mongo.Database) domain.PrivacyRequestRepository {
+	return &privacyRequestRepository{
+		collection: db.Collection("privacy_requests"),
+	}
+}
+
+func (r *privacyRequestRepository) Insert(ctx context.Context, req *domain.PrivacyRequest) error {
+	req.ID = primitive.NewObjectID()
+	_, err := r.collection.InsertOne(ctx, req)
+	return err
+}
+
+func (r *privacyRequestRepository) HasOpen(ctx context.Context, userID primitive.ObjectID, t domain.PrivacyRequestType) (bool, error) {
+	filter := bson.M{
+		"user_id": userID,
+		"type":    t,
+		"status":  bson.M{"$in": bson.A{domain.PrivacyPending, domain.PrivacyRunning}},
+	}
+	n, err := r.collection.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	return n > 0, err
+}
+
+func (r *privacyRequestRepository) ClaimNext(ctx context.Context, t domain.PrivacyRequestType) (*domain.PrivacyRequest, error) {
+	filter := bson.M{"type": t, "status": domain.PrivacyPending}
+	update := bson.M{"$set": bson.M{"status": domain.PrivacyRunning}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "requested_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var req domain.PrivacyRequest
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&req)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &req, nil
+}
+
+func (r *privacyRequestRepository) Complete(ctx context.Context, id primitive.ObjectID, summary map[string]int64, at time.Time) error {
+	update := bson.M{"$set": bson.M{"status": domain.PrivacyCompleted, "summary": summary, "completed_at": at}}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+func (r *privacyRequestRepository) Fail(ctx context.Context, id primitive.ObjectID, reason string, at time.Time) error {
+	update := bson.M{"$set": bson.M{"status": domain.PrivacyFailed, "error": reason, "completed_at": at}}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+func (r *privacyRequestRepository) FindAll(ctx context.Context, userID primitive.ObjectID, skip, limit int64) ([]domain.PrivacyRequest, int64, error) {
+	filter := bson.M{}
+	if !userID.IsZero() {
+		filter["user_id"] = userID
+	}
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findOpts := options.Find().SetSort(bson.D{{Key: "requested_at", Value: -1}}).SetSkip(skip).SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, filter, findOpts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	requests := []domain.PrivacyRequest{}
+	if err := cursor.All(ctx, &requests); err != nil {
+		return nil, 0, err
+	}
+	return requests, total, nil
+}
@@ -0,0 +1,534 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"gofiber-baro/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type reflectionRepository struct {
+	collection *mongo.Collection
+	users      *mongo.Collection
+	// dualRead merges the legacy users.reflections array into per-user reads.
+	dualRead bool
+}
+
+func NewReflectionRepository(db *mongo.Database, dualRead bool) domain.ReflectionRepository {
+	return &reflectionRepository{
+		collection: db.Collection("reflections"),
+		users:      db.Collection("users"),
+		dualRead:   dualRead,
+	}
+}
+
+func (r *reflectionRepository) Insert(ctx context.Context, reflection *domain.Reflection) error {
+	if reflection.ID.IsZero() {
+		reflection.ID = primitive.NewObjectID()
+	}
+	reflection.SearchText = reflection.BuildSearchText()
+	_, err := r.collection.InsertOne(ctx, reflection)
+	if mongo.IsDuplicateKeyError(err) {
+		return domain.ErrReflectionExists
+	}
+	return err
+}
+
+func (r *reflectionRepository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]domain.Reflection, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: 1}, {Key: "createdAt", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	reflections := []domain.Reflection{}
+	if err := cursor.All(ctx, &reflections); err != nil {
+		return nil, err
+	}
+	if !r.dualRead {
+		return reflections, nil
+	}
+
+	legacy, err := r.embedded(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[primitive.ObjectID]bool, len(reflections))
+	for _, ref := range reflections {
+		seen[ref.ID] = true
+	}
+	merged := false
+	for _, ref := range legacy {
+		if !seen[ref.ID] {
+			reflections = append(reflections, ref)
+			merged = true
+		}
+	}
+	if merged {
+		sort.SliceStable(reflections, func(i, j int) bool {
+			return reflections[i].Date.Before(reflections[j].Date)
+		})
+	}
+	return reflections, nil
+}
+
+// embedded reads a user's legacy reflections array.
+func (r *reflectionRepository) embedded(ctx context.Context, userID primitive.ObjectID) ([]domain.Reflection, error) {
+	var doc struct {
+		Reflections []domain.Reflection `bson:"reflections"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"reflections": 1})
+	err := r.users.FindOne(ctx, bson.M{"_id": userID}, opts).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return doc.Reflections, err
+}
+
+func (r *reflectionRepository) ExistsOnDay(ctx context.Context, userID primitive.ObjectID, day string) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"user_id": userID, "day": day}, options.Count().SetLimit(1))
+	if err != nil || count > 0 || !r.dualRead {
+		return count > 0, err
+	}
+
+	legacy, err := r.embedded(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	for i := range legacy {
+		if legacy[i].DayKey() == day {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *reflectionRepository) FindDays(ctx context.Context, userIDs []primitive.ObjectID) (map[primitive.ObjectID][]string, error) {
+	opts := options.Find().SetProjection(bson.M{"user_id": 1, "day": 1}).SetSort(bson.D{{Key: "day", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": bson.M{"$in": userIDs}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		UserID primitive.ObjectID `bson:"user_id"`
+		Day    string             `bson:"day"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	days := make(map[primitive.ObjectID][]string, len(userIDs))
+	seen := map[primitive.ObjectID]map[string]bool{}
+	add := func(userID primitive.ObjectID, day string) {
+		if seen[userID] == nil {
+			seen[userID] = map[string]bool{}
+		}
+		if !seen[userID][day] {
+			seen[userID][day] = true
+			days[userID] = append(days[userID], day)
+		}
+	}
+	for _, row := range rows {
+		add(row.UserID, row.Day)
+	}
+	if !r.dualRead {
+		return days, nil
+	}
+
+	legacyOpts := options.Find().SetProjection(bson.M{"reflections.day": 1, "reflections.date": 1})
+	legacyCursor, err := r.users.Find(ctx, bson.M{"_id": bson.M{"$in": userIDs}, "reflections.0": bson.M{"$exists": true}}, legacyOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer legacyCursor.Close(ctx)
+
+	var users []struct {
+		ID          primitive.ObjectID  `bson:"_id"`
+		Reflections []domain.Reflection `bson:"reflections"`
+	}
+	if err := legacyCursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		for i := range u.Reflections {
+			add(u.ID, u.Reflections[i].DayKey())
+		}
+		sort.Strings(days[u.ID])
+	}
+	return days, nil
+}
+
+func (r *reflectionRepository) FindByID(ctx context.Context, userID, reflectionID primitive.ObjectID) (*domain.Reflection, error) {
+	var reflection domain.Reflection
+	err := r.collection.FindOne(ctx, bson.M{"_id": reflectionID, "user_id": userID}).Decode(&reflection)
+	if err == mongo.ErrNoDocuments {
+		if r.dualRead {
+			return r.promote(ctx, userID, reflectionID)
+		}
+		return nil, domain.ErrReflectionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &reflection, nil
+}
+
+// promote copies a reflection still in the user's legacy array into the
+// collection, as migrate-reflections would, so that writes to it (feedback
+// marks, edits) have a document to land on.
+func (r *reflectionRepository) promote(ctx context.Context, userID, reflectionID primitive.ObjectID) (*domain.Reflection, error) {
+	var doc struct {
+		CohortNumber int                 `bson:"cohort_number"`
+		Reflections  []domain.Reflection `bson:"reflections"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"cohort_number": 1, "reflections": bson.M{"$elemMatch": bson.M{"_id": reflectionID}}})
+	err := r.users.FindOne(ctx, bson.M{"_id": userID}, opts).Decode(&doc)
+	if err == mongo.ErrNoDocuments || (err == nil && len(doc.Reflections) == 0) {
+		return nil, domain.ErrReflectionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	reflection := doc.Reflections[0]
+	reflection.UserID = userID
+	reflection.Day = reflection.DayKey()
+	if reflection.CohortNumber == 0 {
+		reflection.CohortNumber = doc.CohortNumber
+	}
+	_, conflicts, err := r.Copy(ctx, []domain.Reflection{reflection})
+	if err != nil {
+		return nil, err
+	}
+	if conflicts > 0 {
+		// Another reflection already holds its day; the copy can't exist.
+		return nil, domain.ErrReflectionNotFound
+	}
+	return &reflection, nil
+}
+
+func (r *reflectionRepository) UpdateContent(ctx context.Context, reflection *domain.Reflection, readUpdatedAt *time.Time) error {
+	if len(reflection.Revisions) == 0 {
+		return errors.New("reflection update without a revision")
+	}
+	filter := bson.M{"_id": reflection.ID, "user_id": reflection.UserID, "updated_at": readUpdatedAt}
+	update := bson.M{
+		"$set": bson.M{
+			"reflection":            reflection.ReflectionData,
+			"answers":               reflection.Answers,
+			"updated_at":            reflection.UpdatedAt,
+			"edited_after_feedback": reflection.EditedAfterFeedback,
+			"search_text":           reflection.BuildSearchText(),
+		},
+		"$push": bson.M{"revisions": reflection.Revisions[len(reflection.Revisions)-1]},
+	}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrReflectionEditConflict
+	}
+	return nil
+}
+
+func (r *reflectionRepository) MarkStaffReply(ctx context.Context, userID, reflectionID primitive.ObjectID, at time.Time) error {
+	filter := bson.M{"_id": reflectionID, "user_id": userID}
+	// The reply is written against the current text, so clear the edit flag.
+	update := bson.M{"$set": bson.M{"last_staff_reply_at": at, "edited_after_feedback": false}}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrReflectionNotFound
+	}
+	return nil
+}
+
+func (r *reflectionRepository) MarkFeedbackRead(ctx context.Context, userID, reflectionID primitive.ObjectID, at time.Time) error {
+	filter := bson.M{"_id": reflectionID, "user_id": userID}
+	_, err := r.collection.UpdateOne(ctx, filter, bson.M{"$max": bson.M{"feedback_read_at": at}})
+	return err
+}
+
+func (r *reflectionRepository) FindUnreadFeedback(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	// A missing feedback_read_at sorts below any date.
+	filter := bson.M{
+		"user_id":             userID,
+		"last_staff_reply_at": bson.M{"$ne": nil},
+		"$expr":               bson.M{"$gt": bson.A{"$last_staff_reply_at", "$feedback_read_at"}},
+	}
+	opts := options.Find().SetProjection(bson.M{"_id": 1}).SetSort(bson.D{{Key: "date", Value: -1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	return ids, nil
+}
+
+func (r *reflectionRepository) EachLegacyFeedback(ctx context.Context, fn func(reflection *domain.Reflection) error) error {
+	opts := options.Find().SetProjection(bson.M{"user_id": 1, "date": 1, "createdAt": 1, "admin_feedback": 1})
+	cursor, err := r.collection.Find(ctx, bson.M{"admin_feedback": bson.M{"$nin": bson.A{nil, ""}}}, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var reflection domain.Reflection
+		if err := cursor.Decode(&reflection); err != nil {
+			return err
+		}
+		if err := fn(&reflection); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+func (r *reflectionRepository) ClearLegacyFeedback(ctx context.Context, reflectionID primitive.ObjectID, at time.Time) error {
+	update := bson.M{
+		"$unset": bson.M{"admin_feedback": ""},
+		"$max":   bson.M{"last_staff_reply_at": at, "feedback_read_at": at},
+	}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": reflectionID}, update)
+	return err
+}
+
+func (r *reflectionRepository) ReassignCohort(ctx context.Context, userID primitive.ObjectID, fromDay string, cohort int) (int64, error) {
+	filter := bson.M{
+		"user_id": userID,
+		"day":     bson.M{"$gte": fromDay},
+	}
+	result, err := r.collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"cohort_number": cohort}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// pendingEmbedded matches users whose legacy array holds more reflections
+// than the last copy saw (reflections_copied).
+var pendingEmbedded = bson.M{
+	"reflections.0": bson.M{"$exists": true},
+	"$expr": bson.M{"$gt": bson.A{
+		bson.M{"$size": "$reflections"},
+		bson.M{"$ifNull": bson.A{"$reflections_copied", 0}},
+	}},
+}
+
+func (r *reflectionRepository) EachEmbedded(ctx context.Context, pendingOnly bool, fn func(userID primitive.ObjectID, cohort int, reflections []domain.Reflection) error) error {
+	filter := bson.M{"reflections.0": bson.M{"$exists": true}}
+	if pendingOnly {
+		filter = pendingEmbedded
+	}
+	opts := options.Find().SetProjection(bson.M{"cohort_number": 1, "reflections": 1})
+	cursor, err := r.users.Find(ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var u struct {
+			ID           primitive.ObjectID  `bson:"_id"`
+			CohortNumber int                 `bson:"cohort_number"`
+			Reflections  []domain.Reflection `bson:"reflections"`
+		}
+		if err := cursor.Decode(&u); err != nil {
+			return err
+		}
+		if err := fn(u.ID, u.CohortNumber, u.Reflections); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+func (r *reflectionRepository) HasPendingEmbedded(ctx context.Context) (bool, error) {
+	n, err := r.users.CountDocuments(ctx, pendingEmbedded, options.Count().SetLimit(1))
+	return n > 0, err
+}
+
+func (r *reflectionRepository) MarkCopied(ctx context.Context, userID primitive.ObjectID, count int) error {
+	_, err := r.users.UpdateOne(ctx, bson.M{"_id": userID}, bson.M{"$set": bson.M{"reflections_copied": count}})
+	return err
+}
+
+func (r *reflectionRepository) Copy(ctx context.Context, reflections []domain.Reflection) (int64, int64, error) {
+	if len(reflections) == 0 {
+		return 0, 0, nil
+	}
+	models := make([]mongo.WriteModel, len(reflections))
+	for i := range reflections {
+		reflections[i].SearchText = reflections[i].BuildSearchText()
+		models[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": reflections[i].ID}).
+			SetUpdate(bson.M{"$setOnInsert": reflections[i]}).
+			SetUpsert(true)
+	}
+
+	result, err := r.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	var copied, conflicts int64
+	if result != nil {
+		copied = result.UpsertedCount
+	}
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && bulkErr.WriteConcernError == nil {
+		for _, we := range bulkErr.WriteErrors {
+			if !mongo.IsDuplicateKeyError(we) {
+				return copied, conflicts, err
+			}
+			conflicts++
+		}
+		err = nil
+	}
+	return copied, conflicts, err
+}
+
+func (r *reflectionRepository) PruneEmbedded(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	ids, err := r.collection.Distinct(ctx, "_id", bson.M{"user_id": userID})
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	var before struct {
+		Reflections []struct {
+			ID primitive.ObjectID `bson:"_id"`
+		} `bson:"reflections"`
+	}
+	opts := options.FindOneAndUpdate().SetProjection(bson.M{"reflections._id": 1}).SetReturnDocument(options.Before)
+	update := bson.M{"$pull": bson.M{"reflections": bson.M{"_id": bson.M{"$in": ids}}}}
+	err = r.users.FindOneAndUpdate(ctx, bson.M{"_id": userID}, update, opts).Decode(&before)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	copied := make(map[primitive.ObjectID]bool, len(ids))
+	for _, id := range ids {
+		if oid, ok := id.(primitive.ObjectID); ok {
+			copied[oid] = true
+		}
+	}
+	var pruned int64
+	for _, ref := range before.Reflections {
+		if copied[ref.ID] {
+			pruned++
+		}
+	}
+	return pruned, nil
+}
+
+func (r *reflectionRepository) DistinctZones(ctx context.Context) ([]string, error) {
+	zones := map[string]bool{}
+	for _, src := range []struct {
+		collection *mongo.Collection
+		field      string
+	}{
+		{r.collection, "reflection.barometer"},
+		{r.users, "reflections.reflection.barometer"},
+	} {
+		values, err := src.collection.Distinct(ctx, src.field, bson.M{})
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range values {
+			if s, ok := v.(string); ok {
+				zones[s] = true
+			}
+		}
+	}
+
+	distinct := make([]string, 0, len(zones))
+	for z := range zones {
+		distinct = append(distinct, z)
+	}
+	sort.Strings(distinct)
+	return distinct, nil
+}
+
+func (r *reflectionRepository) RenameZone(ctx context.Context, from string, to domain.BarometerZone) (int64, error) {
+	result, err := r.collection.UpdateMany(ctx, bson.M{"reflection.barometer": from}, bson.M{"$set": bson.M{"reflection.barometer": to}})
+	if err != nil {
+		return 0, err
+	}
+	renamed := result.ModifiedCount
+
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{Filters: bson.A{bson.M{"r.reflection.barometer": from}}})
+	legacy, err := r.users.UpdateMany(ctx,
+		bson.M{"reflections.reflection.barometer": from},
+		bson.M{"$set": bson.M{"reflections.$[r].reflection.barometer": to}},
+		opts,
+	)
+	if err != nil {
+		return renamed, err
+	}
+	return renamed + legacy.ModifiedCount, nil
+}
+
+func (r *reflectionRepository) BackfillSearchText(ctx context.Context) (int64, error) {
+	opts := options.Find().SetProjection(bson.M{"reflection": 1})
+	cursor, err := r.collection.Find(ctx, bson.M{"search_text": bson.M{"$exists": false}}, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var filled int64
+	var models []mongo.WriteModel
+	write := func() error {
+		if len(models) == 0 {
+			return nil
+		}
+		result, err := r.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+		if result != nil {
+			filled += result.ModifiedCount
+		}
+		models = models[:0]
+		return err
+	}
+	for cursor.Next(ctx) {
+		var reflection domain.Reflection
+		if err := cursor.Decode(&reflection); err != nil {
+			return filled, err
+		}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": reflection.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"search_text": reflection.BuildSearchText()}}))
+		if len(models) == 500 {
+			if err := write(); err != nil {
+				return filled, err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return filled, err
+	}
+	return filled, write()
+}
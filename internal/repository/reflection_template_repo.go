@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+
+	"gofiber-baro/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type reflectionTemplateRepository struct {
+	collection *mongo.Collection
+}
+
+func NewReflectionTemplateRepository(db *mongo.Database) domain.ReflectionTemplateRepository {
+	return &reflectionTemplateRepository{
+		collection: db.Collection("reflection_templates"),
+	}
+}
+
+func (r *reflectionTemplateRepository) Insert(ctx context.Context, t *domain.ReflectionTemplate) error {
+	// {cohort_number, version} is unique, so two admins saving at once
+	// cannot get the same version; the loser retries with the next one.
+	for attempt := 0; ; attempt++ {
+		var latest domain.ReflectionTemplate
+		opts := options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}}).SetProjection(bson.M{"version": 1})
+		err := r.collection.FindOne(ctx, bson.M{"cohort_number": t.CohortNumber}, opts).Decode(&latest)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return err
+		}
+
+		t.ID = primitive.NewObjectID()
+		t.Version = latest.Version + 1
+		_, err = r.collection.InsertOne(ctx, t)
+		if !mongo.IsDuplicateKeyError(err) || attempt == 2 {
+			return err
+		}
+	}
+}
+
+func (r *reflectionTemplateRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*domain.ReflectionTemplate, error) {
+	var t domain.ReflectionTemplate
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&t)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrTemplateNotFound
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *reflectionTemplateRepository) FindByCohort(ctx context.Context, cohort int) ([]domain.ReflectionTemplate, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "version", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"cohort_number": cohort}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	templates := []domain.ReflectionTemplate{}
+	if err := cursor.All(ctx, &templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+func (r *reflectionTemplateRepository) FindActive(ctx context.Context, cohort int) (*domain.ReflectionTemplate, error) {
+	var t domain.ReflectionTemplate
+	// Activate briefly leaves no version active, never two; sorting still
+	// settles on the newest should that change.
+	opts := options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}})
+	err := r.collection.FindOne(ctx, bson.M{"cohort_number": cohort, "active": true}, opts).Decode(&t)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrTemplateNotFound
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *reflectionTemplateRepository) Activate(ctx context.Context, cohort int, id primitive.ObjectID) error {
+	filter := bson.M{"cohort_number": cohort, "active": true, "_id": bson.M{"$ne": id}}
+	if _, err := r.collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"active": false}}); err != nil {
+		return err
+	}
+	if id.IsZero() {
+		return nil
+	}
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "cohort_number": cohort}, bson.M{"$set": bson.M{"active": true}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrTemplateNotFound
+	}
+	return nil
+}
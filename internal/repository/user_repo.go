@@ -53,7 +53,77 @@ func (r *userRepository) FindByEmail(ctx interface{}, email string) (*domain.Use
 	return &user, nil
 }
 
+func (r *userRepository) FindByEmailsOrJSDNumbers(ctx interface{}, emails, jsdNumbers []string) ([]domain.User, error) {
+	c := ctx.(context.Context)
+	filter := bson.M{"$or": bson.A{
+		bson.M{"email": bson.M{"$in": emails}},
+		bson.M{"jsd_number": bson.M{"$in": jsdNumbers}},
+	}}
+	projection := bson.M{"email": 1, "jsd_number": 1}
+	cursor, err := r.collection.Find(c, filter, options.Find().SetProjection(projection))
+	if err != nil {
+		return nil, err
+	}
+	var users []domain.User
+	if err := cursor.All(c, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *userRepository) FindAuthState(ctx interface{}, id primitive.ObjectID) (*domain.UserAuthState, error) {
+	c := ctx.(context.Context)
+	projection := bson.M{"role": 1, "cohort_number": 1, "deleted": 1, "disabled": 1, "must_change_password": 1, "token_version": 1, "staff_cohorts": 1, "totp_enabled": 1, "totp_required": 1}
+	var state domain.UserAuthState
+	err := r.collection.FindOne(c, bson.M{"_id": id}, options.FindOne().SetProjection(projection)).Decode(&state)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &state, nil
+}
+
+// rosterProjection keeps the user fields attendance and exports read. The
+// embedded reflections, comments, reactions and fertilizer log make up most
+// of a user document and are left behind.
+var rosterProjection = bson.M{
+	"jsd_number": 1, "first_name": 1, "last_name": 1, "email": 1, "cohort_number": 1, "role": 1, "zoom_name": 1,
+	"project_group": 1, "project_group_id": 1, "genmate_group": 1, "genmate_group_id": 1,
+	"salesforce_id": 1, "attendance_status": 1, "status_history": 1,
+	"deleted": 1, "deleted_at": 1, "disabled": 1,
+}
+
+// summaryProjection adds profile and plant fields to the roster, with only
+// the dates of protected days.
+var summaryProjection = func() bson.M {
+	p := bson.M{
+		"badges": 1, "bio": 1, "social_links": 1, "pinned_badge_ids": 1, "plant_reactions": 1,
+		"selected_palette": 1, "selected_species": 1, "selected_pot": 1,
+		"selected_leaf": 1, "selected_flower": 1, "selected_stem": 1,
+		"fertilizer_balance": 1, "growth_points": 1,
+		"fertilizer_log.kind": 1, "fertilizer_log.relatedDate": 1,
+	}
+	for k, v := range rosterProjection {
+		p[k] = v
+	}
+	return p
+}()
+
 func (r *userRepository) FindAll(ctx interface{}, filter domain.UserFilter, opts interface{}) ([]domain.User, int, error) {
+	return r.find(ctx, filter, nil, opts)
+}
+
+func (r *userRepository) FindRoster(ctx interface{}, filter domain.UserFilter, opts interface{}) ([]domain.User, int, error) {
+	return r.find(ctx, filter, rosterProjection, opts)
+}
+
+func (r *userRepository) FindSummaries(ctx interface{}, filter domain.UserFilter, opts interface{}) ([]domain.User, int, error) {
+	return r.find(ctx, filter, summaryProjection, opts)
+}
+
+func (r *userRepository) find(ctx interface{}, filter domain.UserFilter, projection bson.M, opts interface{}) ([]domain.User, int, error) {
 	c := ctx.(context.Context)
 	bsonFilter := r.buildFilter(filter)
 
@@ -63,6 +133,9 @@ func (r *userRepository) FindAll(ctx interface{}, filter domain.UserFilter, opts
 			findOpts = o
 		}
 	}
+	if projection != nil {
+		findOpts.SetProjection(projection)
+	}
 
 	cursor, err := r.collection.Find(c, bsonFilter, findOpts)
 	if err != nil {
@@ -103,6 +176,44 @@ func (r *userRepository) Update(ctx interface{}, id primitive.ObjectID, update i
 	return err
 }
 
+func (r *userRepository) IncrementTokenVersion(ctx interface{}, id primitive.ObjectID) error {
+	c := ctx.(context.Context)
+	result, err := r.collection.UpdateOne(c, bson.M{"_id": id}, bson.M{"$inc": bson.M{"token_version": 1}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) ConsumeTOTPStep(ctx interface{}, id primitive.ObjectID, step int64) (bool, error) {
+	c := ctx.(context.Context)
+	filter := bson.M{
+		"_id": id,
+		"$or": bson.A{
+			bson.M{"totp_last_step": bson.M{"$lt": step}},
+			bson.M{"totp_last_step": bson.M{"$exists": false}},
+		},
+	}
+	result, err := r.collection.UpdateOne(c, filter, bson.M{"$set": bson.M{"totp_last_step": step}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *userRepository) ConsumeRecoveryCode(ctx interface{}, id primitive.ObjectID, hash string) (bool, error) {
+	c := ctx.(context.Context)
+	filter := bson.M{"_id": id, "recovery_codes": hash}
+	result, err := r.collection.UpdateOne(c, filter, bson.M{"$pull": bson.M{"recovery_codes": hash}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
 func (r *userRepository) AddBadge(ctx interface{}, userID primitive.ObjectID, badge domain.Badge) error {
 	c := ctx.(context.Context)
 	filter := bson.M{"_id": userID}
@@ -136,6 +247,22 @@ func (r *userRepository) GrantFertilizer(ctx interface{}, userID primitive.Objec
 	return nil
 }
 
+func (r *userRepository) AddStatusTransition(ctx interface{}, userID primitive.ObjectID, transition domain.StatusTransition, currentStatus string) error {
+	c := ctx.(context.Context)
+	update := bson.M{
+		"$push": bson.M{"status_history": transition},
+		"$set":  bson.M{"attendance_status": currentStatus},
+	}
+	result, err := r.collection.UpdateOne(c, bson.M{"_id": userID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
 func (r *userRepository) UseFertilizerProtect(ctx interface{}, userID primitive.ObjectID, dateStr string) error {
 	c := ctx.(context.Context)
 	filter := bson.M{
@@ -207,27 +334,6 @@ func (r *userRepository) hasProtectedDate(ctx context.Context, userID primitive.
 	return count > 0, nil
 }
 
-func (r *userRepository) UpdateReflectionFeedback(ctx interface{}, userID, reflectionID primitive.ObjectID, feedback string) error {
-	c := ctx.(context.Context)
-	filter := bson.M{
-		"_id":             userID,
-		"reflections._id": reflectionID,
-	}
-	update := bson.M{
-		"$set": bson.M{
-			"reflections.$.admin_feedback": feedback,
-		},
-	}
-	result, err := r.collection.UpdateOne(c, filter, update)
-	if err != nil {
-		return err
-	}
-	if result.ModifiedCount == 0 {
-		return errors.New("user or reflection not found")
-	}
-	return nil
-}
-
 func (r *userRepository) buildFilter(filter domain.UserFilter) bson.M {
 	bsonFilter := bson.M{}
 
@@ -256,27 +362,25 @@ func (r *userRepository) buildFilter(filter domain.UserFilter) bson.M {
 			bsonFilter["attendance_status"] = bson.M{"$nin": statuses}
 		}
 	}
-
-	return bsonFilter
-}
-
-func (r *userRepository) CreateReflection(ctx interface{}, userID primitive.ObjectID, reflection domain.Reflection) error {
-	c := ctx.(context.Context)
-	reflection.ID = primitive.NewObjectID()
-
-	pipeline := mongo.Pipeline{
-		{{Key: "$set", Value: bson.D{
-			{Key: "reflections", Value: bson.D{
-				{Key: "$ifNull", Value: bson.A{"$reflections", bson.A{}}},
-			}},
-		}}},
+	if !filter.GroupID.IsZero() {
+		inGroup := []bson.M{
+			{"project_group_id": filter.GroupID},
+			{"genmate_group_id": filter.GroupID},
+		}
+		if _, ok := bsonFilter["$or"]; ok {
+			bsonFilter["$and"] = []bson.M{{"$or": inGroup}}
+		} else {
+			bsonFilter["$or"] = inGroup
+		}
+	}
+	if filter.IDs != nil {
+		bsonFilter["_id"] = bson.M{"$in": filter.IDs}
+	}
+	if filter.StaffCohort > 0 {
+		bsonFilter["staff_cohorts"] = filter.StaffCohort
 	}
-	r.collection.UpdateOne(c, bson.M{"_id": userID}, pipeline)
 
-	filter := bson.M{"_id": userID}
-	update := bson.M{"$push": bson.M{"reflections": reflection}}
-	_, err := r.collection.UpdateOne(c, filter, update)
-	return err
+	return bsonFilter
 }
 
 func (r *userRepository) AddProfileComment(ctx interface{}, userID primitive.ObjectID, comment domain.ProfileComment) error {
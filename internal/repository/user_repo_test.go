@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"gofiber-baro/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testDB connects to MONGO_TEST_URI and returns a scratch database that is
+// dropped after the test. Without the variable the test is skipped.
+func testDB(t *testing.T) *mongo.Database {
+	t.Helper()
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := client.Database(fmt.Sprintf("baro_test_%d", time.Now().UnixNano()))
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		db.Drop(ctx)
+		client.Disconnect(ctx)
+	})
+	return db
+}
+
+// seedLearner is a learner of a long-running cohort: every field the roster
+// and summary views keep is set, alongside the reflections, comments and
+// fertilizer notes they leave behind.
+func seedLearner(cohort, n, reflections int) domain.User {
+	groupID := primitive.NewObjectID()
+	u := domain.User{
+		ID:                primitive.NewObjectID(),
+		JSDNumber:         fmt.Sprintf("JSD%d-%03d", cohort, n),
+		FirstName:         fmt.Sprintf("First%d", n),
+		LastName:          fmt.Sprintf("Last%d", n),
+		Email:             fmt.Sprintf("learner%d@example.com", n),
+		CohortNumber:      cohort,
+		Role:              "learner",
+		ZoomName:          fmt.Sprintf("Zoom %d", n),
+		ProjectGroup:      "Project A",
+		ProjectGroupID:    &groupID,
+		GenmateGroup:      "Genmate B",
+		GenmateGroupID:    &groupID,
+		SalesforceID:      "a1g000000000001",
+		AttendanceStatus:  string(domain.LearnerActive),
+		StatusHistory:     []domain.StatusTransition{{Status: domain.LearnerActive, EffectiveDate: "2025-01-06", ChangedAt: time.Now()}},
+		Badges:            []domain.Badge{{Type: "streak", Name: "Streak", Emoji: "🔥", AwardedAt: time.Now()}},
+		Bio:               "Learning to code",
+		SelectedSpecies:   "monstera",
+		SelectedPot:       "terracotta",
+		FertilizerBalance: 3,
+		GrowthPoints:      42,
+	}
+	start := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	text := strings.Repeat("Today we practised SQL joins and I finally understood them. ", 10)
+	for i := 0; i < reflections; i++ {
+		date := start.AddDate(0, 0, i)
+		u.Reflections = append(u.Reflections, domain.Reflection{
+			ID:   primitive.NewObjectID(),
+			Day:  date.Format("2006-01-02"),
+			Date: date,
+			ReflectionData: domain.ReflectionContent{
+				Barometer:       domain.ZoneComfort,
+				TechSessions:    domain.SessionDetails{SessionName: []string{"SQL Joins"}, Happy: text, Improve: text},
+				NonTechSessions: domain.SessionDetails{SessionName: []string{"Standup"}, Happy: text, Improve: text},
+			},
+		})
+		u.ProfileComments = append(u.ProfileComments, domain.ProfileComment{
+			ID: primitive.NewObjectID(), ZoomName: "Friend", Cohort: cohort, Content: text, CreatedAt: date,
+		})
+		u.FertilizerLog = append(u.FertilizerLog, domain.FertilizerLogEntry{
+			ID: primitive.NewObjectID(), Kind: "protect", Amount: 1, RelatedDate: date.Format("2006-01-02"),
+			Note: text, GrantedBy: "coach", CreatedAt: date,
+		})
+	}
+	return u
+}
+
+func bsonSize(t *testing.T, users []domain.User) int {
+	t.Helper()
+	var total int
+	for _, u := range users {
+		raw, err := bson.Marshal(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		total += len(raw)
+	}
+	return total
+}
+
+// TestRosterAndSummaryPayload measures how much of a seeded cohort each view
+// loads (run with -v to see the sizes) and checks that the fields the
+// attendance, export and garden handlers read survive the projections.
+func TestRosterAndSummaryPayload(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	const cohort, learners, reflections = 7, 40, 60
+
+	docs := make([]interface{}, learners)
+	for i := range docs {
+		docs[i] = seedLearner(cohort, i+1, reflections)
+	}
+	if _, err := db.Collection("users").InsertMany(ctx, docs); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewUserRepository(db)
+	filter := domain.UserFilter{Cohort: cohort}
+	full, _, err := repo.FindAll(ctx, filter, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roster, _, err := repo.FindRoster(ctx, filter, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	summaries, _, err := repo.FindSummaries(ctx, filter, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(full) != learners || len(roster) != learners || len(summaries) != learners {
+		t.Fatalf("got %d full, %d roster, %d summaries; want %d each", len(full), len(roster), len(summaries), learners)
+	}
+
+	fullSize, rosterSize, summarySize := bsonSize(t, full), bsonSize(t, roster), bsonSize(t, summaries)
+	t.Logf("%d learners with %d reflections each: full %d bytes, roster %d bytes, summary %d bytes",
+		learners, reflections, fullSize, rosterSize, summarySize)
+	if rosterSize*100 > fullSize {
+		t.Errorf("roster is %d bytes, want under 1%% of the full %d", rosterSize, fullSize)
+	}
+	if summarySize*10 > fullSize {
+		t.Errorf("summary is %d bytes, want under 10%% of the full %d", summarySize, fullSize)
+	}
+
+	for _, u := range roster {
+		if u.JSDNumber == "" || u.FirstName == "" || u.LastName == "" || u.Email == "" || u.ZoomName == "" ||
+			u.CohortNumber != cohort || u.Role != "learner" ||
+			u.ProjectGroup == "" || u.ProjectGroupID == nil || u.GenmateGroup == "" || u.GenmateGroupID == nil ||
+			u.SalesforceID == "" || u.AttendanceStatus == "" || len(u.StatusHistory) != 1 {
+			t.Fatalf("roster is missing fields it should keep: %+v", u)
+		}
+		if len(u.Reflections) != 0 || len(u.ProfileComments) != 0 || len(u.FertilizerLog) != 0 || len(u.Badges) != 0 {
+			t.Fatalf("roster loaded fields it should leave behind")
+		}
+	}
+
+	for _, u := range summaries {
+		if u.FirstName == "" || u.ZoomName == "" || u.GenmateGroupID == nil || len(u.StatusHistory) != 1 {
+			t.Fatalf("summary is missing roster fields: %+v", u)
+		}
+		if len(u.Badges) != 1 || u.Bio == "" || u.SelectedSpecies == "" || u.SelectedPot == "" ||
+			u.FertilizerBalance != 3 || u.GrowthPoints != 42 {
+			t.Fatalf("summary is missing profile or plant fields: %+v", u)
+		}
+		if len(u.FertilizerLog) != reflections {
+			t.Fatalf("summary has %d fertilizer entries, want %d", len(u.FertilizerLog), reflections)
+		}
+		for _, e := range u.FertilizerLog {
+			if e.Kind != "protect" || e.RelatedDate == "" {
+				t.Fatalf("fertilizer entry lost kind or date: %+v", e)
+			}
+			if e.Note != "" || e.GrantedBy != "" {
+				t.Fatalf("fertilizer entry kept more than kind and date: %+v", e)
+			}
+		}
+		if len(u.Reflections) != 0 || len(u.ProfileComments) != 0 {
+			t.Fatalf("summary loaded reflections or comments")
+		}
+	}
+}
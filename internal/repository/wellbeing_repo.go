@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+
+	"gofiber-baro/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type wellbeingCaseRepository struct {
+	collection *mongo.Collection
+}
+
+func NewWellbeingCaseRepository(db *mongo.Database) domain.WellbeingCaseRepository {
+	return &wellbeingCaseRepository{
+		collection: db.Collection("wellbeing_cases"),
+	}
+}
+
+func (r *wellbeingCaseRepository) Open(ctx context.Context, c *domain.WellbeingCase) (bool, error) {
+	for attempt := 0; ; attempt++ {
+		var existing domain.WellbeingCase
+		filter := bson.M{"user_id": c.UserID, "rule_id": c.RuleID, "active": true}
+		err := r.collection.FindOne(ctx, filter).Decode(&existing)
+		if err == nil {
+			return false, r.extend(ctx, &existing, c)
+		}
+		if err != mongo.ErrNoDocuments {
+			return false, err
+		}
+
+		c.ID = primitive.NewObjectID()
+		c.Active = true
+		_, err = r.collection.InsertOne(ctx, c)
+		// Two reflections evaluated at once: the other one opened the case,
+		// so add to it instead.
+		if mongo.IsDuplicateKeyError(err) && attempt == 0 {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+}
+
+// extend adds the reflections of c that existing doesn't have yet, with c's
+// history, and leaves existing alone when there are none.
+func (r *wellbeingCaseRepository) extend(ctx context.Context, existing, c *domain.WellbeingCase) error {
+	known := make(map[primitive.ObjectID]bool, len(existing.ReflectionIDs))
+	for _, id := range existing.ReflectionIDs {
+		known[id] = true
+	}
+	var added []primitive.ObjectID
+	for _, id := range c.ReflectionIDs {
+		if !known[id] {
+			added = append(added, id)
+		}
+	}
+	if len(added) == 0 {
+		return nil
+	}
+
+	// The new reading doesn't change where the follow-up stands.
+	for i := range c.History {
+		c.History[i].Status = existing.Status
+	}
+	update := bson.M{
+		"$addToSet": bson.M{"reflection_ids": bson.M{"$each": added}},
+		"$push":     bson.M{"history": bson.M{"$each": c.History}},
+		"$set":      bson.M{"updated_at": c.UpdatedAt, "reason": c.Reason},
+	}
+	*c = *existing
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": existing.ID}, update)
+	return err
+}
+
+func (r *wellbeingCaseRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*domain.WellbeingCase, error) {
+	var c domain.WellbeingCase
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&c)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrCaseNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *wellbeingCaseRepository) FindAll(ctx context.Context, filter domain.WellbeingCaseFilter, skip, limit int64) ([]domain.WellbeingCase, int64, error) {
+	query := bson.M{}
+	if filter.Cohort > 0 {
+		query["cohort_number"] = filter.Cohort
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	if !filter.AssignedTo.IsZero() {
+		query["assigned_to"] = filter.AssignedTo
+	}
+	if !filter.UserID.IsZero() {
+		query["user_id"] = filter.UserID
+	}
+
+	total, err := r.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "active", Value: -1}, {Key: "updated_at", Value: -1}}).
+		SetSkip(skip).
+		SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	cases := []domain.WellbeingCase{}
+	if err := cursor.All(ctx, &cases); err != nil {
+		return nil, 0, err
+	}
+	return cases, total, nil
+}
+
+func (r *wellbeingCaseRepository) Update(ctx context.Context, c *domain.WellbeingCase, event domain.CaseEvent) error {
+	update := bson.M{
+		"$set": bson.M{
+			"status":        c.Status,
+			"active":        c.Active,
+			"assigned_to":   c.AssignedTo,
+			"assigned_name": c.AssignedName,
+			"updated_at":    c.UpdatedAt,
+			"resolved_at":   c.ResolvedAt,
+		},
+		"$push": bson.M{"history": event},
+	}
+	// Resolved cases are final.
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": c.ID, "active": true}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrCaseClosed
+	}
+	c.History = append(c.History, event)
+	return nil
+}
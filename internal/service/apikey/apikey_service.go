@@ -0,0 +1,111 @@
+package apikey
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"gofiber-baro/internal/domain"
+	"gofiber-baro/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// KeyPrefix marks a bearer credential as an API key rather than a JWT. It
+// must match apiKeyPrefix in pkg/middleware.
+const KeyPrefix = "baro_"
+
+// Service manages integration API keys and authenticates requests made with
+// them.
+type Service struct {
+	repo      domain.APIKeyRepository
+	usageRepo domain.APIKeyUsageRepository
+}
+
+func NewService(repo domain.APIKeyRepository, usageRepo domain.APIKeyUsageRepository) *Service {
+	return &Service{repo: repo, usageRepo: usageRepo}
+}
+
+// Create issues a key. The raw key is returned once and never stored.
+func (s *Service) Create(name string, permissions []string, expiresAt *time.Time, createdBy primitive.ObjectID) (string, *domain.APIKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	secret, _, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", nil, err
+	}
+	raw := KeyPrefix + secret
+	key := &domain.APIKey{
+		Name:        name,
+		Prefix:      raw[:len(KeyPrefix)+8],
+		KeyHash:     utils.HashToken(raw),
+		Permissions: permissions,
+		CreatedBy:   createdBy,
+		CreatedAt:   time.Now(),
+		ExpiresAt:   expiresAt,
+	}
+	if err := s.repo.Insert(ctx, key); err != nil {
+		return "", nil, err
+	}
+	return raw, key, nil
+}
+
+func (s *Service) List() ([]domain.APIKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return s.repo.FindAll(ctx)
+}
+
+func (s *Service) Revoke(id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.repo.Revoke(ctx, id, time.Now())
+}
+
+// Usage lists the requests made with a key, newest first.
+func (s *Service) Usage(id primitive.ObjectID, page, limit int) ([]domain.APIKeyUsage, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return s.usageRepo.FindByKey(ctx, id, int64((page-1)*limit), int64(limit))
+}
+
+// AuthenticateAPIKey satisfies middleware.APIKeyAuthenticator. ok is false
+// for unknown, revoked and expired keys.
+func (s *Service) AuthenticateAPIKey(ctx context.Context, raw string) (keyID string, permissions []string, ok bool, err error) {
+	key, err := s.repo.FindByHash(ctx, utils.HashToken(raw))
+	if err == domain.ErrAPIKeyNotFound {
+		return "", nil, false, nil
+	}
+	if err != nil {
+		return "", nil, false, err
+	}
+	if !key.Active(time.Now()) {
+		return "", nil, false, nil
+	}
+	return key.ID.Hex(), key.Permissions, true, nil
+}
+
+// RecordAPIKeyUse satisfies middleware.APIKeyAuthenticator: it logs the
+// request and bumps the key's last-used time. Failures are logged only.
+func (s *Service) RecordAPIKeyUse(ctx context.Context, keyID, method, route, ip string, status int) {
+	oid, err := primitive.ObjectIDFromHex(keyID)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	usage := &domain.APIKeyUsage{
+		KeyID:      oid,
+		Method:     method,
+		Route:      route,
+		StatusCode: status,
+		IPAddress:  ip,
+		UsedAt:     now,
+	}
+	if err := s.usageRepo.Insert(ctx, usage); err != nil {
+		log.Printf("[ERROR] api key: record use of %s: %v", keyID, err)
+	}
+	if err := s.repo.TouchLastUsed(ctx, oid, now); err != nil {
+		log.Printf("[ERROR] api key: touch %s: %v", keyID, err)
+	}
+}
@@ -117,6 +117,8 @@ func clearAttempts(userID primitive.ObjectID, session string) {
 type UserServiceInterface interface {
 	GetUserByID(id string) (*domain.User, error)
 	GetAllUsers(cohort int, role, email, search, sort string, sortDir, page, limit int, excludeAttendanceStatus ...string) ([]domain.User, int, error)
+	GetGroupMembers(groupID primitive.ObjectID) ([]domain.User, error)
+	GetRoster(cohort int, role string, limit int) ([]domain.User, error)
 }
 
 type CodeService struct {
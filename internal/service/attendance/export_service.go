@@ -1,9 +1,7 @@
 package attendance
 
 import (
-	"bytes"
 	"context"
-	"encoding/csv"
 	"fmt"
 	"sort"
 	"strings"
@@ -11,9 +9,10 @@ import (
 
 	"gofiber-baro/internal/domain"
 	userService "gofiber-baro/internal/service/user"
+	"gofiber-baro/pkg/utils"
 
-	"github.com/xuri/excelize/v2"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -40,7 +39,8 @@ type ExportRequest struct {
 	Structure  ExportStructure
 	SplitAMPM  bool
 	LeaveData  []domain.LeaveRequest
-	StatusFilter string // "", "active", "dropout", "dismissed"
+	StatusFilter string // "" or "all", or a domain.LearnerStatus
+	GroupID      primitive.ObjectID // zero for the whole cohort
 }
 
 type ExportService struct {
@@ -117,17 +117,20 @@ func buildAttendanceLookup(records []domain.AttendanceRecord) map[sessionKey]dom
 	return m
 }
 
-func isExcludedAttendanceStatus(status string) bool {
-	return status == "dropout" || status == "dismissed"
-}
 
 func Export(req ExportRequest, recordRepo domain.AttendanceRepository, userService *userService.Service) ([]byte, string, error) {
 	ctx := context.Background()
 
-	users, _, err := userService.GetAllUsers(req.Cohort, "learner", "", "", "first_name", 1, 1, 5000)
+	users, err := userService.GetRoster(req.Cohort, "learner", 5000)
 	if err != nil {
 		return nil, "", fmt.Errorf("fetch users: %w", err)
 	}
+	if !req.GroupID.IsZero() {
+		users, err = filterByGroup(users, req.GroupID, userService)
+		if err != nil {
+			return nil, "", fmt.Errorf("fetch group: %w", err)
+		}
+	}
 
 	findOpts := options.Find().SetSort(bson.D{
 		{Key: "date", Value: 1},
@@ -153,7 +156,7 @@ func Export(req ExportRequest, recordRepo domain.AttendanceRepository, userServi
 	}
 	dates := sortedKeys(dateSet)
 
-	filteredUsers := filterByStatus(users, req.StatusFilter)
+	filteredUsers := filterByStatus(users, req.StatusFilter, req.StartDate, req.EndDate)
 
 	switch req.Structure {
 	case ExportStructureSummary:
@@ -165,23 +168,41 @@ func Export(req ExportRequest, recordRepo domain.AttendanceRepository, userServi
 	}
 }
 
-func filterByStatus(users []domain.User, statusFilter string) []domain.User {
+// filterByGroup keeps the cohort's learners who are in the group.
+func filterByGroup(users []domain.User, groupID primitive.ObjectID, userService *userService.Service) ([]domain.User, error) {
+	members, err := userService.GetGroupMembers(groupID)
+	if err != nil {
+		return nil, err
+	}
+	inGroup := make(map[primitive.ObjectID]bool, len(members))
+	for _, m := range members {
+		inGroup[m.ID] = true
+	}
+	kept := make([]domain.User, 0, len(members))
+	for _, u := range users {
+		if inGroup[u.ID] {
+			kept = append(kept, u)
+		}
+	}
+	return kept, nil
+}
+
+// filterByStatus keeps "active" learners who were active at some point in the
+// range; any other status is matched by the learner's status at the end of
+// it.
+func filterByStatus(users []domain.User, statusFilter, startDate, endDate string) []domain.User {
 	if statusFilter == "" || statusFilter == "all" {
 		return users
 	}
 	var filtered []domain.User
 	for _, u := range users {
-		switch statusFilter {
-		case "active":
-			if !isExcludedAttendanceStatus(u.AttendanceStatus) {
-				filtered = append(filtered, u)
-			}
-		case "dropout":
-			if u.AttendanceStatus == "dropout" {
+		switch domain.LearnerStatus(statusFilter) {
+		case domain.LearnerActive:
+			if u.ActiveDuring(startDate, endDate) {
 				filtered = append(filtered, u)
 			}
-		case "dismissed":
-			if u.AttendanceStatus == "dismissed" {
+		default:
+			if string(u.StatusOn(endDate)) == statusFilter {
 				filtered = append(filtered, u)
 			}
 		}
@@ -197,7 +218,7 @@ func dailyStatusFromLookup(u domain.User, date string, lookup map[sessionKey]dom
 	amStatus := ""
 	pmStatus := ""
 
-	if isExcludedAttendanceStatus(u.AttendanceStatus) {
+	if !u.CountsOn(date) {
 		return amStatus, pmStatus, status
 	}
 
@@ -356,8 +377,10 @@ func buildSummaries(users []domain.User, lookup map[sessionKey]domain.Attendance
 		afternoon string
 	})
 
-	for _, u := range users {
+	byID := make(map[string]*domain.User, len(users))
+	for i, u := range users {
 		uid := u.ID.Hex()
+		byID[uid] = &users[i]
 		summaries[uid] = &userSummary{}
 		userDates[uid] = make(map[string]struct {
 			morning   string
@@ -370,6 +393,10 @@ func buildSummaries(users []domain.User, lookup map[sessionKey]domain.Attendance
 		if _, ok := summaries[uid]; !ok {
 			continue
 		}
+		// Days after a learner left (or before they rejoined) don't count.
+		if !byID[uid].CountsOn(key.date) {
+			continue
+		}
 		us := summaries[uid]
 		switch status {
 		case domain.StatusPresent:
@@ -530,7 +557,7 @@ func weeklyRows(users []domain.User, lookup map[sessionKey]domain.AttendanceStat
 				dateStr := d.Format("2006-01-02")
 				morning := lookup[sessionKey{uid, dateStr, domain.SessionMorning}]
 				afternoon := lookup[sessionKey{uid, dateStr, domain.SessionAfternoon}]
-				if isExcludedAttendanceStatus(u.AttendanceStatus) {
+				if !u.CountsOn(dateStr) {
 					continue
 				}
 				if morning == domain.StatusAbsent || afternoon == domain.StatusAbsent {
@@ -552,65 +579,6 @@ func weeklyRows(users []domain.User, lookup map[sessionKey]domain.AttendanceStat
 	return rows
 }
 
-// ---- CSV OUTPUT ----
-
-func writeCSV(headers []string, rows [][]string) ([]byte, error) {
-	var buf bytes.Buffer
-	w := csv.NewWriter(&buf)
-	if err := w.Write(headers); err != nil {
-		return nil, err
-	}
-	for _, row := range rows {
-		if err := w.Write(row); err != nil {
-			return nil, err
-		}
-	}
-	w.Flush()
-	return buf.Bytes(), w.Error()
-}
-
-// ---- EXCEL OUTPUT ----
-
-func writeXLSX(headers []string, rows [][]string) ([]byte, error) {
-	f := excelize.NewFile()
-	defer f.Close()
-
-	sheet := "Sheet1"
-	style, _ := f.NewStyle(&excelize.Style{
-		Font: &excelize.Font{Bold: true, Color: "FFFFFF"},
-		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"4472C4"}},
-	})
-	f.SetCellStyle(sheet, "A1", cellRef(len(headers), 1), style)
-
-	for col, h := range headers {
-		cell, _ := excelize.CoordinatesToCellName(col+1, 1)
-		f.SetCellValue(sheet, cell, h)
-	}
-
-	for rowIdx, row := range rows {
-		for colIdx, val := range row {
-			cell, _ := excelize.CoordinatesToCellName(colIdx+1, rowIdx+2)
-			f.SetCellValue(sheet, cell, val)
-		}
-	}
-
-	for col := 0; col < len(headers); col++ {
-		colLetter, _ := excelize.ColumnNumberToName(col + 1)
-		f.SetColWidth(sheet, colLetter, colLetter, 18)
-	}
-
-	var buf bytes.Buffer
-	if err := f.Write(&buf); err != nil {
-		return nil, err
-	}
-	return buf.Bytes(), nil
-}
-
-func cellRef(cols int, row int) string {
-	colLetter, _ := excelize.ColumnNumberToName(cols)
-	return fmt.Sprintf("%s%d", colLetter, row)
-}
-
 // ---- EXPORT BY STRUCTURE ----
 
 func exportDaily(req ExportRequest, users []domain.User, lookup map[sessionKey]domain.AttendanceStatus, dates []string) ([]byte, string, error) {
@@ -641,10 +609,10 @@ func exportDaily(req ExportRequest, users []domain.User, lookup map[sessionKey]d
 	switch req.Format {
 	case ExportFormatXLSX:
 		ext = "xlsx"
-		data, err := writeXLSX(headers, rows)
+		data, err := utils.WriteXLSX(headers, rows)
 		return data, ext, err
 	default:
-		data, err := writeCSV(headers, rows)
+		data, err := utils.WriteCSV(headers, rows)
 		return data, ext, err
 	}
 }
@@ -658,10 +626,10 @@ func exportSummary(req ExportRequest, users []domain.User, lookup map[sessionKey
 	switch req.Format {
 	case ExportFormatXLSX:
 		ext = "xlsx"
-		data, err := writeXLSX(headers, rows)
+		data, err := utils.WriteXLSX(headers, rows)
 		return data, ext, err
 	default:
-		data, err := writeCSV(headers, rows)
+		data, err := utils.WriteCSV(headers, rows)
 		return data, ext, err
 	}
 }
@@ -675,10 +643,10 @@ func exportWeekly(req ExportRequest, users []domain.User, lookup map[sessionKey]
 	switch req.Format {
 	case ExportFormatXLSX:
 		ext = "xlsx"
-		data, err := writeXLSX(headers, rows)
+		data, err := utils.WriteXLSX(headers, rows)
 		return data, ext, err
 	default:
-		data, err := writeCSV(headers, rows)
+		data, err := utils.WriteCSV(headers, rows)
 		return data, ext, err
 	}
 }
@@ -67,13 +67,16 @@ func (s *OverviewService) GetAttendanceOverviewByDate(cohort int, session domain
 		}
 	}
 
-	users, _, err := s.userService.GetAllUsers(cohort, "", "", "", "first_name", 1, 1, 500, "dropout,dismissed")
+	users, err := s.userService.GetRoster(cohort, "", 500)
 	if err != nil {
 		return nil, err
 	}
 
 	students := make([]domain.StudentAttendanceRow, 0, len(users))
 	for _, user := range users {
+		if !user.CountsOn(targetDate) {
+			continue
+		}
 		row := domain.StudentAttendanceRow{
 			UserID:    user.ID,
 			JSDNumber: user.JSDNumber,
@@ -24,7 +24,33 @@ func NewStatsService(recordRepo domain.AttendanceRepository, userService UserSer
 	}
 }
 
-func (s *StatsService) GetAttendanceStats(cohort int, startDate, endDate string) ([]domain.AttendanceStats, error) {
+// groupMembers returns the learners of a group, limited to cohort when one
+// is given.
+func (s *StatsService) groupMembers(groupID primitive.ObjectID, cohort int) ([]domain.User, error) {
+	users, err := s.userService.GetGroupMembers(groupID)
+	if err != nil {
+		return nil, err
+	}
+	members := make([]domain.User, 0, len(users))
+	for _, u := range users {
+		if cohort <= 0 || u.CohortNumber == cohort {
+			members = append(members, u)
+		}
+	}
+	return members, nil
+}
+
+func userIDs(users []domain.User) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, len(users))
+	for i := range users {
+		ids[i] = users[i].ID
+	}
+	return ids
+}
+
+// GetAttendanceStats summarises each learner's attendance. A non-zero
+// groupID limits it to that group's members.
+func (s *StatsService) GetAttendanceStats(cohort int, groupID primitive.ObjectID, startDate, endDate string) ([]domain.AttendanceStats, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -34,6 +60,13 @@ func (s *StatsService) GetAttendanceStats(cohort int, startDate, endDate string)
 	if cohort > 0 {
 		bsonFilter["cohort_number"] = cohort
 	}
+	if !groupID.IsZero() {
+		members, err := s.groupMembers(groupID, cohort)
+		if err != nil {
+			return nil, err
+		}
+		bsonFilter["user_id"] = bson.M{"$in": userIDs(members)}
+	}
 	if startDate != "" && endDate != "" {
 		bsonFilter["date"] = bson.M{"$gte": startDate, "$lte": endDate}
 	}
@@ -178,7 +211,8 @@ func toInt(v interface{}) int {
 }
 
 // GetDailyAttendanceStatsByDateRange - get daily stats for a specific date range
-func (s *StatsService) GetDailyAttendanceStatsByDateRange(cohort int, startDate, endDate string) ([]map[string]interface{}, error) {
+// A non-zero groupID limits it to that group's members.
+func (s *StatsService) GetDailyAttendanceStatsByDateRange(cohort int, groupID primitive.ObjectID, startDate, endDate string) ([]map[string]interface{}, error) {
 	// If no startDate provided, default to 30 days ago
 	if startDate == "" {
 		startDate = utils.GetThailandTime().AddDate(0, 0, -30).Format("2006-01-02")
@@ -200,6 +234,15 @@ func (s *StatsService) GetDailyAttendanceStatsByDateRange(cohort int, startDate,
 	if cohort > 0 {
 		matchFilter["cohort_number"] = cohort
 	}
+	var groupLearners []domain.User
+	if !groupID.IsZero() {
+		members, err := s.groupMembers(groupID, cohort)
+		if err != nil {
+			return nil, err
+		}
+		groupLearners = members
+		matchFilter["user_id"] = bson.M{"$in": userIDs(members)}
+	}
 
 	// First aggregation: get stats grouped by date AND session (AM/PM)
 	// We first group by date, session and user_id to ensure each student is only counted once per session
@@ -233,16 +276,26 @@ func (s *StatsService) GetDailyAttendanceStatsByDateRange(cohort int, startDate,
 		return nil, err
 	}
 
-	// Get cohort learner count
-	cohortTotal := 0
-	if cohort > 0 {
-		users, _, err := s.userService.GetAllUsers(cohort, "learner", "", "", "email", 1, 0, 0, "dropout,dismissed")
+	// Get cohort learners; the expected headcount varies by date as learners
+	// leave or come back.
+	learners := groupLearners
+	if groupID.IsZero() && cohort > 0 {
+		users, err := s.userService.GetRoster(cohort, "learner", 0)
 		if err == nil {
-			cohortTotal = len(users)
+			learners = users
 		} else {
 			log.Printf("[WARN] GetDailyAttendanceStats: could not get cohort %d count: %v", cohort, err)
 		}
 	}
+	cohortTotalOn := func(date string) int {
+		n := 0
+		for i := range learners {
+			if learners[i].CountsOn(date) {
+				n++
+			}
+		}
+		return n
+	}
 
 	// Transform results: group by date, combine AM/PM
 	dateMap := make(map[string]map[string]interface{})
@@ -317,7 +370,7 @@ func (s *StatsService) GetDailyAttendanceStatsByDateRange(cohort int, startDate,
 		sessionAbsentTotal := absent + absentExcused
 
 		// Use cohortTotal for denominators if available
-		totalForSession := cohortTotal
+		totalForSession := cohortTotalOn(date)
 		if totalForSession == 0 {
 			totalForSession = sessionPresent + sessionAbsentTotal
 		}
@@ -335,7 +388,8 @@ func (s *StatsService) GetDailyAttendanceStatsByDateRange(cohort int, startDate,
 
 	// Convert map to slice and add cohort total
 	finalResults := make([]map[string]interface{}, 0, len(dateMap))
-	for _, v := range dateMap {
+	for date, v := range dateMap {
+		cohortTotal := cohortTotalOn(date)
 		v["total"] = cohortTotal
 
 		// Calculate attendance rate
@@ -364,7 +418,7 @@ func (s *StatsService) GetDailyAttendanceStatsByDateRange(cohort int, startDate,
 		finalResults = append(finalResults, v)
 	}
 
-	log.Printf("[DEBUG] GetDailyAttendanceStatsByDateRange: cohort=%d, startDate=%s, endDate=%s, totalDates=%d, cohortTotal=%d", cohort, startDate, endDate, len(finalResults), cohortTotal)
+	log.Printf("[DEBUG] GetDailyAttendanceStatsByDateRange: cohort=%d, startDate=%s, endDate=%s, totalDates=%d, learners=%d", cohort, startDate, endDate, len(finalResults), len(learners))
 
 	return finalResults, nil
 }
@@ -77,6 +77,20 @@ func (s *SubmissionService) ManualMarkAttendance(userID primitive.ObjectID, date
 	return record, nil
 }
 
+// FindRecord returns the live record for a learner's session, or an error if
+// there is none.
+func (s *SubmissionService) FindRecord(userID primitive.ObjectID, date, session string) (*domain.AttendanceRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return s.recordRepo.FindRecord(ctx, domain.AttendanceRecordFilter{
+		UserID:     userID,
+		Date:       date,
+		Session:    domain.AttendanceSession(session),
+		NotDeleted: true,
+	})
+}
+
 func (s *SubmissionService) BulkMarkAttendance(userIDs []primitive.ObjectID, date string, session domain.AttendanceSession, status domain.AttendanceStatus, markedBy string) ([]domain.AttendanceRecord, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -0,0 +1,103 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"gofiber-baro/internal/domain"
+	"gofiber-baro/pkg/utils"
+)
+
+// Service keeps the audit trail of admin mutations. Entries are append-only;
+// there is deliberately no update or delete.
+type Service struct {
+	repo     domain.AuditLogRepository
+	userRepo domain.UserRepository
+}
+
+func NewService(repo domain.AuditLogRepository, userRepo domain.UserRepository) *Service {
+	return &Service{repo: repo, userRepo: userRepo}
+}
+
+// Record stores entry, caching the actor's and (for user targets) the
+// target's display names so the log still reads after users are deleted.
+func (s *Service) Record(ctx context.Context, entry *domain.AuditLog) error {
+	if entry.ActorName == "" && !entry.ActorID.IsZero() {
+		if u, err := s.userRepo.FindByID(ctx, entry.ActorID); err == nil {
+			entry.ActorName = displayName(u)
+		}
+	}
+	if entry.TargetName == "" && entry.TargetType == "user" && !entry.TargetID.IsZero() {
+		if u, err := s.userRepo.FindByID(ctx, entry.TargetID); err == nil {
+			entry.TargetName = displayName(u)
+		}
+	}
+	entry.CreatedAt = time.Now()
+	return s.repo.Insert(ctx, entry)
+}
+
+func (s *Service) List(filter domain.AuditLogFilter, page, limit int) ([]domain.AuditLog, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return s.repo.FindAll(ctx, filter, int64((page-1)*limit), int64(limit))
+}
+
+// ExportCSV writes every entry matching filter, newest first.
+func (s *Service) ExportCSV(filter domain.AuditLogFilter) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	logs, _, err := s.repo.FindAll(ctx, filter, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := []string{"Time", "Action", "Actor ID", "Actor", "Target Type", "Target ID", "Target", "Details", "Before", "After", "IP Address"}
+	rows := make([][]string, 0, len(logs))
+	for _, l := range logs {
+		targetID := ""
+		if !l.TargetID.IsZero() {
+			targetID = l.TargetID.Hex()
+		}
+		row := []string{
+			l.CreatedAt.Format(time.RFC3339),
+			l.Action,
+			l.ActorID.Hex(),
+			l.ActorName,
+			l.TargetType,
+			targetID,
+			l.TargetName,
+			l.Details,
+			toJSON(l.Before),
+			toJSON(l.After),
+			l.IPAddress,
+		}
+		// Names, details and values can hold what learners typed.
+		for i := range row {
+			row[i] = utils.CSVCell(row[i])
+		}
+		rows = append(rows, row)
+	}
+	return utils.WriteCSV(headers, rows)
+}
+
+func displayName(u *domain.User) string {
+	name := strings.TrimSpace(u.FirstName + " " + u.LastName)
+	if name == "" {
+		return u.Email
+	}
+	return name
+}
+
+func toJSON(v map[string]interface{}) string {
+	if v == nil {
+		return ""
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
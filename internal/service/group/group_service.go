@@ -0,0 +1,281 @@
+package group
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"gofiber-baro/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Service manages a cohort's project and genmate groups and who is in them.
+type Service struct {
+	repo     domain.GroupRepository
+	members  domain.GroupMembershipRepository
+	userRepo domain.UserRepository
+}
+
+func NewService(repo domain.GroupRepository, members domain.GroupMembershipRepository, userRepo domain.UserRepository) *Service {
+	return &Service{repo: repo, members: members, userRepo: userRepo}
+}
+
+// GroupInput is a group's editable metadata. Nil fields are left unchanged
+// on update.
+type GroupInput struct {
+	Name        *string
+	Mentor      *string
+	ChannelURL  *string
+	Description *string
+}
+
+// MigrationResult reports what MigrateLegacyNames did.
+type MigrationResult struct {
+	GroupsCreated int   `json:"groups_created"`
+	UsersLinked   int64 `json:"users_linked"`
+}
+
+// nameKey is how group names are compared: case and extra spaces ignored.
+func nameKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+func (s *Service) Create(cohort int, kind domain.GroupKind, in GroupInput) (*domain.Group, error) {
+	if !kind.Valid() {
+		return nil, domain.ErrInvalidGroupKind
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	group := &domain.Group{CohortNumber: cohort, Kind: kind, CreatedAt: now, UpdatedAt: now}
+	applyInput(group, in)
+	group.NameKey = nameKey(group.Name)
+	if err := s.repo.Insert(ctx, group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func applyInput(group *domain.Group, in GroupInput) {
+	if in.Name != nil {
+		group.Name = strings.Join(strings.Fields(*in.Name), " ")
+	}
+	if in.Mentor != nil {
+		group.Mentor = strings.TrimSpace(*in.Mentor)
+	}
+	if in.ChannelURL != nil {
+		group.ChannelURL = strings.TrimSpace(*in.ChannelURL)
+	}
+	if in.Description != nil {
+		group.Description = strings.TrimSpace(*in.Description)
+	}
+}
+
+// List returns a cohort's groups with their member counts; an empty kind
+// lists both kinds.
+func (s *Service) List(cohort int, kind domain.GroupKind) ([]domain.Group, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	groups, err := s.repo.FindByCohort(ctx, cohort, kind)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, len(groups))
+	for i := range groups {
+		ids[i] = groups[i].ID
+	}
+	counts, err := s.members.CountMembers(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range groups {
+		groups[i].MemberCount = counts[groups[i].ID]
+	}
+	return groups, nil
+}
+
+func (s *Service) Get(id primitive.ObjectID) (*domain.Group, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	group, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.members.CountMembers(ctx, []primitive.ObjectID{id})
+	if err != nil {
+		return nil, err
+	}
+	group.MemberCount = counts[id]
+	return group, nil
+}
+
+// Update edits a group's metadata. A new name is copied onto its members.
+func (s *Service) Update(id primitive.ObjectID, in GroupInput) (*domain.Group, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	group, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	oldName := group.Name
+	applyInput(group, in)
+	group.NameKey = nameKey(group.Name)
+
+	fields := map[string]interface{}{
+		"name":        group.Name,
+		"name_key":    group.NameKey,
+		"mentor":      group.Mentor,
+		"channel_url": group.ChannelURL,
+		"description": group.Description,
+	}
+	if err := s.repo.Update(ctx, id, fields); err != nil {
+		return nil, err
+	}
+	if group.Name != oldName {
+		if err := s.members.Rename(ctx, group); err != nil {
+			return nil, err
+		}
+	}
+	return group, nil
+}
+
+// Delete removes a group after taking its members out of it.
+func (s *Service) Delete(id primitive.ObjectID) (*domain.Group, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	group, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.members.Unassign(ctx, group, nil); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// Members lists a group's non-deleted members by first name.
+func (s *Service) Members(id primitive.ObjectID) ([]domain.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := s.repo.FindByID(ctx, id); err != nil {
+		return nil, err
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "first_name", Value: 1}})
+	users, _, err := s.userRepo.FindAll(ctx, domain.UserFilter{GroupID: id}, opts)
+	if err != nil {
+		return nil, err
+	}
+	active := make([]domain.User, 0, len(users))
+	for _, u := range users {
+		if !u.Deleted {
+			active = append(active, u)
+		}
+	}
+	return active, nil
+}
+
+// AssignMembers moves users into a group, out of any other group of the
+// same kind. Users outside the group's cohort are left alone and counted in
+// skipped.
+func (s *Service) AssignMembers(id primitive.ObjectID, userIDs []primitive.ObjectID) (group *domain.Group, assigned, skipped int64, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	group, err = s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	assigned, err = s.members.Assign(ctx, group, userIDs)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return group, assigned, int64(len(userIDs)) - assigned, nil
+}
+
+// RemoveMembers takes users out of a group.
+func (s *Service) RemoveMembers(id primitive.ObjectID, userIDs []primitive.ObjectID) (*domain.Group, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	group, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	removed, err := s.members.Unassign(ctx, group, userIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+	return group, removed, nil
+}
+
+// MigrateLegacyNames turns the free-text project_group and genmate_group
+// values of users without a group ID into groups, one per cohort, kind and
+// name (ignoring case and spacing), and links the users to them. It is safe
+// to run repeatedly: existing groups are reused and linked users skipped.
+func (s *Service) MigrateLegacyNames() (MigrationResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	var result MigrationResult
+	for _, kind := range []domain.GroupKind{domain.GroupKindProject, domain.GroupKindGenmate} {
+		names, err := s.members.LegacyNames(ctx, kind)
+		if err != nil {
+			return result, err
+		}
+
+		type bucket struct {
+			cohort int
+			key    string
+		}
+		spellings := map[bucket][]string{}
+		var order []bucket
+		for _, n := range names {
+			key := nameKey(n.Name)
+			if key == "" {
+				continue
+			}
+			b := bucket{n.CohortNumber, key}
+			if _, seen := spellings[b]; !seen {
+				order = append(order, b)
+			}
+			spellings[b] = append(spellings[b], n.Name)
+		}
+
+		for _, b := range order {
+			group, err := s.repo.FindByNameKey(ctx, b.cohort, kind, b.key)
+			if err == domain.ErrGroupNotFound {
+				name := spellings[b][0]
+				group, err = s.Create(b.cohort, kind, GroupInput{Name: &name})
+				if err == nil {
+					result.GroupsCreated++
+				}
+			}
+			if err != nil {
+				return result, err
+			}
+			linked, err := s.members.AssignLegacy(ctx, group, spellings[b])
+			if err != nil {
+				return result, err
+			}
+			result.UsersLinked += linked
+		}
+	}
+
+	if result.GroupsCreated > 0 || result.UsersLinked > 0 {
+		log.Printf("[INFO] groups: migrated free-text names into %d new groups, linked %d users", result.GroupsCreated, result.UsersLinked)
+	}
+	return result, nil
+}
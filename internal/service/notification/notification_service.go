@@ -42,8 +42,11 @@ func (s *Service) GetAllNotifications() ([]domain.Notification, error) {
 	return s.repo.GetAll()
 }
 
-func (s *Service) GetActiveNotifications() ([]domain.Notification, error) {
-	return s.repo.GetActive()
+// GetActiveNotifications returns the active broadcasts and, for a signed-in
+// user, the notifications addressed to them.
+func (s *Service) GetActiveNotifications(userID string) ([]domain.Notification, error) {
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+	return s.repo.GetActive(userObjID)
 }
 
 func (s *Service) GetNotificationByID(id string) (*domain.Notification, error) {
@@ -92,12 +95,12 @@ func (s *Service) IsNotificationReadByUser(notification *domain.Notification, us
 }
 
 func (s *Service) GetUnreadNotifications(userID string) ([]domain.Notification, error) {
-	notifications, err := s.repo.GetActive()
+	userObjID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return nil, err
 	}
 
-	userObjID, err := primitive.ObjectIDFromHex(userID)
+	notifications, err := s.repo.GetActive(userObjID)
 	if err != nil {
 		return nil, err
 	}
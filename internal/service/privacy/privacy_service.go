@@ -0,0 +1,542 @@
+package privacy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"gofiber-baro/internal/domain"
+	"gofiber-baro/internal/storage"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Placeholders written over personal data on erasure.
+const (
+	erasedFirstName = "Deleted"
+	erasedLastName  = "User"
+	erasedName      = "Deleted user"
+	erasedContent   = "[removed]"
+)
+
+// secretUserFields never leave the database, not even in the user's own
+// export.
+var secretUserFields = []string{
+	"password", "token_version", "totp_secret", "totp_pending_secret", "totp_last_step", "recovery_codes",
+}
+
+// Service answers PDPA data subject requests: a full export of one user's
+// data, and erasure, which anonymises the user everywhere while leaving
+// attendance, barometer and reward figures in place for cohort statistics.
+type Service struct {
+	db      *mongo.Database
+	repo    domain.PrivacyRequestRepository
+	storage storage.Storage
+}
+
+func NewService(db *mongo.Database, repo domain.PrivacyRequestRepository, storage storage.Storage) *Service {
+	return &Service{db: db, repo: repo, storage: storage}
+}
+
+// exportSource is one collection's worth of a user's documents.
+type exportSource struct {
+	file       string
+	collection string
+	filter     bson.M
+	projection bson.M
+}
+
+func exportSources(userID primitive.ObjectID) []exportSource {
+	return []exportSource{
+		{"attendance_records.json", "attendance_records", bson.M{"user_id": userID}, nil},
+		{"leave_requests.json", "leave_requests", bson.M{"user_id": userID}, nil},
+		{"reflections.json", "reflections", bson.M{"user_id": userID}, nil},
+		{"reflection_feedback.json", "reflection_feedback", bson.M{"$or": bson.A{bson.M{"learner_id": userID}, bson.M{"author_id": userID}}}, nil},
+		{"wellbeing_cases.json", "wellbeing_cases", bson.M{"user_id": userID}, nil},
+		{"notifications.json", "notifications", bson.M{"recipient_id": userID}, nil},
+		{"board_posts.json", "talk_board", bson.M{"userId": userID}, nil},
+		{"stamps.json", "stamps", bson.M{"ownerId": userID}, nil},
+		{"cohort_memberships.json", "cohort_memberships", bson.M{"user_id": userID}, nil},
+		{"sessions.json", "refresh_tokens", bson.M{"user_id": userID}, bson.M{"token_hash": 0}},
+		{"lockout_events.json", "lockout_events", bson.M{"user_id": userID}, nil},
+		{"audit_logs.json", "audit_logs", bson.M{"target_id": userID}, nil},
+	}
+}
+
+// Export packages everything stored about a user into a ZIP: one JSON file
+// per collection, plus their uploaded stamp images under attachments/.
+func (s *Service) Export(userID, requestedBy primitive.ObjectID) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	var user bson.M
+	if err := s.db.Collection("users").FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, err
+	}
+	for _, field := range secretUserFields {
+		delete(user, field)
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	summary := map[string]int64{}
+
+	if err := writeJSON(zw, "user.json", user); err != nil {
+		return nil, err
+	}
+
+	var stamps []bson.M
+	for _, src := range exportSources(userID) {
+		docs, err := s.find(ctx, src.collection, src.filter, src.projection)
+		if err != nil {
+			return nil, fmt.Errorf("export %s: %w", src.collection, err)
+		}
+		if err := writeJSON(zw, src.file, docs); err != nil {
+			return nil, err
+		}
+		summary[src.collection] = int64(len(docs))
+		if src.collection == "stamps" {
+			stamps = docs
+		}
+	}
+
+	// Comments written on other people's posts and profiles live inside
+	// those documents.
+	boardComments, err := s.aggregate(ctx, "talk_board", "comments", "userId", userID, "post_id")
+	if err != nil {
+		return nil, fmt.Errorf("export board comments: %w", err)
+	}
+	if err := writeJSON(zw, "board_comments.json", boardComments); err != nil {
+		return nil, err
+	}
+	profileComments, err := s.aggregate(ctx, "users", "profile_comments", "userId", userID, "profile_user_id")
+	if err != nil {
+		return nil, fmt.Errorf("export profile comments: %w", err)
+	}
+	if err := writeJSON(zw, "profile_comments_written.json", profileComments); err != nil {
+		return nil, err
+	}
+
+	missing := s.writeStampImages(ctx, zw, stamps)
+
+	manifest := bson.M{
+		"user_id":             userID.Hex(),
+		"generated_at":        time.Now().Format(time.RFC3339),
+		"documents":           summary,
+		"missing_attachments": missing,
+	}
+	if err := writeJSON(zw, "manifest.json", manifest); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	record := &domain.PrivacyRequest{
+		UserID:      userID,
+		Type:        domain.PrivacyExport,
+		Status:      domain.PrivacyCompleted,
+		RequestedBy: requestedBy,
+		RequestedAt: now,
+		CompletedAt: &now,
+		Summary:     summary,
+	}
+	if err := s.repo.Insert(ctx, record); err != nil {
+		log.Printf("[ERROR] privacy: record export for %s: %v", userID.Hex(), err)
+	}
+	return buf.Bytes(), nil
+}
+
+// writeStampImages copies each stamp image into the archive and returns the
+// URLs that could not be fetched.
+func (s *Service) writeStampImages(ctx context.Context, zw *zip.Writer, stamps []bson.M) []string {
+	missing := []string{}
+	for _, stamp := range stamps {
+		url, _ := stamp["imageUrl"].(string)
+		if url == "" {
+			continue
+		}
+		if s.storage == nil {
+			missing = append(missing, url)
+			continue
+		}
+		key, ok := s.storage.KeyFromURL(url)
+		if !ok {
+			missing = append(missing, url)
+			continue
+		}
+		body, err := s.storage.Download(ctx, key)
+		if err != nil {
+			log.Printf("[ERROR] privacy: download %s: %v", key, err)
+			missing = append(missing, url)
+			continue
+		}
+		w, err := zw.Create("attachments/" + key)
+		if err == nil {
+			_, err = io.Copy(w, body)
+		}
+		body.Close()
+		if err != nil {
+			missing = append(missing, url)
+		}
+	}
+	return missing
+}
+
+// RequestErasure queues an erasure for the privacy job.
+func (s *Service) RequestErasure(userID, requestedBy primitive.ObjectID) (*domain.PrivacyRequest, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var user struct {
+		ErasedAt *time.Time `bson:"erased_at"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"erased_at": 1})
+	if err := s.db.Collection("users").FindOne(ctx, bson.M{"_id": userID}, opts).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, err
+	}
+	if user.ErasedAt != nil {
+		return nil, domain.ErrUserErased
+	}
+	open, err := s.repo.HasOpen(ctx, userID, domain.PrivacyErasure)
+	if err != nil {
+		return nil, err
+	}
+	if open {
+		return nil, domain.ErrPrivacyRequestOpen
+	}
+
+	req := &domain.PrivacyRequest{
+		UserID:      userID,
+		Type:        domain.PrivacyErasure,
+		Status:      domain.PrivacyPending,
+		RequestedBy: requestedBy,
+		RequestedAt: time.Now(),
+	}
+	if err := s.repo.Insert(ctx, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// List returns privacy requests newest first, optionally for one user.
+func (s *Service) List(userID primitive.ObjectID, page, limit int) ([]domain.PrivacyRequest, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return s.repo.FindAll(ctx, userID, int64((page-1)*limit), int64(limit))
+}
+
+// ProcessErasures runs every queued erasure. Each one is recorded as
+// completed with its per-collection counts, or failed with the error; a
+// failed erasure can be requested again because every step is idempotent.
+func (s *Service) ProcessErasures(ctx context.Context) {
+	for {
+		req, err := s.repo.ClaimNext(ctx, domain.PrivacyErasure)
+		if err != nil {
+			log.Printf("[ERROR] privacy: claim erasure: %v", err)
+			return
+		}
+		if req == nil {
+			return
+		}
+
+		summary, err := s.erase(ctx, req.UserID)
+		if err != nil {
+			log.Printf("[ERROR] privacy: erase %s: %v", req.UserID.Hex(), err)
+			if err := s.repo.Fail(ctx, req.ID, err.Error(), time.Now()); err != nil {
+				log.Printf("[ERROR] privacy: mark request %s failed: %v", req.ID.Hex(), err)
+			}
+			continue
+		}
+		if err := s.repo.Complete(ctx, req.ID, summary, time.Now()); err != nil {
+			log.Printf("[ERROR] privacy: mark request %s completed: %v", req.ID.Hex(), err)
+		}
+		log.Printf("Privacy erasure completed for user %s", req.UserID.Hex())
+	}
+}
+
+// erase anonymises userID across every collection and deletes their stamp
+// images. Dates, statuses, barometer zones, badges and fertilizer stay so
+// cohort statistics don't change.
+func (s *Service) erase(ctx context.Context, userID primitive.ObjectID) (map[string]int64, error) {
+	users := s.db.Collection("users")
+	var user struct {
+		Email string `bson:"email"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"email": 1})
+	if err := users.FindOne(ctx, bson.M{"_id": userID}, opts).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	summary := map[string]int64{}
+	add := func(collection string, n int64) { summary[collection] += n }
+	now := time.Now()
+
+	// The account itself. The email stays unique but points nowhere, and the
+	// token version bump signs out every device.
+	accountUpdate := bson.M{
+		"$set": bson.M{
+			"first_name":       erasedFirstName,
+			"last_name":        erasedLastName,
+			"email":            fmt.Sprintf("erased-%s@deleted.invalid", userID.Hex()),
+			"jsd_number":       "",
+			"zoom_name":        erasedName,
+			"salesforce_id":    "",
+			"bio":              "",
+			"social_links":     bson.M{},
+			"profile_comments": bson.A{},
+			"deleted":          true,
+			"disabled":         true,
+			"erased_at":        now,
+		},
+		"$unset": bson.M{
+			"password": "", "totp_enabled": "", "totp_required": "", "totp_secret": "",
+			"totp_pending_secret": "", "totp_last_step": "", "recovery_codes": "", "must_change_password": "",
+		},
+		"$inc": bson.M{"token_version": 1},
+	}
+	res, err := users.UpdateOne(ctx, bson.M{"_id": userID}, accountUpdate)
+	if err != nil {
+		return nil, fmt.Errorf("users: %w", err)
+	}
+	add("users", res.ModifiedCount)
+	if _, err := users.UpdateOne(ctx, bson.M{"_id": userID, "deleted_at": nil}, bson.M{"$set": bson.M{"deleted_at": now}}); err != nil {
+		return nil, fmt.Errorf("users: %w", err)
+	}
+
+	// Reflection text, template answers and revisions go; the day and zone
+	// stay for the charts.
+	reflectionText := bson.M{"$set": bson.M{
+		"reflections.$[].reflection.tech_sessions.happy":       "",
+		"reflections.$[].reflection.tech_sessions.improve":     "",
+		"reflections.$[].reflection.non_tech_sessions.happy":   "",
+		"reflections.$[].reflection.non_tech_sessions.improve": "",
+		"reflections.$[].admin_feedback":                       "",
+	}}
+	if _, err := users.UpdateOne(ctx, bson.M{"_id": userID, "reflections.0": bson.M{"$exists": true}}, reflectionText); err != nil {
+		return nil, fmt.Errorf("reflections: %w", err)
+	}
+	res, err = s.db.Collection("reflections").UpdateMany(ctx, bson.M{"user_id": userID}, bson.M{"$set": bson.M{
+		"reflection.tech_sessions.happy":       "",
+		"reflection.tech_sessions.improve":     "",
+		"reflection.non_tech_sessions.happy":   "",
+		"reflection.non_tech_sessions.improve": "",
+		"admin_feedback":                       "",
+		"search_text":                          "",
+	}, "$unset": bson.M{"answers": "", "revisions": ""}})
+	if err != nil {
+		return nil, fmt.Errorf("reflections: %w", err)
+	}
+	add("reflections", res.ModifiedCount)
+
+	// Feedback threads on their reflections, and messages they wrote on
+	// other people's.
+	res, err = s.db.Collection("reflection_feedback").UpdateMany(ctx, bson.M{"learner_id": userID}, bson.M{"$set": bson.M{"body": erasedContent}})
+	if err != nil {
+		return nil, fmt.Errorf("reflection_feedback: %w", err)
+	}
+	add("reflection_feedback", res.ModifiedCount)
+	res, err = s.db.Collection("reflection_feedback").UpdateMany(ctx, bson.M{"author_id": userID}, bson.M{"$set": bson.M{"body": erasedContent, "author_name": erasedName}})
+	if err != nil {
+		return nil, fmt.Errorf("reflection_feedback: %w", err)
+	}
+	add("reflection_feedback", res.ModifiedCount)
+
+	// Wellbeing cases about them, with the coaches' notes, and cases
+	// assigned to them as staff.
+	cases := s.db.Collection("wellbeing_cases")
+	res, err = cases.UpdateMany(ctx, bson.M{"user_id": userID}, bson.M{"$set": bson.M{"user_name": erasedName, "history.$[].note": erasedContent}})
+	if err != nil {
+		return nil, fmt.Errorf("wellbeing_cases: %w", err)
+	}
+	add("wellbeing_cases", res.ModifiedCount)
+	res, err = cases.UpdateMany(ctx, bson.M{"assigned_to": userID}, bson.M{"$set": bson.M{"assigned_name": erasedName}})
+	if err != nil {
+		return nil, fmt.Errorf("wellbeing_cases: %w", err)
+	}
+	add("wellbeing_cases", res.ModifiedCount)
+
+	// Comments they left on other learners' profiles.
+	authored := options.Update().SetArrayFilters(options.ArrayFilters{Filters: bson.A{bson.M{"c.userId": userID}}})
+	res, err = users.UpdateMany(ctx, bson.M{"profile_comments.userId": userID}, bson.M{"$set": bson.M{
+		"profile_comments.$[c].content":  erasedContent,
+		"profile_comments.$[c].zoomName": erasedName,
+	}}, authored)
+	if err != nil {
+		return nil, fmt.Errorf("profile comments: %w", err)
+	}
+	add("users", res.ModifiedCount)
+
+	// Copies of their name on attendance and leave records.
+	res, err = s.db.Collection("attendance_records").UpdateMany(ctx, bson.M{"user_id": userID}, bson.M{"$set": bson.M{
+		"first_name": erasedFirstName, "last_name": erasedLastName, "jsd_number": "", "ip_address": "",
+	}})
+	if err != nil {
+		return nil, fmt.Errorf("attendance_records: %w", err)
+	}
+	add("attendance_records", res.ModifiedCount)
+
+	res, err = s.db.Collection("leave_requests").UpdateMany(ctx, bson.M{"user_id": userID}, bson.M{"$set": bson.M{
+		"first_name": erasedFirstName, "last_name": erasedLastName, "jsd_number": "", "reason": "", "review_notes": "",
+	}})
+	if err != nil {
+		return nil, fmt.Errorf("leave_requests: %w", err)
+	}
+	add("leave_requests", res.ModifiedCount)
+
+	// Board posts and comments keep their reactions but lose their text.
+	board := s.db.Collection("talk_board")
+	res, err = board.UpdateMany(ctx, bson.M{"userId": userID}, bson.M{"$set": bson.M{"content": erasedContent, "zoomName": erasedName}})
+	if err != nil {
+		return nil, fmt.Errorf("talk_board: %w", err)
+	}
+	add("talk_board", res.ModifiedCount)
+	res, err = board.UpdateMany(ctx, bson.M{"comments.userId": userID}, bson.M{"$set": bson.M{
+		"comments.$[c].content":  erasedContent,
+		"comments.$[c].zoomName": erasedName,
+	}}, authored)
+	if err != nil {
+		return nil, fmt.Errorf("talk_board comments: %w", err)
+	}
+	add("talk_board", res.ModifiedCount)
+
+	// Stamps are photos of the learner's work; both image and record go.
+	stamps, err := s.find(ctx, "stamps", bson.M{"ownerId": userID}, bson.M{"imageUrl": 1})
+	if err != nil {
+		return nil, fmt.Errorf("stamps: %w", err)
+	}
+	for _, stamp := range stamps {
+		url, _ := stamp["imageUrl"].(string)
+		if s.storage == nil || url == "" {
+			continue
+		}
+		if key, ok := s.storage.KeyFromURL(url); ok {
+			if err := s.storage.DeleteObjectsByPrefix(ctx, key); err != nil {
+				return nil, fmt.Errorf("stamp image %s: %w", key, err)
+			}
+			add("storage", 1)
+		}
+	}
+	del, err := s.db.Collection("stamps").DeleteMany(ctx, bson.M{"ownerId": userID})
+	if err != nil {
+		return nil, fmt.Errorf("stamps: %w", err)
+	}
+	add("stamps", del.DeletedCount)
+
+	// Reminders and other notifications sent to them alone.
+	del, err = s.db.Collection("notifications").DeleteMany(ctx, bson.M{"recipient_id": userID})
+	if err != nil {
+		return nil, fmt.Errorf("notifications: %w", err)
+	}
+	add("notifications", del.DeletedCount)
+
+	// Credentials and login traces.
+	for _, collection := range []string{"refresh_tokens", "password_resets"} {
+		del, err := s.db.Collection(collection).DeleteMany(ctx, bson.M{"user_id": userID})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", collection, err)
+		}
+		add(collection, del.DeletedCount)
+	}
+	if user.Email != "" {
+		del, err := s.db.Collection("login_throttles").DeleteMany(ctx, bson.M{"email": strings.ToLower(strings.TrimSpace(user.Email))})
+		if err != nil {
+			return nil, fmt.Errorf("login_throttles: %w", err)
+		}
+		add("login_throttles", del.DeletedCount)
+	}
+	res, err = s.db.Collection("lockout_events").UpdateMany(ctx, bson.M{"user_id": userID}, bson.M{"$set": bson.M{"email": "", "ip_address": ""}})
+	if err != nil {
+		return nil, fmt.Errorf("lockout_events: %w", err)
+	}
+	add("lockout_events", res.ModifiedCount)
+
+	// The audit trail keeps what happened but not the cached names or the
+	// old and new values, which can hold personal details.
+	audit := s.db.Collection("audit_logs")
+	res, err = audit.UpdateMany(ctx, bson.M{"target_id": userID}, bson.M{
+		"$set":   bson.M{"target_name": erasedName},
+		"$unset": bson.M{"before": "", "after": ""},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("audit_logs: %w", err)
+	}
+	add("audit_logs", res.ModifiedCount)
+	res, err = audit.UpdateMany(ctx, bson.M{"actor_id": userID}, bson.M{"$set": bson.M{"actor_name": erasedName}})
+	if err != nil {
+		return nil, fmt.Errorf("audit_logs: %w", err)
+	}
+	add("audit_logs", res.ModifiedCount)
+
+	return summary, nil
+}
+
+func (s *Service) find(ctx context.Context, collection string, filter, projection bson.M) ([]bson.M, error) {
+	opts := options.Find()
+	if projection != nil {
+		opts.SetProjection(projection)
+	}
+	cursor, err := s.db.Collection(collection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	docs := []bson.M{}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// aggregate pulls the elements of an embedded array written by userID out
+// of every document in collection, tagged with the parent's ID as parentKey.
+func (s *Service) aggregate(ctx context.Context, collection, array, authorField string, userID primitive.ObjectID, parentKey string) ([]bson.M, error) {
+	match := bson.M{array + "." + authorField: userID}
+	pipeline := []bson.M{
+		{"$match": match},
+		{"$unwind": "$" + array},
+		{"$match": match},
+		{"$project": bson.M{"_id": 0, parentKey: "$_id", "comment": "$" + array}},
+	}
+	cursor, err := s.db.Collection(collection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	docs := []bson.M{}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+func writeJSON(zw *zip.Writer, name string, v interface{}) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -2,7 +2,6 @@ package reflection
 
 import (
 	"context"
-	"strings"
 	"time"
 
 	"gofiber-baro/internal/domain"
@@ -11,6 +10,8 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// BarometerData is one day's count of reflections per zone, keyed in JSON by
+// the domain.BarometerZone values.
 type BarometerData struct {
 	Date                             string `json:"date"`
 	ComfortZone                      int    `json:"Comfort Zone"`
@@ -27,28 +28,42 @@ func NewBarometerService(db *mongo.Database) *BarometerService {
 	return &BarometerService{db: db}
 }
 
-func (s *BarometerService) GetUserBarometerData(users []domain.User) (map[string]int, error) {
-	zoneCounts := map[string]int{
-		"Comfort Zone":                           0,
-		"Stretch Zone - Enjoying the Challenges": 0,
-		"Stretch Zone - Overwhelmed":             0,
-		"Panic Zone":                             0,
+// GetUserBarometerData counts reflections by zone, in cohort or, when it
+// is 0, in every cohort.
+func (s *BarometerService) GetUserBarometerData(cohort int) (map[string]int, error) {
+	ctx := context.Background()
+
+	zoneCounts := make(map[string]int, len(domain.BarometerZones))
+	for _, zone := range domain.BarometerZones {
+		zoneCounts[string(zone)] = 0
 	}
 
-	for _, user := range users {
-		for _, reflection := range user.Reflections {
-			barometer := reflection.ReflectionData.Barometer
-
-			switch strings.ToLower(barometer) {
-			case "comfort zone":
-				zoneCounts["Comfort Zone"]++
-			case "stretch zone - enjoying the challenges":
-				zoneCounts["Stretch Zone - Enjoying the Challenges"]++
-			case "stretch zone - overwhelmed":
-				zoneCounts["Stretch Zone - Overwhelmed"]++
-			case "panic zone":
-				zoneCounts["Panic Zone"]++
-			}
+	matchFilter := bson.M{}
+	if cohort > 0 {
+		matchFilter["cohort_number"] = cohort
+	}
+
+	pipeline := []bson.M{
+		{"$match": matchFilter},
+		{"$group": bson.M{"_id": "$reflection.barometer", "count": bson.M{"$sum": 1}}},
+	}
+	cursor, err := s.db.Collection("reflections").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Barometer string `bson:"_id"`
+		Count     int    `bson:"count"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	for _, result := range results {
+		if zone, ok := domain.ParseBarometerZone(result.Barometer); ok {
+			zoneCounts[string(zone)] += result.Count
 		}
 	}
 
@@ -76,7 +91,7 @@ func (s *BarometerService) GetAllUsersBarometerData(timeRange string, cohort int
 	endDateStr := endDate.Format("2006-01-02")
 
 	matchFilter := bson.M{
-		"reflections.day": bson.M{
+		"day": bson.M{
 			"$gte": startDateStr,
 			"$lte": endDateStr,
 		},
@@ -87,19 +102,18 @@ func (s *BarometerService) GetAllUsersBarometerData(timeRange string, cohort int
 	}
 
 	pipeline := []bson.M{
-		{"$unwind": "$reflections"},
 		{"$match": matchFilter},
 		{"$group": bson.M{
 			"_id": bson.M{
-				"date":      "$reflections.day",
-				"barometer": "$reflections.reflection.barometer",
+				"date":      "$day",
+				"barometer": "$reflection.barometer",
 			},
 			"count": bson.M{"$sum": 1},
 		}},
 		{"$sort": bson.M{"_id.date": 1}},
 	}
 
-	cursor, err := s.db.Collection("users").Aggregate(ctx, pipeline)
+	cursor, err := s.db.Collection("reflections").Aggregate(ctx, pipeline)
 	if err != nil {
 		return nil, err
 	}
@@ -137,15 +151,16 @@ func (s *BarometerService) GetAllUsersBarometerData(timeRange string, cohort int
 			continue
 		}
 
-		switch result.ID.Barometer {
-		case "Comfort Zone":
-			data.ComfortZone = result.Count
-		case "Panic Zone":
-			data.PanicZone = result.Count
-		case "Stretch zone - Enjoying the challenges":
-			data.StretchZoneEnjoyingTheChallenges = result.Count
-		case "Stretch zone - Overwhelmed":
-			data.StretchZoneOverwhelmed = result.Count
+		zone, _ := domain.ParseBarometerZone(result.ID.Barometer)
+		switch zone {
+		case domain.ZoneComfort:
+			data.ComfortZone += result.Count
+		case domain.ZonePanic:
+			data.PanicZone += result.Count
+		case domain.ZoneStretchEnjoying:
+			data.StretchZoneEnjoyingTheChallenges += result.Count
+		case domain.ZoneStretchOverwhelmed:
+			data.StretchZoneOverwhelmed += result.Count
 		}
 	}
 
@@ -160,28 +175,27 @@ func (s *BarometerService) GetAllUsersBarometerData(timeRange string, cohort int
 func (s *BarometerService) GetWeeklySummary(page, limit, cohort int) ([]domain.WeeklySummary, int, error) {
 	ctx := context.Background()
 
-	collection := s.db.Collection("users")
+	collection := s.db.Collection("reflections")
 
 	matchFilter := bson.M{
-		"reflections.reflection.barometer": bson.M{
-			"$in": bson.A{"Stretch zone - Overwhelmed", "Panic Zone"},
+		"reflection.barometer": bson.M{
+			"$in": bson.A{domain.ZoneStretchOverwhelmed, domain.ZonePanic},
 		},
 	}
 	if cohort > 0 {
 		matchFilter["cohort_number"] = cohort
 	}
 
-	unwindStage := bson.D{{Key: "$unwind", Value: "$reflections"}}
 	matchStage := bson.D{{Key: "$match", Value: matchFilter}}
 	groupStage := bson.D{{Key: "$group", Value: bson.D{
 		{Key: "_id", Value: bson.D{
-			{Key: "year", Value: bson.D{{Key: "$year", Value: "$reflections.date"}}},
-			{Key: "week", Value: bson.D{{Key: "$isoWeek", Value: "$reflections.date"}}},
+			{Key: "year", Value: bson.D{{Key: "$year", Value: "$date"}}},
+			{Key: "week", Value: bson.D{{Key: "$isoWeek", Value: "$date"}}},
 		}},
 	}}}
 	countStage := bson.D{{Key: "$count", Value: "total"}}
 
-	countPipeline := mongo.Pipeline{unwindStage, matchStage, groupStage, countStage}
+	countPipeline := mongo.Pipeline{matchStage, groupStage, countStage}
 	countCursor, err := collection.Aggregate(ctx, countPipeline)
 	if err != nil {
 		return nil, 0, err
@@ -197,21 +211,22 @@ func (s *BarometerService) GetWeeklySummary(page, limit, cohort int) ([]domain.W
 	countCursor.Close(ctx)
 
 	paginatedPipeline := mongo.Pipeline{
-		unwindStage,
 		matchStage,
+		bson.D{{Key: "$lookup", Value: userLookup["$lookup"]}},
+		bson.D{{Key: "$unwind", Value: bson.M{"path": "$user", "preserveNullAndEmptyArrays": true}}},
 		bson.D{{Key: "$group", Value: bson.D{
 			{Key: "_id", Value: bson.D{
-				{Key: "year", Value: bson.D{{Key: "$year", Value: "$reflections.date"}}},
-				{Key: "week", Value: bson.D{{Key: "$isoWeek", Value: "$reflections.date"}}},
+				{Key: "year", Value: bson.D{{Key: "$year", Value: "$date"}}},
+				{Key: "week", Value: bson.D{{Key: "$isoWeek", Value: "$date"}}},
 			}},
 			{Key: "students", Value: bson.D{{Key: "$push", Value: bson.D{
-				{Key: "user_id", Value: bson.D{{Key: "$toString", Value: "$_id"}}},
-				{Key: "first_name", Value: "$first_name"},
-				{Key: "last_name", Value: "$last_name"},
-				{Key: "zoom_name", Value: "$zoom_name"},
-				{Key: "jsd_number", Value: "$jsd_number"},
-				{Key: "barometer", Value: "$reflections.reflection.barometer"},
-				{Key: "date", Value: "$reflections.date"},
+				{Key: "user_id", Value: bson.D{{Key: "$toString", Value: "$user_id"}}},
+				{Key: "first_name", Value: "$user.first_name"},
+				{Key: "last_name", Value: "$user.last_name"},
+				{Key: "zoom_name", Value: "$user.zoom_name"},
+				{Key: "jsd_number", Value: "$user.jsd_number"},
+				{Key: "barometer", Value: "$reflection.barometer"},
+				{Key: "date", Value: "$date"},
 			}}}},
 		}}},
 		bson.D{{Key: "$sort", Value: bson.D{
@@ -249,9 +264,9 @@ func (s *BarometerService) GetWeeklySummary(page, limit, cohort int) ([]domain.W
 		var overwhelmedStudents []domain.StudentInfo
 
 		for _, student := range result.Students {
-			if student.Barometer == "Stretch zone - Overwhelmed" {
+			if student.Barometer == domain.ZoneStretchOverwhelmed {
 				stressedStudents = append(stressedStudents, student)
-			} else if student.Barometer == "Panic Zone" {
+			} else if student.Barometer == domain.ZonePanic {
 				overwhelmedStudents = append(overwhelmedStudents, student)
 			}
 		}
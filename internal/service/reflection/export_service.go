@@ -0,0 +1,196 @@
+package reflection
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gofiber-baro/internal/domain"
+	"gofiber-baro/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ExportRequest selects a cohort's reflections for a spreadsheet. From and
+// To are inclusive Bangkok days; zero values don't filter. Format is "csv"
+// or "xlsx". Anonymize replaces learners' names with "Learner 1", "Learner
+// 2"... and leaves out their Zoom names and JSD numbers.
+type ExportRequest struct {
+	Cohort    int
+	From      string
+	To        string
+	Zone      domain.BarometerZone
+	GroupID   primitive.ObjectID
+	Format    string
+	Anonymize bool
+}
+
+// Export writes one row per reflection, oldest first, and returns the file
+// and its extension. Free text is written as typed, except that CSV cells
+// that would start a formula are quoted (see utils.CSVCell).
+func (s *Service) Export(req ExportRequest) ([]byte, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	filter := bson.M{"cohort_number": req.Cohort}
+	if req.From != "" || req.To != "" {
+		day := bson.M{}
+		if req.From != "" {
+			day["$gte"] = req.From
+		}
+		if req.To != "" {
+			day["$lte"] = req.To
+		}
+		filter["day"] = day
+	}
+	if req.Zone != "" {
+		filter["reflection.barometer"] = req.Zone
+	}
+	if !req.GroupID.IsZero() {
+		members, err := s.db.Collection("users").Distinct(ctx, "_id", bson.M{"$or": bson.A{
+			bson.M{"project_group_id": req.GroupID},
+			bson.M{"genmate_group_id": req.GroupID},
+		}})
+		if err != nil {
+			return nil, "", fmt.Errorf("fetch group: %w", err)
+		}
+		filter["user_id"] = bson.M{"$in": members}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "day", Value: 1}, {Key: "date", Value: 1}}).
+		SetProjection(bson.M{"search_text": 0, "revisions": 0})
+	cursor, err := s.db.Collection("reflections").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, "", fmt.Errorf("fetch reflections: %w", err)
+	}
+	var reflections []domain.Reflection
+	if err := cursor.All(ctx, &reflections); err != nil {
+		return nil, "", fmt.Errorf("fetch reflections: %w", err)
+	}
+
+	users, err := s.exportUsers(ctx, reflections)
+	if err != nil {
+		return nil, "", fmt.Errorf("fetch users: %w", err)
+	}
+	pseudonyms := map[primitive.ObjectID]string{}
+	if req.Anonymize {
+		pseudonyms = pseudonymize(reflections)
+	}
+
+	headers := []string{"Date", "Cohort", "Learner"}
+	if !req.Anonymize {
+		headers = append(headers, "Zoom Name", "JSD Number")
+	}
+	headers = append(headers, "Barometer",
+		"Tech Sessions", "Tech Happy", "Tech Improve",
+		"Non-Tech Sessions", "Non-Tech Happy", "Non-Tech Improve",
+		"Template Answers")
+
+	rows := make([][]string, 0, len(reflections))
+	for i := range reflections {
+		r := &reflections[i]
+		row := []string{r.DayKey(), strconv.Itoa(r.CohortNumber)}
+		if req.Anonymize {
+			row = append(row, pseudonyms[r.UserID])
+		} else {
+			u := users[r.UserID]
+			row = append(row, strings.TrimSpace(u.FirstName+" "+u.LastName), u.ZoomName, u.JSDNumber)
+		}
+		tech, nonTech := r.ReflectionData.TechSessions, r.ReflectionData.NonTechSessions
+		row = append(row, string(r.ReflectionData.Barometer),
+			exportText(strings.Join(tech.SessionName, ", ")), exportText(tech.Happy), exportText(tech.Improve),
+			exportText(strings.Join(nonTech.SessionName, ", ")), exportText(nonTech.Happy), exportText(nonTech.Improve),
+			exportAnswers(r.Answers))
+		rows = append(rows, row)
+	}
+
+	if req.Format == "xlsx" {
+		data, err := utils.WriteXLSX(headers, rows)
+		return data, "xlsx", err
+	}
+	for _, row := range rows {
+		for i := range row {
+			row[i] = utils.CSVCell(row[i])
+		}
+	}
+	data, err := utils.WriteCSV(headers, rows)
+	return data, "csv", err
+}
+
+// exportUsers looks up the names of the reflections' authors.
+func (s *Service) exportUsers(ctx context.Context, reflections []domain.Reflection) (map[primitive.ObjectID]domain.User, error) {
+	users := map[primitive.ObjectID]domain.User{}
+	var ids []primitive.ObjectID
+	for _, r := range reflections {
+		if _, ok := users[r.UserID]; !ok {
+			users[r.UserID] = domain.User{}
+			ids = append(ids, r.UserID)
+		}
+	}
+	if len(ids) == 0 {
+		return users, nil
+	}
+	opts := options.Find().SetProjection(bson.M{"first_name": 1, "last_name": 1, "zoom_name": 1, "jsd_number": 1})
+	cursor, err := s.db.Collection("users").Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var found []domain.User
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+	for _, u := range found {
+		users[u.ID] = u
+	}
+	return users, nil
+}
+
+// pseudonymize numbers the authors in order of their user IDs, i.e. of
+// registration, so the numbers say nothing about names.
+func pseudonymize(reflections []domain.Reflection) map[primitive.ObjectID]string {
+	var ids []primitive.ObjectID
+	seen := map[primitive.ObjectID]bool{}
+	for _, r := range reflections {
+		if !seen[r.UserID] {
+			seen[r.UserID] = true
+			ids = append(ids, r.UserID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].Hex() < ids[j].Hex() })
+	names := make(map[primitive.ObjectID]string, len(ids))
+	for i, id := range ids {
+		names[id] = fmt.Sprintf("Learner %d", i+1)
+	}
+	return names
+}
+
+// exportText undoes the HTML escaping reflections are stored with.
+func exportText(s string) string {
+	return html.UnescapeString(s)
+}
+
+// exportAnswers puts a template reflection's answers in one cell, one
+// "question_id: answer" line each.
+func exportAnswers(answers []domain.ReflectionAnswer) string {
+	lines := make([]string, 0, len(answers))
+	for _, a := range answers {
+		var value string
+		switch {
+		case a.Value != nil:
+			value = strconv.Itoa(*a.Value)
+		case len(a.Choices) > 0:
+			value = strings.Join(a.Choices, ", ")
+		default:
+			value = a.Text
+		}
+		lines = append(lines, a.QuestionID+": "+exportText(value))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,222 @@
+package reflection
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"gofiber-baro/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FeedbackAuthor is who is reading or writing a feedback thread.
+type FeedbackAuthor struct {
+	ID   primitive.ObjectID
+	Role string
+	// Moderator may delete other people's entries.
+	Moderator bool
+	// Impersonator is set when an admin is viewing the app as the user;
+	// their reads leave the learner's unread state alone.
+	Impersonator string
+}
+
+// FeedbackService runs the feedback thread of each reflection between staff
+// and the learner who wrote it.
+type FeedbackService struct {
+	repo        domain.FeedbackRepository
+	reflections domain.ReflectionRepository
+	userRepo    domain.UserRepository
+}
+
+func NewFeedbackService(repo domain.FeedbackRepository, reflections domain.ReflectionRepository, userRepo domain.UserRepository) *FeedbackService {
+	return &FeedbackService{repo: repo, reflections: reflections, userRepo: userRepo}
+}
+
+// maxFeedbackLength caps an entry, in characters.
+const maxFeedbackLength = 5000
+
+func checkBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", domain.ErrFeedbackEmpty
+	}
+	if len([]rune(body)) > maxFeedbackLength {
+		return "", domain.ErrFeedbackTooLong
+	}
+	return body, nil
+}
+
+// Thread returns a reflection's feedback, oldest first. When the learner
+// reads it, staff replies stop counting as unread; an admin viewing as the
+// learner doesn't count.
+func (s *FeedbackService) Thread(learnerID, reflectionID primitive.ObjectID, reader FeedbackAuthor) ([]domain.FeedbackEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := s.reflections.FindByID(ctx, learnerID, reflectionID); err != nil {
+		return nil, err
+	}
+	entries, err := s.repo.FindByReflection(ctx, reflectionID)
+	if err != nil {
+		return nil, err
+	}
+	if reader.ID == learnerID && reader.Impersonator == "" {
+		if err := s.reflections.MarkFeedbackRead(ctx, learnerID, reflectionID, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// Post adds an entry to a reflection's thread. A reply from anyone but the
+// learner shows up as unread for them.
+func (s *FeedbackService) Post(learnerID, reflectionID primitive.ObjectID, author FeedbackAuthor, body string) (*domain.FeedbackEntry, error) {
+	body, err := checkBody(body)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := s.reflections.FindByID(ctx, learnerID, reflectionID); err != nil {
+		return nil, err
+	}
+
+	entry := &domain.FeedbackEntry{
+		ReflectionID: reflectionID,
+		LearnerID:    learnerID,
+		AuthorID:     author.ID,
+		AuthorName:   s.authorName(ctx, author.ID),
+		AuthorRole:   author.Role,
+		Body:         body,
+		CreatedAt:    time.Now(),
+	}
+	if err := s.repo.Insert(ctx, entry); err != nil {
+		return nil, err
+	}
+	if !entry.FromLearner() {
+		if err := s.reflections.MarkStaffReply(ctx, learnerID, reflectionID, entry.CreatedAt); err != nil {
+			return nil, err
+		}
+	}
+	return entry, nil
+}
+
+// authorName is the name shown on an entry, kept as written.
+func (s *FeedbackService) authorName(ctx context.Context, id primitive.ObjectID) string {
+	if id.IsZero() {
+		return "Staff"
+	}
+	users, _, err := s.userRepo.FindRoster(ctx, domain.UserFilter{IDs: []primitive.ObjectID{id}}, nil)
+	if err != nil || len(users) == 0 {
+		return "Staff"
+	}
+	u := users[0]
+	if name := strings.TrimSpace(u.FirstName + " " + u.LastName); name != "" {
+		return name
+	}
+	return u.ZoomName
+}
+
+// entry loads an entry of the given reflection thread.
+func (s *FeedbackService) entry(ctx context.Context, learnerID, reflectionID, entryID primitive.ObjectID) (*domain.FeedbackEntry, error) {
+	entry, err := s.repo.FindByID(ctx, entryID)
+	if err != nil {
+		return nil, err
+	}
+	if entry.LearnerID != learnerID || entry.ReflectionID != reflectionID {
+		return nil, domain.ErrFeedbackNotFound
+	}
+	return entry, nil
+}
+
+// Edit changes the body of the author's own entry.
+func (s *FeedbackService) Edit(learnerID, reflectionID, entryID primitive.ObjectID, author FeedbackAuthor, body string) (*domain.FeedbackEntry, error) {
+	body, err := checkBody(body)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	entry, err := s.entry(ctx, learnerID, reflectionID, entryID)
+	if err != nil {
+		return nil, err
+	}
+	if entry.AuthorID.IsZero() || entry.AuthorID != author.ID {
+		return nil, domain.ErrFeedbackNotAuthor
+	}
+	now := time.Now()
+	if err := s.repo.UpdateBody(ctx, entryID, body, now); err != nil {
+		return nil, err
+	}
+	entry.Body = body
+	entry.EditedAt = &now
+	return entry, nil
+}
+
+// Delete removes an entry. Authors can delete their own; moderators any.
+func (s *FeedbackService) Delete(learnerID, reflectionID, entryID primitive.ObjectID, author FeedbackAuthor) (*domain.FeedbackEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	entry, err := s.entry(ctx, learnerID, reflectionID, entryID)
+	if err != nil {
+		return nil, err
+	}
+	if entry.AuthorID != author.ID && !author.Moderator {
+		return nil, domain.ErrFeedbackNotAuthor
+	}
+	if err := s.repo.Delete(ctx, entryID); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Unread lists the learner's reflections with staff replies they have not
+// read yet, newest first.
+func (s *FeedbackService) Unread(learnerID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.reflections.FindUnreadFeedback(ctx, learnerID)
+}
+
+// MigrateFeedback turns each reflection's admin_feedback string into the
+// first entry of its thread, dated to the reflection and marked as read. It
+// runs at startup and after migrate-reflections, and is a no-op once done.
+func (s *FeedbackService) MigrateFeedback() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	var migrated int64
+	err := s.reflections.EachLegacyFeedback(ctx, func(r *domain.Reflection) error {
+		at := r.CreatedAt
+		if at.IsZero() {
+			at = r.Date
+		}
+		// The entry reuses the reflection's ID, so a rerun after a crash
+		// between the two writes below does not duplicate it.
+		inserted, err := s.repo.InsertLegacy(ctx, &domain.FeedbackEntry{
+			ID:           r.ID,
+			ReflectionID: r.ID,
+			LearnerID:    r.UserID,
+			AuthorName:   "Staff",
+			AuthorRole:   "staff",
+			Body:         r.AdminFeedback,
+			CreatedAt:    at,
+		})
+		if err != nil {
+			return err
+		}
+		if inserted {
+			migrated++
+		}
+		return s.reflections.ClearLegacyFeedback(ctx, r.ID, at)
+	})
+	if migrated > 0 {
+		log.Printf("[INFO] reflections: moved %d feedback strings into threads", migrated)
+	}
+	return migrated, err
+}
@@ -0,0 +1,140 @@
+package reflection
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"gofiber-baro/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MigrationResult reports what Migrate did.
+type MigrationResult struct {
+	Users  int   `json:"users"`
+	Copied int64 `json:"copied"`
+	// Conflicts are legacy reflections on a day the user already has one
+	// for in the collection. They stay in the user document.
+	Conflicts int64 `json:"conflicts"`
+	Pruned    int64 `json:"pruned"`
+}
+
+// Migrate copies the reflections embedded in user documents into the
+// reflections collection, keeping their IDs and tagging them with the
+// user's current cohort. It is safe to run repeatedly: copies that already
+// exist are left alone. With prune, the copied reflections are then removed
+// from the user documents; run that only once no server reads them anymore.
+func (s *Service) Migrate(prune bool) (MigrationResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	return s.migrate(ctx, false, prune)
+}
+
+// CopyPending copies the legacy reflections added since the last copy, so
+// the collection-only views (charts, search, session analytics, export) see
+// them. Once every array has been copied it is a single cheap query; it runs
+// at startup.
+func (s *Service) CopyPending() (MigrationResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	pending, err := s.repo.HasPendingEmbedded(ctx)
+	if err != nil || !pending {
+		return MigrationResult{}, err
+	}
+	return s.migrate(ctx, true, false)
+}
+
+func (s *Service) migrate(ctx context.Context, pendingOnly, prune bool) (MigrationResult, error) {
+	var result MigrationResult
+	err := s.repo.EachEmbedded(ctx, pendingOnly, func(userID primitive.ObjectID, cohort int, reflections []domain.Reflection) error {
+		result.Users++
+		for i := range reflections {
+			r := &reflections[i]
+			if r.ID.IsZero() {
+				r.ID = primitive.NewObjectID()
+			}
+			r.UserID = userID
+			r.Day = r.DayKey()
+			if r.CohortNumber == 0 {
+				r.CohortNumber = cohort
+			}
+		}
+
+		copied, conflicts, err := s.repo.Copy(ctx, reflections)
+		if err != nil {
+			return err
+		}
+		result.Copied += copied
+		result.Conflicts += conflicts
+		if conflicts > 0 {
+			log.Printf("[WARN] reflections: user %s has %d legacy reflections on days already taken", userID.Hex(), conflicts)
+		}
+
+		remaining := int64(len(reflections))
+		if prune {
+			pruned, err := s.repo.PruneEmbedded(ctx, userID)
+			if err != nil {
+				return err
+			}
+			result.Pruned += pruned
+			remaining -= pruned
+		}
+		return s.repo.MarkCopied(ctx, userID, int(remaining))
+	})
+	if err != nil {
+		return result, err
+	}
+
+	log.Printf("[INFO] reflections: migrated %d users, copied %d, %d conflicts, pruned %d", result.Users, result.Copied, result.Conflicts, result.Pruned)
+	return result, nil
+}
+
+// NormalizeZones rewrites stored barometer values to their canonical
+// spelling, e.g. "Stretch zone - Overwhelmed" to "Stretch Zone -
+// Overwhelmed". Values that match no zone are logged and left alone. It runs
+// at startup and is a no-op once the data is clean.
+func (s *Service) NormalizeZones() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	values, err := s.repo.DistinctZones(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var renamed int64
+	for _, v := range values {
+		zone, ok := domain.ParseBarometerZone(v)
+		if !ok {
+			log.Printf("[WARN] reflections: unknown barometer zone %q left as is", v)
+			continue
+		}
+		if string(zone) == v {
+			continue
+		}
+		n, err := s.repo.RenameZone(ctx, v, zone)
+		if err != nil {
+			return renamed, err
+		}
+		renamed += n
+	}
+
+	if renamed > 0 {
+		log.Printf("[INFO] reflections: normalised the barometer zone of %d documents", renamed)
+	}
+	return renamed, nil
+}
+
+// IndexSearchText fills in the search text of reflections written before
+// search existed. It runs at startup and is a no-op once all have it.
+func (s *Service) IndexSearchText() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	filled, err := s.repo.BackfillSearchText(ctx)
+	if filled > 0 {
+		log.Printf("[INFO] reflections: indexed %d reflections for search", filled)
+	}
+	return filled, err
+}
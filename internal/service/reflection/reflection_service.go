@@ -3,40 +3,28 @@ package reflection
 import (
 	"context"
 	"sort"
-	"strings"
 
 	"gofiber-baro/internal/domain"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type Service struct {
-	db *mongo.Database
+	db   *mongo.Database
+	repo domain.ReflectionRepository
 }
 
-func NewService(db *mongo.Database) *Service {
-	return &Service{db: db}
+func NewService(db *mongo.Database, repo domain.ReflectionRepository) *Service {
+	return &Service{db: db, repo: repo}
 }
 
 func (s *Service) GetAllReflections() ([]domain.Reflection, error) {
 	ctx := context.Background()
 
-	pipeline := []bson.M{
-		{"$unwind": "$reflections"},
-		{"$project": bson.M{
-			"_id":            "$reflections._id",
-			"day":            "$reflections.day",
-			"user_id":        "$reflections.user_id",
-			"date":           "$reflections.date",
-			"created_at":     "$reflections.createdAt",
-			"reflection":     "$reflections.reflection",
-			"admin_feedback": "$reflections.admin_feedback",
-		}},
-	}
-
-	cursor, err := s.db.Collection("users").Aggregate(ctx, pipeline)
+	cursor, err := s.db.Collection("reflections").Find(ctx, bson.M{})
 	if err != nil {
 		return nil, err
 	}
@@ -50,39 +38,63 @@ func (s *Service) GetAllReflections() ([]domain.Reflection, error) {
 	return reflections, nil
 }
 
-func (s *Service) GetAllReflectionsWithUserInfo(page int, limit int) ([]map[string]interface{}, int, error) {
+// userLookup joins the author's name fields onto each reflection as "user".
+var userLookup = bson.M{"$lookup": bson.M{
+	"from": "users",
+	"let":  bson.M{"uid": "$user_id"},
+	"pipeline": bson.A{
+		bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$_id", "$$uid"}}}},
+		bson.M{"$project": bson.M{"first_name": 1, "last_name": 1, "zoom_name": 1, "jsd_number": 1}},
+	},
+	"as": "user",
+}}
+
+// GetAllReflectionsWithUserInfo lists reflections newest first with their
+// authors' names; cohort 0 means every cohort.
+func (s *Service) GetAllReflectionsWithUserInfo(page int, limit int, cohort int) ([]map[string]interface{}, int, error) {
 	ctx := context.Background()
 	offset := (page - 1) * limit
 
+	filter := bson.M{}
+	if cohort > 0 {
+		filter["cohort_number"] = cohort
+	}
+
 	pipeline := []bson.M{
-		{"$unwind": "$reflections"},
+		{"$match": filter},
+		{"$sort": bson.M{"date": -1}},
+		{"$skip": offset},
+		{"$limit": limit},
+		userLookup,
+		{"$unwind": bson.M{"path": "$user", "preserveNullAndEmptyArrays": true}},
 		{"$project": bson.M{
-			"_id":       "$reflections._id",
-			"user_id":   bson.M{"$toString": "$_id"},
-			"FirstName": "$first_name",
-			"LastName":  "$last_name",
-			"JsdNumber": "$jsd_number",
-			"Date":      "$reflections.date",
+			"_id":       1,
+			"user_id":   bson.M{"$toString": "$user_id"},
+			"FirstName": "$user.first_name",
+			"LastName":  "$user.last_name",
+			"JsdNumber": "$user.jsd_number",
+			"Date":      "$date",
+			// Edited reflections show when, and whether it was after feedback.
+			"UpdatedAt":           "$updated_at",
+			"EditedAfterFeedback": "$edited_after_feedback",
+			"Answers":             "$answers",
 			"Reflection": bson.M{
-				"Barometer": "$reflections.reflection.barometer",
+				"Barometer": "$reflection.barometer",
 				"TechSessions": bson.M{
-					"SessionName": "$reflections.reflection.tech_sessions.session_name",
-					"Happy":       "$reflections.reflection.tech_sessions.happy",
-					"Improve":     "$reflections.reflection.tech_sessions.improve",
+					"SessionName": "$reflection.tech_sessions.session_name",
+					"Happy":       "$reflection.tech_sessions.happy",
+					"Improve":     "$reflection.tech_sessions.improve",
 				},
 				"NonTechSessions": bson.M{
-					"SessionName": "$reflections.reflection.non_tech_sessions.session_name",
-					"Happy":       "$reflections.reflection.non_tech_sessions.happy",
-					"Improve":     "$reflections.reflection.non_tech_sessions.improve",
+					"SessionName": "$reflection.non_tech_sessions.session_name",
+					"Happy":       "$reflection.non_tech_sessions.happy",
+					"Improve":     "$reflection.non_tech_sessions.improve",
 				},
 			},
 		}},
-		{"$sort": bson.M{"Date": -1}},
-		{"$skip": offset},
-		{"$limit": limit},
 	}
 
-	cursor, err := s.db.Collection("users").Aggregate(ctx, pipeline, options.Aggregate())
+	cursor, err := s.db.Collection("reflections").Aggregate(ctx, pipeline, options.Aggregate())
 	if err != nil {
 		return nil, 0, err
 	}
@@ -93,31 +105,41 @@ func (s *Service) GetAllReflectionsWithUserInfo(page int, limit int) ([]map[stri
 		return nil, 0, err
 	}
 
-	countPipeline := []bson.M{
-		{"$unwind": "$reflections"},
-		{"$count": "total"},
-	}
-
-	countCursor, err := s.db.Collection("users").Aggregate(ctx, countPipeline, options.Aggregate())
+	total, err := s.db.Collection("reflections").CountDocuments(ctx, filter)
 	if err != nil {
 		return nil, 0, err
 	}
-	defer countCursor.Close(ctx)
 
-	var countResult []bson.M
-	if err := countCursor.All(ctx, &countResult); err != nil {
-		return nil, 0, err
-	}
+	return reflections, int(total), nil
+}
+
+// GetEmojiZoneTableData returns each user's zone per day, from their first
+// reflection of the day.
+func (s *Service) GetEmojiZoneTableData(users []domain.User) ([]domain.EmojiZoneTableData, error) {
+	ctx := context.Background()
 
-	total := 0
-	if len(countResult) > 0 {
-		total = int(countResult[0]["total"].(int32))
+	userIDs := make([]primitive.ObjectID, len(users))
+	for i := range users {
+		userIDs[i] = users[i].ID
 	}
+	opts := options.Find().
+		SetProjection(bson.M{"user_id": 1, "day": 1, "reflection.barometer": 1}).
+		SetSort(bson.D{{Key: "createdAt", Value: 1}})
+	cursor, err := s.db.Collection("reflections").Find(ctx, bson.M{"user_id": bson.M{"$in": userIDs}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
 
-	return reflections, total, nil
-}
+	var reflections []domain.Reflection
+	if err := cursor.All(ctx, &reflections); err != nil {
+		return nil, err
+	}
+	byUser := make(map[primitive.ObjectID][]domain.Reflection)
+	for _, r := range reflections {
+		byUser[r.UserID] = append(byUser[r.UserID], r)
+	}
 
-func (s *Service) GetEmojiZoneTableData(users []domain.User) ([]domain.EmojiZoneTableData, error) {
 	var tableData []domain.EmojiZoneTableData
 
 	for _, user := range users {
@@ -126,8 +148,8 @@ func (s *Service) GetEmojiZoneTableData(users []domain.User) ([]domain.EmojiZone
 		}
 
 		entriesMap := make(map[string]string)
-		for _, reflection := range user.Reflections {
-			dateStr := reflection.Date.Format("2006-01-02")
+		for _, reflection := range byUser[user.ID] {
+			dateStr := reflection.Day
 			if _, exists := entriesMap[dateStr]; !exists {
 				entriesMap[dateStr] = mapBarometerToZone(reflection.ReflectionData.Barometer)
 			}
@@ -152,15 +174,16 @@ func (s *Service) GetEmojiZoneTableData(users []domain.User) ([]domain.EmojiZone
 	return tableData, nil
 }
 
-func mapBarometerToZone(barometer string) string {
-	switch strings.ToLower(barometer) {
-	case "comfort zone":
+func mapBarometerToZone(barometer domain.BarometerZone) string {
+	zone, _ := domain.ParseBarometerZone(string(barometer))
+	switch zone {
+	case domain.ZoneComfort:
 		return "comfort"
-	case "stretch zone - enjoying the challenges":
+	case domain.ZoneStretchEnjoying:
 		return "stretch-enjoying"
-	case "stretch zone - overwhelmed":
+	case domain.ZoneStretchOverwhelmed:
 		return "stretch-overwhelmed"
-	case "panic zone":
+	case domain.ZonePanic:
 		return "panic"
 	default:
 		return "no-data"
@@ -0,0 +1,226 @@
+package reflection
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"gofiber-baro/internal/domain"
+	"gofiber-baro/internal/service/holiday"
+	"gofiber-baro/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ReminderService finds learners who haven't reflected on a school day and
+// reminds them before the day is over.
+type ReminderService struct {
+	userRepo      domain.UserRepository
+	reflections   domain.ReflectionRepository
+	cohorts       domain.CohortRepository
+	holidays      *holiday.Service
+	notifications domain.NotificationRepository
+	// remindAt is the Bangkok time of day reminders go out, in minutes
+	// after midnight; negative turns them off.
+	remindAt int
+}
+
+func NewReminderService(userRepo domain.UserRepository, reflections domain.ReflectionRepository, cohorts domain.CohortRepository, holidays *holiday.Service, notifications domain.NotificationRepository) *ReminderService {
+	return &ReminderService{
+		userRepo:      userRepo,
+		reflections:   reflections,
+		cohorts:       cohorts,
+		holidays:      holidays,
+		notifications: notifications,
+		remindAt:      remindAtFromEnv(),
+	}
+}
+
+// defaultRemindAt is 20:00.
+const defaultRemindAt = 20 * 60
+
+// remindAtFromEnv reads REFLECTION_REMINDER_TIME: "HH:MM" in Bangkok time,
+// or "off".
+func remindAtFromEnv() int {
+	v := strings.TrimSpace(os.Getenv("REFLECTION_REMINDER_TIME"))
+	switch v {
+	case "":
+		return defaultRemindAt
+	case "off":
+		return -1
+	}
+	t, err := time.Parse("15:04", v)
+	if err != nil {
+		log.Printf("WARNING: invalid REFLECTION_REMINDER_TIME %q, using 20:00", v)
+		return defaultRemindAt
+	}
+	return t.Hour()*60 + t.Minute()
+}
+
+// SchoolDay reports whether learners are expected to reflect on day: a
+// weekday that isn't a holiday.
+func (s *ReminderService) SchoolDay(day string) (bool, error) {
+	date, err := time.Parse("2006-01-02", day)
+	if err != nil {
+		return false, err
+	}
+	if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+		return false, nil
+	}
+	isHoliday, _, err := s.holidays.IsHoliday(day)
+	if err != nil {
+		return false, err
+	}
+	return !isHoliday, nil
+}
+
+// Missing returns the cohort's active learners with no reflection on day,
+// by first name.
+func (s *ReminderService) Missing(cohort int, day string) ([]domain.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	missing, _, err := s.split(ctx, cohort, day)
+	return missing, err
+}
+
+// split divides the cohort's active learners into those with and without a
+// reflection on day.
+func (s *ReminderService) split(ctx context.Context, cohort int, day string) (missing, reflected []domain.User, err error) {
+	filter := domain.UserFilter{Cohort: cohort, Role: "learner"}
+	users, _, err := s.userRepo.FindRoster(ctx, filter, options.Find().SetSort(bson.D{{Key: "first_name", Value: 1}}))
+	if err != nil {
+		return nil, nil, err
+	}
+	var learners []domain.User
+	var ids []primitive.ObjectID
+	for _, u := range users {
+		// Only active learners are expected to reflect.
+		status := domain.LearnerStatus(u.AttendanceStatus)
+		if u.Deleted || u.Disabled || (status != "" && status != domain.LearnerActive) {
+			continue
+		}
+		learners = append(learners, u)
+		ids = append(ids, u.ID)
+	}
+	if len(learners) == 0 {
+		return []domain.User{}, nil, nil
+	}
+
+	days, err := s.reflections.FindDays(ctx, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	missing = []domain.User{}
+	for _, u := range learners {
+		if containsString(days[u.ID], day) {
+			reflected = append(reflected, u)
+		} else {
+			missing = append(missing, u)
+		}
+	}
+	return missing, reflected, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// SendReminders reminds the learners of every running cohort who haven't
+// reflected today, once the reminder time has passed. It is safe to run
+// repeatedly: each learner gets one reminder a day, and it is withdrawn
+// once they reflect.
+func (s *ReminderService) SendReminders(ctx context.Context) {
+	if s.remindAt < 0 {
+		return
+	}
+	now := utils.GetThailandTime()
+	if now.Hour()*60+now.Minute() < s.remindAt {
+		return
+	}
+	day := now.Format("2006-01-02")
+	schoolDay, err := s.SchoolDay(day)
+	if err != nil {
+		log.Printf("[ERROR] reminders: check %s: %v", day, err)
+		return
+	}
+	if !schoolDay {
+		return
+	}
+
+	cohorts, err := s.cohorts.List(ctx)
+	if err != nil {
+		log.Printf("[ERROR] reminders: list cohorts: %v", err)
+		return
+	}
+	var sent int
+	for _, cohort := range cohorts {
+		if !running(cohort, now) {
+			continue
+		}
+		n, err := s.remindCohort(ctx, cohort.CohortNumber, day, now)
+		if err != nil {
+			log.Printf("[ERROR] reminders: cohort %d: %v", cohort.CohortNumber, err)
+		}
+		sent += n
+	}
+	if sent > 0 {
+		log.Printf("[INFO] reminders: reminded %d learners to reflect on %s", sent, day)
+	}
+}
+
+// running reports whether the cohort has started and isn't locked yet.
+func running(c domain.Cohort, now time.Time) bool {
+	if c.IsLocked || c.StartDate.After(now) {
+		return false
+	}
+	return c.LockAt.IsZero() || c.LockAt.After(now)
+}
+
+func (s *ReminderService) remindCohort(ctx context.Context, cohort int, day string, now time.Time) (int, error) {
+	missing, reflected, err := s.split(ctx, cohort, day)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(reflected) > 0 {
+		ids := make([]primitive.ObjectID, len(reflected))
+		for i, u := range reflected {
+			ids[i] = u.ID
+		}
+		if _, err := s.notifications.Withdraw(ids, domain.NotificationReflectionReminder, day); err != nil {
+			return 0, fmt.Errorf("withdraw reminders: %w", err)
+		}
+	}
+
+	var sent int
+	for _, u := range missing {
+		created, err := s.notifications.CreateForRecipient(&domain.Notification{
+			Title:       "Time to reflect",
+			Message:     "You haven't written today's reflection yet. Your plant only grows on days you reflect.",
+			IsActive:    true,
+			Priority:    "normal",
+			StartDate:   now,
+			EndDate:     utils.EndOfDay(now),
+			RecipientID: u.ID,
+			Kind:        domain.NotificationReflectionReminder,
+			Day:         day,
+		})
+		if err != nil {
+			return sent, err
+		}
+		if created {
+			sent++
+		}
+	}
+	return sent, nil
+}
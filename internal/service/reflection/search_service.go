@@ -0,0 +1,251 @@
+package reflection
+
+import (
+	"context"
+	"html"
+	"strings"
+	"time"
+	"unicode"
+
+	"gofiber-baro/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SearchQuery is a full-text search over a cohort's reflections. From and To
+// are inclusive Bangkok days; zero values don't filter.
+type SearchQuery struct {
+	Text    string
+	Cohort  int
+	From    string
+	To      string
+	Zone    domain.BarometerZone
+	GroupID primitive.ObjectID
+	Page    int
+	Limit   int
+}
+
+// SearchSnippet is an excerpt of one field of a matching reflection, HTML
+// escaped, with the matches wrapped in <mark>.
+type SearchSnippet struct {
+	Field string `json:"field"`
+	Text  string `json:"text"`
+}
+
+type SearchResult struct {
+	ReflectionID primitive.ObjectID   `json:"reflection_id"`
+	UserID       primitive.ObjectID   `json:"user_id"`
+	FirstName    string               `json:"first_name"`
+	LastName     string               `json:"last_name"`
+	ZoomName     string               `json:"zoom_name"`
+	CohortNumber int                  `json:"cohort_number"`
+	Day          string               `json:"day"`
+	Barometer    domain.BarometerZone `json:"barometer"`
+	Score        float64              `json:"score"`
+	Snippets     []SearchSnippet      `json:"snippets"`
+}
+
+// Search finds the reflections containing every word of q.Text in their
+// session names or what went well and what to improve, best matches first.
+func (s *Service) Search(q SearchQuery) ([]SearchResult, int64, error) {
+	terms := domain.SearchTerms(q.Text)
+	if len(terms) == 0 {
+		return nil, 0, domain.ErrEmptySearch
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Quoting every term makes the text search require all of them rather
+	// than any.
+	phrases := make([]string, len(terms))
+	for i, t := range terms {
+		phrases[i] = `"` + t + `"`
+	}
+	match := bson.M{"$text": bson.M{"$search": strings.Join(phrases, " ")}}
+	if q.Cohort > 0 {
+		match["cohort_number"] = q.Cohort
+	}
+	if q.From != "" || q.To != "" {
+		day := bson.M{}
+		if q.From != "" {
+			day["$gte"] = q.From
+		}
+		if q.To != "" {
+			day["$lte"] = q.To
+		}
+		match["day"] = day
+	}
+	if q.Zone != "" {
+		match["reflection.barometer"] = q.Zone
+	}
+	if !q.GroupID.IsZero() {
+		members, err := s.db.Collection("users").Distinct(ctx, "_id", bson.M{"$or": bson.A{
+			bson.M{"project_group_id": q.GroupID},
+			bson.M{"genmate_group_id": q.GroupID},
+		}})
+		if err != nil {
+			return nil, 0, err
+		}
+		match["user_id"] = bson.M{"$in": members}
+	}
+
+	collection := s.db.Collection("reflections")
+	total, err := collection.CountDocuments(ctx, match)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	pipeline := bson.A{
+		bson.M{"$match": match},
+		bson.M{"$addFields": bson.M{"score": bson.M{"$meta": "textScore"}}},
+		bson.M{"$sort": bson.D{{Key: "score", Value: -1}, {Key: "date", Value: -1}}},
+		bson.M{"$skip": (q.Page - 1) * q.Limit},
+		bson.M{"$limit": q.Limit},
+		userLookup,
+		bson.M{"$unwind": bson.M{"path": "$user", "preserveNullAndEmptyArrays": true}},
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		domain.Reflection `bson:",inline"`
+		Score             float64 `bson:"score"`
+		User              struct {
+			FirstName string `bson:"first_name"`
+			LastName  string `bson:"last_name"`
+			ZoomName  string `bson:"zoom_name"`
+		} `bson:"user"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, 0, err
+	}
+
+	needles := highlightNeedles(q.Text)
+	results := make([]SearchResult, len(rows))
+	for i, row := range rows {
+		results[i] = SearchResult{
+			ReflectionID: row.ID,
+			UserID:       row.UserID,
+			FirstName:    row.User.FirstName,
+			LastName:     row.User.LastName,
+			ZoomName:     row.User.ZoomName,
+			CohortNumber: row.CohortNumber,
+			Day:          row.DayKey(),
+			Barometer:    row.ReflectionData.Barometer,
+			Score:        row.Score,
+			Snippets:     snippets(&row.Reflection, needles),
+		}
+	}
+	return results, total, nil
+}
+
+// highlightNeedles are the parts of the search text to mark in snippets:
+// its words, and each run of Thai as a whole.
+func highlightNeedles(text string) [][]rune {
+	var needles [][]rune
+	for _, f := range strings.FieldsFunc(strings.ToLower(html.UnescapeString(text)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.Is(unicode.Mn, r)
+	}) {
+		needles = append(needles, []rune(f))
+	}
+	return needles
+}
+
+func snippets(r *domain.Reflection, needles [][]rune) []SearchSnippet {
+	fields := []struct {
+		name string
+		text string
+	}{
+		{"tech_sessions.session_name", strings.Join(r.ReflectionData.TechSessions.SessionName, ", ")},
+		{"tech_sessions.happy", r.ReflectionData.TechSessions.Happy},
+		{"tech_sessions.improve", r.ReflectionData.TechSessions.Improve},
+		{"non_tech_sessions.session_name", strings.Join(r.ReflectionData.NonTechSessions.SessionName, ", ")},
+		{"non_tech_sessions.happy", r.ReflectionData.NonTechSessions.Happy},
+		{"non_tech_sessions.improve", r.ReflectionData.NonTechSessions.Improve},
+	}
+	out := []SearchSnippet{}
+	for _, f := range fields {
+		if text, ok := highlight(html.UnescapeString(f.text), needles); ok {
+			out = append(out, SearchSnippet{Field: f.name, Text: text})
+		}
+	}
+	return out
+}
+
+// snippetContext is how many characters a snippet shows around its first
+// match.
+const snippetContext = 60
+
+// highlight marks every occurrence of the needles in text and cuts it down
+// to the stretch around the first one. It reports false if none occur.
+func highlight(text string, needles [][]rune) (string, bool) {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	marked := make([]bool, len(runes))
+	first := -1
+	for _, n := range needles {
+		for i := 0; i+len(n) <= len(lower); i++ {
+			if !hasPrefix(lower[i:], n) {
+				continue
+			}
+			for j := i; j < i+len(n); j++ {
+				marked[j] = true
+			}
+			if first < 0 || i < first {
+				first = i
+			}
+		}
+	}
+	if first < 0 {
+		return "", false
+	}
+
+	start := first - snippetContext
+	if start < 0 {
+		start = 0
+	}
+	end := first + 2*snippetContext
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; {
+		j := i
+		for j < end && marked[j] == marked[i] {
+			j++
+		}
+		part := html.EscapeString(string(runes[i:j]))
+		if marked[i] {
+			part = "<mark>" + part + "</mark>"
+		}
+		b.WriteString(part)
+		i = j
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String(), true
+}
+
+func hasPrefix(s, prefix []rune) bool {
+	if len(prefix) > len(s) {
+		return false
+	}
+	for i := range prefix {
+		if s[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
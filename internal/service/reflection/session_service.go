@@ -0,0 +1,409 @@
+package reflection
+
+import (
+	"context"
+	"html"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"gofiber-baro/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SessionQuery selects the reflections session analytics are drawn from.
+// From and To are inclusive Bangkok days; zero values don't filter. Session,
+// if set, keeps only sessions whose name contains it.
+type SessionQuery struct {
+	Cohort  int
+	From    string
+	To      string
+	Session string
+}
+
+// SessionDay is how the learners who attended a session felt on one day it
+// ran.
+type SessionDay struct {
+	Day       string                       `json:"day"`
+	Responses int                          `json:"responses"`
+	Barometer map[domain.BarometerZone]int `json:"barometer"`
+}
+
+type KeywordCount struct {
+	Word  string `json:"word"`
+	Count int    `json:"count"`
+}
+
+// SessionSummary aggregates the reflections that list a session. Key is the
+// normalized name sessions are grouped by; Name is its most used spelling.
+type SessionSummary struct {
+	Key             string                       `json:"key"`
+	Name            string                       `json:"name"`
+	Kinds           []string                     `json:"kinds"`
+	Responses       int                          `json:"responses"`
+	Barometer       map[domain.BarometerZone]int `json:"barometer"`
+	Days            []SessionDay                 `json:"days"`
+	HappyKeywords   []KeywordCount               `json:"happy_keywords"`
+	ImproveKeywords []KeywordCount               `json:"improve_keywords"`
+}
+
+// SessionAnswer is one learner's answers in a reflection listing a session.
+// Kind says whether they came from the tech or the non-tech part.
+type SessionAnswer struct {
+	ReflectionID primitive.ObjectID   `json:"reflection_id"`
+	UserID       primitive.ObjectID   `json:"user_id"`
+	FirstName    string               `json:"first_name"`
+	LastName     string               `json:"last_name"`
+	ZoomName     string               `json:"zoom_name"`
+	Day          string               `json:"day"`
+	Barometer    domain.BarometerZone `json:"barometer"`
+	Kind         string               `json:"kind"`
+	Happy        string               `json:"happy"`
+	Improve      string               `json:"improve"`
+}
+
+const (
+	SessionTech    = "tech"
+	SessionNonTech = "non_tech"
+)
+
+// topKeywordCount is how many keywords a session summary lists for each of
+// happy and improve.
+const topKeywordCount = 10
+
+// sessionMention is a reflection's answers for the sessions of one kind.
+type sessionMention struct {
+	reflection *domain.Reflection
+	kind       string
+	details    domain.SessionDetails
+}
+
+// Sessions groups the reflections matching q by session, most answered
+// first. Reflections written against a template have no session names and
+// aren't counted.
+func (s *Service) Sessions(q SessionQuery) ([]SessionSummary, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	mentions, err := s.sessionMentions(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	type group struct {
+		summary   *SessionSummary
+		spellings map[string]int
+		days      map[string]*SessionDay
+		happy     []string
+		improve   []string
+	}
+	groups := map[string]*group{}
+	var keys []string
+	for key, list := range mentions {
+		g := &group{
+			summary: &SessionSummary{
+				Key:       key,
+				Kinds:     []string{},
+				Barometer: newZoneCounts(),
+				Days:      []SessionDay{},
+			},
+			spellings: map[string]int{},
+			days:      map[string]*SessionDay{},
+		}
+		groups[key] = g
+		keys = append(keys, key)
+
+		for _, m := range list {
+			for _, name := range m.details.SessionName {
+				if domain.SessionKey(name) == key {
+					g.spellings[strings.Join(strings.Fields(html.UnescapeString(name)), " ")]++
+				}
+			}
+			if !containsString(g.summary.Kinds, m.kind) {
+				g.summary.Kinds = append(g.summary.Kinds, m.kind)
+			}
+			g.happy = append(g.happy, m.details.Happy)
+			g.improve = append(g.improve, m.details.Improve)
+
+			// A reflection listing the session under both kinds is still
+			// one learner's day.
+			if seenReflection(list, m) {
+				continue
+			}
+			g.summary.Responses++
+			zone := m.reflection.ReflectionData.Barometer
+			g.summary.Barometer[zone]++
+			day := m.reflection.DayKey()
+			d, ok := g.days[day]
+			if !ok {
+				d = &SessionDay{Day: day, Barometer: newZoneCounts()}
+				g.days[day] = d
+			}
+			d.Responses++
+			d.Barometer[zone]++
+		}
+	}
+
+	summaries := make([]SessionSummary, 0, len(keys))
+	for _, key := range keys {
+		g := groups[key]
+		g.summary.Name = mostUsed(g.spellings)
+		for _, d := range g.days {
+			g.summary.Days = append(g.summary.Days, *d)
+		}
+		sort.Slice(g.summary.Days, func(i, j int) bool { return g.summary.Days[i].Day < g.summary.Days[j].Day })
+		g.summary.HappyKeywords = topKeywords(g.happy, topKeywordCount)
+		g.summary.ImproveKeywords = topKeywords(g.improve, topKeywordCount)
+		summaries = append(summaries, *g.summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Responses != summaries[j].Responses {
+			return summaries[i].Responses > summaries[j].Responses
+		}
+		return summaries[i].Key < summaries[j].Key
+	})
+	return summaries, nil
+}
+
+// SessionAnswers lists the answers in reflections matching q that list the
+// session named name (in any spelling), latest first.
+func (s *Service) SessionAnswers(q SessionQuery, name string, page, limit int) ([]SessionAnswer, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	key := domain.SessionKey(name)
+	q.Session = ""
+	mentions, err := s.sessionMentions(ctx, q)
+	if err != nil {
+		return nil, 0, err
+	}
+	list := mentions[key]
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].reflection.DayKey() > list[j].reflection.DayKey()
+	})
+
+	total := len(list)
+	start := (page - 1) * limit
+	if start > total {
+		start = total
+	}
+	end := start + limit
+	if end > total {
+		end = total
+	}
+	list = list[start:end]
+
+	var ids []primitive.ObjectID
+	for _, m := range list {
+		ids = append(ids, m.reflection.UserID)
+	}
+	names := map[primitive.ObjectID]domain.User{}
+	if len(ids) > 0 {
+		opts := options.Find().SetProjection(bson.M{"first_name": 1, "last_name": 1, "zoom_name": 1})
+		cursor, err := s.db.Collection("users").Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, opts)
+		if err != nil {
+			return nil, 0, err
+		}
+		var users []domain.User
+		if err := cursor.All(ctx, &users); err != nil {
+			return nil, 0, err
+		}
+		for _, u := range users {
+			names[u.ID] = u
+		}
+	}
+
+	answers := make([]SessionAnswer, len(list))
+	for i, m := range list {
+		u := names[m.reflection.UserID]
+		answers[i] = SessionAnswer{
+			ReflectionID: m.reflection.ID,
+			UserID:       m.reflection.UserID,
+			FirstName:    u.FirstName,
+			LastName:     u.LastName,
+			ZoomName:     u.ZoomName,
+			Day:          m.reflection.DayKey(),
+			Barometer:    m.reflection.ReflectionData.Barometer,
+			Kind:         m.kind,
+			Happy:        m.details.Happy,
+			Improve:      m.details.Improve,
+		}
+	}
+	return answers, total, nil
+}
+
+// sessionMentions reads the reflections matching q that list a session and
+// files their answers under each session's key.
+func (s *Service) sessionMentions(ctx context.Context, q SessionQuery) (map[string][]sessionMention, error) {
+	filter := bson.M{
+		"cohort_number": q.Cohort,
+		"$or": bson.A{
+			bson.M{"reflection.tech_sessions.session_name.0": bson.M{"$exists": true}},
+			bson.M{"reflection.non_tech_sessions.session_name.0": bson.M{"$exists": true}},
+		},
+	}
+	if q.From != "" || q.To != "" {
+		day := bson.M{}
+		if q.From != "" {
+			day["$gte"] = q.From
+		}
+		if q.To != "" {
+			day["$lte"] = q.To
+		}
+		filter["day"] = day
+	}
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "user_id": 1, "day": 1, "date": 1, "reflection": 1})
+	cursor, err := s.db.Collection("reflections").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var reflections []domain.Reflection
+	if err := cursor.All(ctx, &reflections); err != nil {
+		return nil, err
+	}
+
+	only := domain.SessionKey(q.Session)
+	mentions := map[string][]sessionMention{}
+	for i := range reflections {
+		r := &reflections[i]
+		for _, part := range []struct {
+			kind    string
+			details domain.SessionDetails
+		}{
+			{SessionTech, r.ReflectionData.TechSessions},
+			{SessionNonTech, r.ReflectionData.NonTechSessions},
+		} {
+			var seen []string
+			for _, name := range part.details.SessionName {
+				key := domain.SessionKey(name)
+				if key == "" || containsString(seen, key) || !strings.Contains(key, only) {
+					continue
+				}
+				seen = append(seen, key)
+				mentions[key] = append(mentions[key], sessionMention{reflection: r, kind: part.kind, details: part.details})
+			}
+		}
+	}
+	return mentions, nil
+}
+
+// seenReflection reports whether m's reflection comes earlier in list.
+func seenReflection(list []sessionMention, m sessionMention) bool {
+	for _, other := range list {
+		if other.reflection == m.reflection {
+			return other.kind != m.kind
+		}
+	}
+	return false
+}
+
+func newZoneCounts() map[domain.BarometerZone]int {
+	counts := make(map[domain.BarometerZone]int, len(domain.BarometerZones))
+	for _, z := range domain.BarometerZones {
+		counts[z] = 0
+	}
+	return counts
+}
+
+// mostUsed returns the most frequent spelling, the first alphabetically on
+// a tie.
+func mostUsed(spellings map[string]int) string {
+	best, count := "", 0
+	for s, n := range spellings {
+		if n > count || (n == count && s < best) {
+			best, count = s, n
+		}
+	}
+	return best
+}
+
+// topKeywords returns the n words found in the most texts, counting each
+// word once per text so one long answer can't dominate.
+func topKeywords(texts []string, n int) []KeywordCount {
+	counts := map[string]int{}
+	for _, text := range texts {
+		seen := map[string]bool{}
+		for _, w := range keywords(text) {
+			if !seen[w] {
+				seen[w] = true
+				counts[w]++
+			}
+		}
+	}
+	out := make([]KeywordCount, 0, len(counts))
+	for w, c := range counts {
+		out = append(out, KeywordCount{Word: w, Count: c})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Word < out[j].Word
+	})
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// maxThaiKeyword is the longest run of Thai, in characters, kept as a
+// keyword. Thai has no spaces between words, so a run is a word or short
+// phrase the learner set apart; longer ones are sentences that won't repeat.
+const maxThaiKeyword = 12
+
+// keywords splits an answer into lowercase words, dropping stop words,
+// numbers and single letters. Trailing Thai politeness particles are
+// trimmed so "สนุกครับ" and "สนุกค่ะ" count as one.
+func keywords(text string) []string {
+	var out []string
+	for _, f := range strings.FieldsFunc(strings.ToLower(html.UnescapeString(text)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.Is(unicode.Mn, r)
+	}) {
+		if isThai(f) {
+			for _, p := range thaiParticles {
+				f = strings.TrimSuffix(f, p)
+			}
+			if n := len([]rune(f)); n < 2 || n > maxThaiKeyword {
+				continue
+			}
+		} else if len([]rune(f)) < 2 || strings.IndexFunc(f, unicode.IsLetter) < 0 {
+			continue
+		}
+		if stopWords[f] {
+			continue
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+func isThai(s string) bool {
+	for _, r := range s {
+		if !unicode.Is(unicode.Thai, r) {
+			return false
+		}
+	}
+	return true
+}
+
+var thaiParticles = []string{"ครับ", "ค่ะ", "คะ", "นะ", "จ้า", "จ้ะ"}
+
+var stopWords = func() map[string]bool {
+	words := strings.Fields(`
+		a about after all also am an and any are as at be because been but by
+		can could did do does doing for from had has have how i if in into is
+		it its it's just me more most my no not of on or our so some than that
+		the their them then there these they this to too up very was we were
+		what when which while who will with would you your
+		ok okay really today thing things lot bit much many get got
+		และ ที่ ได้ ให้ ไป มา เป็น มี ใน การ ของ กับ ก็ จะ แต่ ว่า อยู่ คือ
+		ผม ฉัน เรา หนู ตัวเอง วันนี้ ครับ ค่ะ คะ นะ
+	`)
+	m := make(map[string]bool, len(words))
+	for _, w := range words {
+		m[w] = true
+	}
+	return m
+}()
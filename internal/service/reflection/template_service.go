@@ -0,0 +1,86 @@
+package reflection
+
+import (
+	"context"
+	"time"
+
+	"gofiber-baro/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TemplateService manages the versioned reflection templates of each cohort.
+type TemplateService struct {
+	repo domain.ReflectionTemplateRepository
+}
+
+func NewTemplateService(repo domain.ReflectionTemplateRepository) *TemplateService {
+	return &TemplateService{repo: repo}
+}
+
+// Publish saves questions as the cohort's next template version. With
+// activate it replaces the active version at once; otherwise it is kept as a
+// draft until activated.
+func (s *TemplateService) Publish(cohort int, questions []domain.TemplateQuestion, createdBy primitive.ObjectID, activate bool) (*domain.ReflectionTemplate, error) {
+	t := &domain.ReflectionTemplate{
+		CohortNumber: cohort,
+		Questions:    questions,
+		CreatedBy:    createdBy,
+		CreatedAt:    time.Now(),
+	}
+	if err := t.Check(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.repo.Insert(ctx, t); err != nil {
+		return nil, err
+	}
+	if activate {
+		if err := s.repo.Activate(ctx, cohort, t.ID); err != nil {
+			return nil, err
+		}
+		t.Active = true
+	}
+	return t, nil
+}
+
+// List returns a cohort's template versions, newest first.
+func (s *TemplateService) List(cohort int) ([]domain.ReflectionTemplate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.repo.FindByCohort(ctx, cohort)
+}
+
+func (s *TemplateService) Get(id primitive.ObjectID) (*domain.ReflectionTemplate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.repo.FindByID(ctx, id)
+}
+
+// Activate makes a version its cohort's active template, e.g. to roll back
+// to an earlier one.
+func (s *TemplateService) Activate(id primitive.ObjectID) (*domain.ReflectionTemplate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Activate(ctx, t.CohortNumber, t.ID); err != nil {
+		return nil, err
+	}
+	t.Active = true
+	return t, nil
+}
+
+// Deactivate puts the cohort back on the standard reflection form. The
+// versions are kept.
+func (s *TemplateService) Deactivate(cohort int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.repo.Activate(ctx, cohort, primitive.NilObjectID)
+}
@@ -0,0 +1,145 @@
+package session
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"gofiber-baro/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Login throttling policy. The first few failures are free; after that each
+// one doubles the wait before the next attempt, and lockoutThreshold
+// failures lock the account outright. Failures older than failureWindow are
+// forgotten. The login_throttles TTL index (config.createIndexes) drops
+// throttles two hours after their last failure, so keep failureWindow plus
+// lockoutDuration below that.
+const (
+	freeAttempts     = 3
+	baseDelay        = 2 * time.Second
+	maxDelay         = 60 * time.Second
+	lockoutThreshold = 10
+	lockoutDuration  = 15 * time.Minute
+	failureWindow    = 1 * time.Hour
+)
+
+// LoginGuard persists failed logins per email so throttling survives restarts
+// and holds across IPs.
+type LoginGuard struct {
+	repo      domain.LoginThrottleRepository
+	eventRepo domain.LockoutEventRepository
+	userRepo  domain.UserRepository
+}
+
+func NewLoginGuard(repo domain.LoginThrottleRepository, eventRepo domain.LockoutEventRepository, userRepo domain.UserRepository) *LoginGuard {
+	return &LoginGuard{repo: repo, eventRepo: eventRepo, userRepo: userRepo}
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Check reports whether email may attempt a login now. When it may not, the
+// error is ErrAccountLocked or ErrLoginThrottled and retryAt says when to
+// come back.
+func (g *LoginGuard) Check(email string) (retryAt time.Time, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	throttle, err := g.repo.Find(ctx, normalizeEmail(email))
+	if err != nil || throttle == nil {
+		return time.Time{}, err
+	}
+	now := time.Now()
+	if throttle.LockedUntil != nil && throttle.LockedUntil.After(now) {
+		return *throttle.LockedUntil, domain.ErrAccountLocked
+	}
+	if throttle.NextAllowedAt != nil && throttle.NextAllowedAt.After(now) {
+		return *throttle.NextAllowedAt, domain.ErrLoginThrottled
+	}
+	return time.Time{}, nil
+}
+
+// Failure records a failed attempt, applying the delay or lockout it earns.
+// Unknown emails are tracked too, so they behave exactly like real ones.
+func (g *LoginGuard) Failure(email, ip string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	email = normalizeEmail(email)
+	now := time.Now()
+	throttle, err := g.repo.RecordFailure(ctx, email, now, failureWindow)
+	if err != nil {
+		log.Printf("[ERROR] login guard: record failure for %s: %v", email, err)
+		return
+	}
+
+	if throttle.FailedCount >= lockoutThreshold {
+		until := now.Add(lockoutDuration)
+		if err := g.repo.Lock(ctx, email, until); err != nil {
+			log.Printf("[ERROR] login guard: lock %s: %v", email, err)
+			return
+		}
+		event := &domain.LockoutEvent{
+			Email:          email,
+			IPAddress:      ip,
+			FailedAttempts: throttle.FailedCount,
+			LockedAt:       now,
+			LockedUntil:    until,
+		}
+		if u, err := g.userRepo.FindByEmail(ctx, email); err == nil {
+			event.UserID = u.ID
+		}
+		if err := g.eventRepo.Insert(ctx, event); err != nil {
+			log.Printf("[ERROR] login guard: lockout event for %s: %v", email, err)
+		}
+		return
+	}
+
+	if throttle.FailedCount >= freeAttempts {
+		delay := baseDelay << (throttle.FailedCount - freeAttempts)
+		if delay > maxDelay {
+			delay = maxDelay
+		}
+		if err := g.repo.SetNextAllowed(ctx, email, now.Add(delay)); err != nil {
+			log.Printf("[ERROR] login guard: delay %s: %v", email, err)
+		}
+	}
+}
+
+// Success clears the failure history after a good login.
+func (g *LoginGuard) Success(email string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := g.repo.Reset(ctx, normalizeEmail(email)); err != nil {
+		log.Printf("[ERROR] login guard: reset %s: %v", email, err)
+	}
+}
+
+// Unlock lifts a lockout (and any pending delay) for a user ahead of time.
+func (g *LoginGuard) Unlock(userID, unlockedBy primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	u, err := g.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	email := normalizeEmail(u.Email)
+	if err := g.repo.Reset(ctx, email); err != nil {
+		return err
+	}
+	return g.eventRepo.MarkUnlocked(ctx, email, unlockedBy, time.Now())
+}
+
+// Lockouts lists lockout events newest first, optionally for one user.
+func (g *LoginGuard) Lockouts(userID primitive.ObjectID, page, limit int) ([]domain.LockoutEvent, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return g.eventRepo.FindAll(ctx, userID, int64((page-1)*limit), int64(limit))
+}
@@ -0,0 +1,181 @@
+package session
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"gofiber-baro/internal/domain"
+	"gofiber-baro/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RefreshTokenTTL bounds how long a device stays signed in without activity.
+const RefreshTokenTTL = 30 * 24 * time.Hour
+
+type Service struct {
+	repo     domain.RefreshTokenRepository
+	userRepo domain.UserRepository
+	// enforceAdminTOTP (REQUIRE_ADMIN_TOTP) sends admins without two-factor
+	// to enrollment before anything else.
+	enforceAdminTOTP bool
+}
+
+func NewService(repo domain.RefreshTokenRepository, userRepo domain.UserRepository) *Service {
+	return &Service{repo: repo, userRepo: userRepo, enforceAdminTOTP: os.Getenv("REQUIRE_ADMIN_TOTP") == "true"}
+}
+
+// Login starts a new refresh chain for an already-authenticated user.
+func (s *Service) Login(u *domain.User, userAgent, ip string) (*domain.TokenPair, error) {
+	if u.Deleted {
+		return nil, domain.ErrUserNotFound
+	}
+	if u.Disabled {
+		return nil, domain.ErrUserDisabled
+	}
+	ctx := context.Background()
+	state := domain.UserAuthState{
+		ID:                 u.ID,
+		Role:               u.Role,
+		CohortNumber:       u.CohortNumber,
+		TokenVersion:       u.TokenVersion,
+		MustChangePassword: u.MustChangePassword,
+		StaffCohorts:       u.StaffCohorts,
+		TOTPEnabled:        u.TOTPEnabled,
+		TOTPRequired:       u.TOTPRequired,
+	}
+	return s.issue(ctx, state, primitive.NewObjectID(), userAgent, ip, nil)
+}
+
+// Refresh rotates a refresh token. Presenting a token that was already
+// rotated means it leaked, so the whole chain is revoked.
+func (s *Service) Refresh(raw, userAgent, ip string) (*domain.TokenPair, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	current, err := s.repo.FindByHash(ctx, utils.HashToken(raw))
+	if err != nil {
+		return nil, err
+	}
+	if current.RevokedAt != nil {
+		if current.ReplacedBy != nil {
+			_ = s.repo.RevokeFamily(ctx, current.FamilyID)
+		}
+		return nil, domain.ErrInvalidRefreshToken
+	}
+	if time.Now().After(current.ExpiresAt) {
+		return nil, domain.ErrInvalidRefreshToken
+	}
+
+	state, err := s.userRepo.FindAuthState(ctx, current.UserID)
+	if err != nil {
+		return nil, domain.ErrInvalidRefreshToken
+	}
+	if state.Deleted || state.Disabled {
+		_, _ = s.repo.RevokeAllForUser(ctx, current.UserID)
+		return nil, domain.ErrInvalidRefreshToken
+	}
+
+	return s.issue(ctx, *state, current.FamilyID, userAgent, ip, current)
+}
+
+// Logout revokes a single device's refresh token. The access token it was
+// paired with lapses on its own within AccessTokenTTL.
+func (s *Service) Logout(raw string) error {
+	ctx := context.Background()
+	return s.repo.RevokeByHash(ctx, utils.HashToken(raw))
+}
+
+// LogoutAll revokes every refresh token and bumps the token version so
+// outstanding access tokens stop working immediately.
+func (s *Service) LogoutAll(userID primitive.ObjectID) (int64, error) {
+	ctx := context.Background()
+	revoked, err := s.repo.RevokeAllForUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.userRepo.IncrementTokenVersion(ctx, userID); err != nil {
+		return 0, err
+	}
+	return revoked, nil
+}
+
+// Impersonate issues a short-lived, read-only access token that lets an
+// admin see the app as a learner. It carries the learner's token version, so
+// revoking the learner's sessions ends it too, and it comes with no refresh
+// token.
+func (s *Service) Impersonate(adminID, userID primitive.ObjectID) (string, error) {
+	ctx := context.Background()
+	state, err := s.userRepo.FindAuthState(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if state.Deleted {
+		return "", domain.ErrUserNotFound
+	}
+	if state.Disabled {
+		return "", domain.ErrUserDisabled
+	}
+	if state.Role != "learner" {
+		return "", domain.ErrImpersonationNotAllowed
+	}
+	return utils.GenerateJWT(utils.TokenSubject{
+		UserID:       state.ID,
+		Role:         state.Role,
+		Cohort:       state.CohortNumber,
+		TokenVersion: state.TokenVersion,
+		Impersonator: adminID,
+	}, "")
+}
+
+func (s *Service) issue(ctx context.Context, state domain.UserAuthState, familyID primitive.ObjectID, userAgent, ip string, previous *domain.RefreshToken) (*domain.TokenPair, error) {
+	raw, hash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	next := &domain.RefreshToken{
+		ID:        primitive.NewObjectID(),
+		UserID:    state.ID,
+		TokenHash: hash,
+		FamilyID:  familyID,
+		UserAgent: userAgent,
+		IP:        ip,
+		ExpiresAt: time.Now().Add(RefreshTokenTTL),
+	}
+
+	if previous != nil {
+		ok, err := s.repo.Rotate(ctx, previous.ID, next.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			// Lost a race with another refresh of the same token.
+			return nil, domain.ErrInvalidRefreshToken
+		}
+	}
+
+	if err := s.repo.Insert(ctx, next); err != nil {
+		return nil, err
+	}
+
+	access, err := utils.GenerateJWT(utils.TokenSubject{
+		UserID:             state.ID,
+		Role:               state.Role,
+		Cohort:             state.CohortNumber,
+		StaffCohorts:       state.StaffCohorts,
+		TokenVersion:       state.TokenVersion,
+		MustChangePassword: state.MustChangePassword,
+		TOTPSetupRequired:  state.NeedsTOTPSetup(s.enforceAdminTOTP),
+	}, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.TokenPair{
+		AccessToken:  access,
+		RefreshToken: raw,
+		ExpiresIn:    int(utils.AccessTokenTTL.Seconds()),
+	}, nil
+}
@@ -0,0 +1,303 @@
+package sso
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gofiber-baro/internal/domain"
+	"gofiber-baro/pkg/utils"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Service signs users in through an OpenID Connect provider (e.g. Google
+// Workspace) with the authorization-code flow. The provider only vouches for
+// an email address; the account itself must already exist in Baro.
+type Service struct {
+	userRepo       domain.UserRepository
+	issuer         string
+	clientID       string
+	clientSecret   string
+	redirectURL    string
+	allowedDomains map[string]bool
+	httpClient     *http.Client
+
+	mu        sync.Mutex
+	discovery *providerConfig
+	keys      map[string]*rsa.PublicKey
+}
+
+type providerConfig struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// idTokenClaims are the ID token fields Baro relies on.
+type idTokenClaims struct {
+	Email         string      `json:"email"`
+	EmailVerified interface{} `json:"email_verified"`
+	Nonce         string      `json:"nonce"`
+	jwt.RegisteredClaims
+}
+
+// NewService reads OIDC_ISSUER, OIDC_CLIENT_ID, OIDC_CLIENT_SECRET,
+// OIDC_REDIRECT_URL and OIDC_ALLOWED_DOMAINS (comma-separated; empty allows
+// any domain). Without an issuer and client ID single sign-on is off.
+func NewService(userRepo domain.UserRepository) *Service {
+	domains := map[string]bool{}
+	for _, d := range strings.Split(os.Getenv("OIDC_ALLOWED_DOMAINS"), ",") {
+		if d = strings.ToLower(strings.TrimSpace(d)); d != "" {
+			domains[d] = true
+		}
+	}
+	return &Service{
+		userRepo:       userRepo,
+		issuer:         strings.TrimRight(os.Getenv("OIDC_ISSUER"), "/"),
+		clientID:       os.Getenv("OIDC_CLIENT_ID"),
+		clientSecret:   os.Getenv("OIDC_CLIENT_SECRET"),
+		redirectURL:    os.Getenv("OIDC_REDIRECT_URL"),
+		allowedDomains: domains,
+		httpClient:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *Service) Enabled() bool {
+	return s.issuer != "" && s.clientID != ""
+}
+
+// AuthURL returns the provider URL to send the browser to, and the state the
+// client must hand back with the code.
+func (s *Service) AuthURL() (authURL, state string, err error) {
+	if !s.Enabled() {
+		return "", "", domain.ErrSSODisabled
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cfg, err := s.provider(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, _, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	state, err = utils.GenerateOIDCState(nonce)
+	if err != nil {
+		return "", "", err
+	}
+
+	q := url.Values{
+		"response_type": {"code"},
+		"client_id":     {s.clientID},
+		"redirect_uri":  {s.redirectURL},
+		"scope":         {"openid email profile"},
+		"state":         {state},
+		"nonce":         {nonce},
+	}
+	return cfg.AuthorizationEndpoint + "?" + q.Encode(), state, nil
+}
+
+// Authenticate exchanges an authorization code for an ID token, verifies it
+// and returns the Baro user with that email.
+func (s *Service) Authenticate(code, state string) (*domain.User, error) {
+	if !s.Enabled() {
+		return nil, domain.ErrSSODisabled
+	}
+	nonce, err := utils.ParseOIDCState(state)
+	if err != nil {
+		return nil, domain.ErrSSOInvalidState
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	cfg, err := s.provider(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rawIDToken, err := s.exchange(ctx, cfg, code)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := s.verifyIDToken(ctx, cfg, rawIDToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isTrue(claims.EmailVerified) {
+		return nil, domain.ErrSSOEmailUnverified
+	}
+	email := strings.TrimSpace(claims.Email)
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return nil, domain.ErrSSOInvalidToken
+	}
+	if len(s.allowedDomains) > 0 && !s.allowedDomains[strings.ToLower(email[at+1:])] {
+		return nil, domain.ErrSSODomainNotAllowed
+	}
+
+	return s.userRepo.FindByEmail(ctx, email)
+}
+
+// provider fetches and caches the issuer's discovery document.
+func (s *Service) provider(ctx context.Context) (*providerConfig, error) {
+	s.mu.Lock()
+	cached := s.discovery
+	s.mu.Unlock()
+	if cached != nil {
+		return cached, nil
+	}
+
+	var cfg providerConfig
+	if err := s.getJSON(ctx, s.issuer+"/.well-known/openid-configuration", &cfg); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	if strings.TrimRight(cfg.Issuer, "/") != s.issuer {
+		return nil, fmt.Errorf("oidc discovery: issuer mismatch %q", cfg.Issuer)
+	}
+
+	s.mu.Lock()
+	s.discovery = &cfg
+	s.mu.Unlock()
+	return &cfg, nil
+}
+
+func (s *Service) exchange(ctx context.Context, cfg *providerConfig, code string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {s.redirectURL},
+		"client_id":     {s.clientID},
+		"client_secret": {s.clientSecret},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("oidc token exchange: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		// Usually an expired or reused code.
+		return "", domain.ErrSSOInvalidToken
+	}
+
+	var body struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.IDToken == "" {
+		return "", domain.ErrSSOInvalidToken
+	}
+	return body.IDToken, nil
+}
+
+func (s *Service) verifyIDToken(ctx context.Context, cfg *providerConfig, raw, nonce string) (*idTokenClaims, error) {
+	claims := &idTokenClaims{}
+	token, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		kid, _ := token.Header["kid"].(string)
+		return s.key(ctx, cfg, kid)
+	})
+	if err != nil || !token.Valid {
+		return nil, domain.ErrSSOInvalidToken
+	}
+	if strings.TrimRight(claims.Issuer, "/") != s.issuer || !claims.VerifyAudience(s.clientID, true) {
+		return nil, domain.ErrSSOInvalidToken
+	}
+	if claims.Nonce != nonce {
+		return nil, domain.ErrSSOInvalidState
+	}
+	return claims, nil
+}
+
+// key returns the signing key with id kid, refetching the key set once when
+// it is unknown because providers rotate keys.
+func (s *Service) key(ctx context.Context, cfg *providerConfig, kid string) (*rsa.PublicKey, error) {
+	s.mu.Lock()
+	k, ok := s.keys[kid]
+	s.mu.Unlock()
+	if ok {
+		return k, nil
+	}
+
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := s.getJSON(ctx, cfg.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("oidc jwks: %w", err)
+	}
+	keys := map[string]*rsa.PublicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+		e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+	if k, ok := keys[kid]; ok {
+		return k, nil
+	}
+	return nil, errors.New("unknown signing key")
+}
+
+func (s *Service) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// isTrue accepts email_verified as a boolean or, as some providers send it,
+// the string "true".
+func isTrue(v interface{}) bool {
+	switch b := v.(type) {
+	case bool:
+		return b
+	case string:
+		return b == "true"
+	}
+	return false
+}
@@ -0,0 +1,255 @@
+package sso
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"gofiber-baro/internal/domain"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// mockIdP is a local OpenID Connect provider: it serves discovery, its key
+// set and a token endpoint that answers every code with idToken.
+type mockIdP struct {
+	server  *httptest.Server
+	key     *rsa.PrivateKey
+	idToken string
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &mockIdP{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(providerConfig{
+			Issuer:                idp.server.URL,
+			AuthorizationEndpoint: idp.server.URL + "/authorize",
+			TokenEndpoint:         idp.server.URL + "/token",
+			JWKSURI:               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": "test-key",
+				"kty": "RSA",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.FormValue("code") == "" || r.FormValue("client_secret") != "secret" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": idp.idToken})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// sign issues an ID token; claims override the defaults of a valid one.
+func (idp *mockIdP) sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	base := jwt.MapClaims{
+		"iss":            idp.server.URL,
+		"aud":            "baro",
+		"sub":            "1234",
+		"email":          "learner@example.com",
+		"email_verified": true,
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range claims {
+		base[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, base)
+	token.Header["kid"] = "test-key"
+	raw, err := token.SignedString(idp.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// usersByEmail is the only part of the user repository sign-in uses.
+type usersByEmail struct {
+	domain.UserRepository
+	users map[string]*domain.User
+}
+
+func (r usersByEmail) FindByEmail(ctx interface{}, email string) (*domain.User, error) {
+	if u, ok := r.users[email]; ok {
+		return u, nil
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func newTestService(t *testing.T, idp *mockIdP) *Service {
+	t.Helper()
+	t.Setenv("JWT_SECRET_KEY", "test-secret")
+	t.Setenv("OIDC_ISSUER", idp.server.URL)
+	t.Setenv("OIDC_CLIENT_ID", "baro")
+	t.Setenv("OIDC_CLIENT_SECRET", "secret")
+	t.Setenv("OIDC_REDIRECT_URL", "https://baro.example.com/sso/callback")
+	t.Setenv("OIDC_ALLOWED_DOMAINS", "example.com")
+	return NewService(usersByEmail{users: map[string]*domain.User{
+		"learner@example.com": {Email: "learner@example.com", FirstName: "Somchai"},
+	}})
+}
+
+// begin starts a sign-in and returns its state and nonce.
+func begin(t *testing.T, s *Service) (state, nonce string) {
+	t.Helper()
+	authURL, state, err := s.AuthURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Query().Get("state") != state {
+		t.Fatalf("auth URL state = %q, want %q", u.Query().Get("state"), state)
+	}
+	return state, u.Query().Get("nonce")
+}
+
+func TestAuthenticateValidLogin(t *testing.T) {
+	idp := newMockIdP(t)
+	s := newTestService(t, idp)
+	state, nonce := begin(t, s)
+	idp.idToken = idp.sign(t, jwt.MapClaims{"nonce": nonce})
+
+	u, err := s.Authenticate("code", state)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if u.Email != "learner@example.com" {
+		t.Errorf("user = %q, want learner@example.com", u.Email)
+	}
+}
+
+func TestAuthenticateRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims func(nonce string) jwt.MapClaims
+		state  func(state string) string
+		want   error
+	}{
+		{
+			name:  "tampered state",
+			state: func(state string) string { return state + "x" },
+			want:  domain.ErrSSOInvalidState,
+		},
+		{
+			name:   "wrong nonce",
+			claims: func(string) jwt.MapClaims { return jwt.MapClaims{"nonce": "someone-else"} },
+			want:   domain.ErrSSOInvalidState,
+		},
+		{
+			name:   "wrong audience",
+			claims: func(nonce string) jwt.MapClaims { return jwt.MapClaims{"nonce": nonce, "aud": "another-app"} },
+			want:   domain.ErrSSOInvalidToken,
+		},
+		{
+			name: "expired token",
+			claims: func(nonce string) jwt.MapClaims {
+				return jwt.MapClaims{"nonce": nonce, "exp": time.Now().Add(-time.Minute).Unix()}
+			},
+			want: domain.ErrSSOInvalidToken,
+		},
+		{
+			name: "wrong issuer",
+			claims: func(nonce string) jwt.MapClaims {
+				return jwt.MapClaims{"nonce": nonce, "iss": "https://evil.example.com"}
+			},
+			want: domain.ErrSSOInvalidToken,
+		},
+		{
+			name:   "unverified email",
+			claims: func(nonce string) jwt.MapClaims { return jwt.MapClaims{"nonce": nonce, "email_verified": false} },
+			want:   domain.ErrSSOEmailUnverified,
+		},
+		{
+			name: "unknown email",
+			claims: func(nonce string) jwt.MapClaims {
+				return jwt.MapClaims{"nonce": nonce, "email": "stranger@example.com"}
+			},
+			want: domain.ErrUserNotFound,
+		},
+		{
+			name: "domain not allowed",
+			claims: func(nonce string) jwt.MapClaims {
+				return jwt.MapClaims{"nonce": nonce, "email": "learner@gmail.com"}
+			},
+			want: domain.ErrSSODomainNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newMockIdP(t)
+			s := newTestService(t, idp)
+			state, nonce := begin(t, s)
+			claims := jwt.MapClaims{"nonce": nonce}
+			if tt.claims != nil {
+				claims = tt.claims(nonce)
+			}
+			idp.idToken = idp.sign(t, claims)
+			if tt.state != nil {
+				state = tt.state(state)
+			}
+
+			u, err := s.Authenticate("code", state)
+			if err != tt.want {
+				t.Fatalf("Authenticate = %v, %v; want error %v", u, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthenticateRejectsTokenFromAnotherKey(t *testing.T) {
+	idp := newMockIdP(t)
+	s := newTestService(t, idp)
+	state, nonce := begin(t, s)
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := &mockIdP{server: idp.server, key: other}
+	idp.idToken = signer.sign(t, jwt.MapClaims{"nonce": nonce})
+
+	if _, err := s.Authenticate("code", state); err != domain.ErrSSOInvalidToken {
+		t.Fatalf("Authenticate error = %v, want %v", err, domain.ErrSSOInvalidToken)
+	}
+}
+
+func TestDisabledWithoutIssuer(t *testing.T) {
+	t.Setenv("OIDC_ISSUER", "")
+	t.Setenv("OIDC_CLIENT_ID", "")
+	s := NewService(usersByEmail{})
+	if _, _, err := s.AuthURL(); err != domain.ErrSSODisabled {
+		t.Errorf("AuthURL error = %v, want %v", err, domain.ErrSSODisabled)
+	}
+	if _, err := s.Authenticate("code", "state"); err != domain.ErrSSODisabled {
+		t.Errorf("Authenticate error = %v, want %v", err, domain.ErrSSODisabled)
+	}
+}
@@ -0,0 +1,453 @@
+package user
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net/mail"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gofiber-baro/internal/domain"
+	"gofiber-baro/internal/service/group"
+
+	"github.com/xuri/excelize/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxBulkRows caps a single uploaded sheet.
+const MaxBulkRows = 500
+
+// bulkImportTTL is how long a finished import, and its uncollected results,
+// are kept.
+const bulkImportTTL = 24 * time.Hour
+
+// bulkColumns maps normalised sheet headers to BulkUserInput fields. Headers
+// are matched case-insensitively, ignoring spaces, dashes and underscores,
+// so both "first_name" and Salesforce's "First Name" work.
+var bulkColumns = map[string]string{
+	"firstname":    "first_name",
+	"first":        "first_name",
+	"lastname":     "last_name",
+	"last":         "last_name",
+	"surname":      "last_name",
+	"email":        "email",
+	"emailaddress": "email",
+	"jsdnumber":    "jsd_number",
+	"jsdno":        "jsd_number",
+	"jsd":          "jsd_number",
+	"password":     "password",
+	"projectgroup": "project_group",
+	"genmategroup": "genmate_group",
+	"genmate":      "genmate_group",
+	"zoomname":     "zoom_name",
+	"zoom":         "zoom_name",
+}
+
+// BulkRow is one data row of an uploaded sheet. Row is the spreadsheet row
+// number, counting the header as row 1.
+type BulkRow struct {
+	Row   int
+	Input BulkUserInput
+}
+
+// BulkRowCheck is the dry-run verdict for one row.
+type BulkRowCheck struct {
+	Row       int      `json:"row"`
+	Email     string   `json:"email"`
+	JSDNumber string   `json:"jsd_number,omitempty"`
+	Name      string   `json:"name"`
+	Valid     bool     `json:"valid"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// ParseBulkSheet reads a CSV or XLSX registration sheet, chosen by the file
+// extension. The first row must be a header naming at least the first name,
+// last name and email columns; unknown columns are ignored.
+func ParseBulkSheet(filename string, r io.Reader) ([]BulkRow, error) {
+	var records [][]string
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		cr.TrimLeadingSpace = true
+		all, err := cr.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		records = all
+	case ".xlsx":
+		f, err := excelize.OpenReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid XLSX: %w", err)
+		}
+		defer f.Close()
+		all, err := f.GetRows(f.GetSheetName(0))
+		if err != nil {
+			return nil, fmt.Errorf("invalid XLSX: %w", err)
+		}
+		records = all
+	default:
+		return nil, domain.ErrUnsupportedSheet
+	}
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("sheet is empty")
+	}
+
+	columns := map[string]int{}
+	for i, header := range records[0] {
+		if field, ok := bulkColumns[normaliseHeader(header)]; ok {
+			if _, seen := columns[field]; !seen {
+				columns[field] = i
+			}
+		}
+	}
+	for _, required := range []string{"first_name", "last_name", "email"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing column %q", required)
+		}
+	}
+
+	cell := func(record []string, field string) string {
+		i, ok := columns[field]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []BulkRow
+	for i, record := range records[1:] {
+		if isBlankRecord(record) {
+			continue
+		}
+		rows = append(rows, BulkRow{
+			Row: i + 2,
+			Input: BulkUserInput{
+				FirstName:    cell(record, "first_name"),
+				LastName:     cell(record, "last_name"),
+				Email:        cell(record, "email"),
+				JSDNumber:    cell(record, "jsd_number"),
+				Password:     cell(record, "password"),
+				ProjectGroup: cell(record, "project_group"),
+				GenmateGroup: cell(record, "genmate_group"),
+				ZoomName:     cell(record, "zoom_name"),
+			},
+		})
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("sheet has no data rows")
+	}
+	if len(rows) > MaxBulkRows {
+		return nil, fmt.Errorf("maximum %d users per sheet", MaxBulkRows)
+	}
+	return rows, nil
+}
+
+func normaliseHeader(h string) string {
+	// Excel's CSV export starts with a byte order mark.
+	h = strings.TrimPrefix(h, "\ufeff")
+	h = strings.ToLower(h)
+	return strings.NewReplacer(" ", "", "_", "", "-", "", ".", "").Replace(h)
+}
+
+func isBlankRecord(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckBulkRows validates each row on its own and against the rest of the
+// sheet and the existing accounts: required fields, email format, password
+// length, and emails or JSD numbers that are repeated or already taken.
+func (s *Service) CheckBulkRows(rows []BulkRow) ([]BulkRowCheck, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var emails, jsdNumbers []string
+	for _, r := range rows {
+		if r.Input.Email != "" {
+			emails = append(emails, r.Input.Email)
+		}
+		if r.Input.JSDNumber != "" {
+			jsdNumbers = append(jsdNumbers, r.Input.JSDNumber)
+		}
+	}
+	existing, err := s.repo.FindByEmailsOrJSDNumbers(ctx, emails, jsdNumbers)
+	if err != nil {
+		return nil, err
+	}
+	takenEmails := map[string]bool{}
+	takenJSD := map[string]string{}
+	for _, u := range existing {
+		takenEmails[strings.ToLower(u.Email)] = true
+		if u.JSDNumber != "" {
+			takenJSD[u.JSDNumber] = u.Email
+		}
+	}
+
+	emailRow := map[string]int{}
+	jsdRow := map[string]int{}
+	checks := make([]BulkRowCheck, len(rows))
+	for i, r := range rows {
+		in := r.Input
+		var problems []string
+
+		if in.FirstName == "" {
+			problems = append(problems, "first name is required")
+		}
+		if in.LastName == "" {
+			problems = append(problems, "last name is required")
+		}
+		if in.Email == "" {
+			problems = append(problems, "email is required")
+		} else {
+			if addr, err := mail.ParseAddress(in.Email); err != nil || addr.Address != in.Email {
+				problems = append(problems, "email is not valid")
+			}
+			key := strings.ToLower(in.Email)
+			if first, ok := emailRow[key]; ok {
+				problems = append(problems, fmt.Sprintf("email duplicates row %d", first))
+			} else {
+				emailRow[key] = r.Row
+			}
+			if takenEmails[key] {
+				problems = append(problems, "email already registered")
+			}
+		}
+		if in.JSDNumber != "" {
+			if first, ok := jsdRow[in.JSDNumber]; ok {
+				problems = append(problems, fmt.Sprintf("JSD number duplicates row %d", first))
+			} else {
+				jsdRow[in.JSDNumber] = r.Row
+			}
+			if owner, ok := takenJSD[in.JSDNumber]; ok {
+				problems = append(problems, "JSD number already used by "+owner)
+			}
+		}
+		if in.Password != "" && validateNewPassword(in.Password) != nil {
+			problems = append(problems, fmt.Sprintf("password must be at least %d characters", minPasswordLength))
+		}
+
+		checks[i] = BulkRowCheck{
+			Row:       r.Row,
+			Email:     in.Email,
+			JSDNumber: in.JSDNumber,
+			Name:      strings.TrimSpace(in.FirstName + " " + in.LastName),
+			Valid:     len(problems) == 0,
+			Errors:    problems,
+		}
+	}
+	return checks, nil
+}
+
+// BulkImportService commits uploaded sheets in the background and keeps the
+// results sheet until it is downloaded once. Imports live in this process
+// only, so the passwords in a results sheet are never stored: with several
+// instances, progress and results are only available from the one that took
+// the upload, and a restart loses them.
+type BulkImportService struct {
+	userService  *Service
+	groupService *group.Service
+
+	mu      sync.Mutex
+	imports map[string]*bulkImport
+}
+
+type bulkImport struct {
+	domain.BulkImport
+	results []byte
+}
+
+func NewBulkImportService(userService *Service, groupService *group.Service) *BulkImportService {
+	return &BulkImportService{userService: userService, groupService: groupService, imports: map[string]*bulkImport{}}
+}
+
+// Start commits the valid rows of a sheet in the background. Rows that fail
+// the checks are reported as skipped in the results sheet.
+func (s *BulkImportService) Start(filename string, rows []BulkRow, cohortNumber int, sharedPassword string, startedBy primitive.ObjectID) domain.BulkImport {
+	imp := &bulkImport{BulkImport: domain.BulkImport{
+		ID:           primitive.NewObjectID().Hex(),
+		Filename:     filename,
+		CohortNumber: cohortNumber,
+		Status:       domain.BulkImportRunning,
+		Total:        len(rows),
+		StartedBy:    startedBy,
+		StartedAt:    time.Now(),
+	}}
+
+	s.mu.Lock()
+	s.purge()
+	s.imports[imp.ID] = imp
+	snapshot := imp.BulkImport
+	s.mu.Unlock()
+
+	go s.run(imp, rows, sharedPassword)
+	return snapshot
+}
+
+// Get returns an import's progress.
+func (s *BulkImportService) Get(id string) (domain.BulkImport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.purge()
+	imp, ok := s.imports[id]
+	if !ok {
+		return domain.BulkImport{}, missingImport(id)
+	}
+	return imp.BulkImport, nil
+}
+
+// CollectResults hands out the results sheet, with any generated passwords,
+// and then drops it: a second call fails.
+func (s *BulkImportService) CollectResults(id string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	imp, ok := s.imports[id]
+	if !ok {
+		return nil, missingImport(id)
+	}
+	switch imp.Status {
+	case domain.BulkImportRunning:
+		return nil, domain.ErrBulkImportRunning
+	case domain.BulkImportFailed:
+		return nil, domain.ErrBulkImportFailed
+	}
+	if imp.ResultsCollected {
+		return nil, domain.ErrBulkResultsCollected
+	}
+	data := imp.results
+	now := time.Now()
+	imp.results = nil
+	imp.ResultsCollected = true
+	imp.ResultsCollectedAt = &now
+	return data, nil
+}
+
+// missingImport tells an ID that was never issued from one this server no
+// longer (or never) held: import IDs are ObjectIDs, so a well-formed one from
+// the past was most likely purged, lost in a restart or issued by another
+// instance.
+func missingImport(id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil || oid.Timestamp().After(time.Now()) {
+		return domain.ErrBulkImportNotFound
+	}
+	return domain.ErrBulkImportGone
+}
+
+// purge drops imports that finished more than a day ago. Callers hold mu.
+func (s *BulkImportService) purge() {
+	cutoff := time.Now().Add(-bulkImportTTL)
+	for id, imp := range s.imports {
+		if imp.FinishedAt != nil && imp.FinishedAt.Before(cutoff) {
+			delete(s.imports, id)
+		}
+	}
+}
+
+func (s *BulkImportService) run(imp *bulkImport, rows []BulkRow, sharedPassword string) {
+	status := domain.BulkImportFailed
+	var created, skipped, failed int
+	var sheet []byte
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[ERROR] bulk import %s panicked: %v", imp.ID, r)
+		}
+		now := time.Now()
+		s.mu.Lock()
+		imp.Status = status
+		imp.Created, imp.Skipped, imp.Failed = created, skipped, failed
+		imp.FinishedAt = &now
+		imp.results = sheet
+		s.mu.Unlock()
+	}()
+
+	// Re-check: accounts may have been created since the dry run.
+	checks, err := s.userService.CheckBulkRows(rows)
+	if err != nil {
+		log.Printf("[ERROR] bulk import %s: check rows: %v", imp.ID, err)
+		return
+	}
+
+	var inputs []BulkUserInput
+	for i, check := range checks {
+		if check.Valid {
+			inputs = append(inputs, rows[i].Input)
+		}
+	}
+	var results []BulkUserResult
+	if len(inputs) > 0 {
+		results, err = s.userService.BulkCreateUsers(inputs, imp.CohortNumber, sharedPassword)
+		if err != nil {
+			log.Printf("[ERROR] bulk import %s: create users: %v", imp.ID, err)
+			return
+		}
+		if _, err := s.groupService.MigrateLegacyNames(); err != nil {
+			log.Printf("[ERROR] bulk import %s: link groups: %v", imp.ID, err)
+		}
+	}
+
+	headers := []string{"Row", "First Name", "Last Name", "Email", "JSD Number", "Status", "Password", "Error"}
+	var out [][]string
+	next := 0
+	for i, check := range checks {
+		in := rows[i].Input
+		result := BulkUserResult{Email: in.Email, Status: "skipped", Error: strings.Join(check.Errors, "; ")}
+		if check.Valid {
+			result = results[next]
+			next++
+		}
+		switch result.Status {
+		case "created":
+			created++
+		case "skipped":
+			skipped++
+		default:
+			failed++
+		}
+		out = append(out, []string{
+			fmt.Sprint(check.Row), in.FirstName, in.LastName, in.Email, in.JSDNumber,
+			result.Status, result.Password, result.Error,
+		})
+	}
+
+	sheet, err = resultsSheet(headers, out)
+	if err != nil {
+		log.Printf("[ERROR] bulk import %s: write results: %v", imp.ID, err)
+		return
+	}
+	status = domain.BulkImportCompleted
+	log.Printf("[INFO] bulk import %s: %d created, %d skipped, %d failed", imp.ID, created, skipped, failed)
+}
+
+func resultsSheet(headers []string, rows [][]string) ([]byte, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+	sheet := f.GetSheetName(0)
+
+	if err := f.SetSheetRow(sheet, "A1", &headers); err != nil {
+		return nil, err
+	}
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		if err := f.SetSheetRow(sheet, cell, &row); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
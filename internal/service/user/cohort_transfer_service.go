@@ -0,0 +1,176 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"gofiber-baro/internal/domain"
+	"gofiber-baro/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TransferService moves a learner between cohorts. What follows the learner:
+//   - attendance records and leave requests dated on or after the effective
+//     date are re-tagged with the new cohort; earlier ones keep the old tag so
+//     the old cohort's stats stay intact.
+//   - talk board posts and stamps keep the old cohort tag — they belong to
+//     that cohort's board and poster, not to the learner.
+//   - reflections dated on or after the effective date are re-tagged too;
+//     ones still embedded in the user document carry no cohort tag.
+//
+// Every transfer bumps the user's token version so stale `cohort` claims die.
+//
+// The writes aren't in a transaction, but each can be repeated: running a
+// transfer that failed partway again with the same cohort and effective date
+// finishes it.
+type TransferService struct {
+	userRepo       domain.UserRepository
+	membershipRepo domain.CohortMembershipRepository
+	attendanceRepo domain.AttendanceRepository
+	leaveRepo      domain.LeaveRequestRepository
+	reflectionRepo domain.ReflectionRepository
+}
+
+func NewTransferService(
+	userRepo domain.UserRepository,
+	membershipRepo domain.CohortMembershipRepository,
+	attendanceRepo domain.AttendanceRepository,
+	leaveRepo domain.LeaveRequestRepository,
+	reflectionRepo domain.ReflectionRepository,
+) *TransferService {
+	return &TransferService{
+		userRepo:       userRepo,
+		membershipRepo: membershipRepo,
+		attendanceRepo: attendanceRepo,
+		leaveRepo:      leaveRepo,
+		reflectionRepo: reflectionRepo,
+	}
+}
+
+func (s *TransferService) Transfer(userID primitive.ObjectID, toCohort int, effectiveDate, reason, transferredBy string) (*domain.CohortTransferResult, error) {
+	if toCohort <= 0 {
+		return nil, domain.ErrInvalidCohort
+	}
+
+	today := utils.GetThailandDate()
+	if effectiveDate == "" {
+		effectiveDate = today
+	}
+	if _, err := time.Parse("2006-01-02", effectiveDate); err != nil || effectiveDate > today {
+		return nil, domain.ErrInvalidEffectiveDate
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	u, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	fromCohort := u.CohortNumber
+
+	history, err := s.membershipRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	// The steps below are separate writes, each safe to repeat. If the
+	// learner's open membership is already this transfer's, an earlier
+	// attempt got that far and failed later; pick up where it stopped.
+	resuming := false
+	openSince := ""
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].EndDate != "" {
+			continue
+		}
+		resuming = history[i].CohortNumber == toCohort && history[i].StartDate == effectiveDate
+		openSince = history[i].StartDate
+		break
+	}
+	// A transfer can't reach back past the one before it: that membership
+	// would end before it started, and records already moved would move
+	// again.
+	if !resuming && effectiveDate < openSince {
+		return nil, domain.ErrInvalidEffectiveDate
+	}
+	if resuming {
+		for _, m := range history {
+			if m.EndDate == effectiveDate && m.CohortNumber != toCohort {
+				fromCohort = m.CohortNumber
+			}
+		}
+	} else if fromCohort == toCohort {
+		return nil, domain.ErrSameCohort
+	}
+
+	if !resuming {
+		// Learners created before history existed get their original cohort
+		// recorded first so the timeline has no gap.
+		if len(history) == 0 && fromCohort > 0 {
+			if err := s.membershipRepo.Insert(ctx, &domain.CohortMembership{
+				UserID:       userID,
+				CohortNumber: fromCohort,
+			}); err != nil {
+				return nil, err
+			}
+		}
+
+		if _, err := s.membershipRepo.CloseOpen(ctx, userID, effectiveDate); err != nil {
+			return nil, err
+		}
+		if err := s.membershipRepo.Insert(ctx, &domain.CohortMembership{
+			UserID:        userID,
+			CohortNumber:  toCohort,
+			StartDate:     effectiveDate,
+			Reason:        reason,
+			TransferredBy: transferredBy,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	// Groups belong to a cohort, so the learner leaves theirs.
+	update := bson.M{
+		"cohort_number":    toCohort,
+		"project_group":    "",
+		"project_group_id": nil,
+		"genmate_group":    "",
+		"genmate_group_id": nil,
+	}
+	if err := s.userRepo.Update(ctx, userID, update); err != nil {
+		return nil, err
+	}
+
+	attendanceMoved, err := s.attendanceRepo.ReassignCohort(ctx, userID, effectiveDate, toCohort)
+	if err != nil {
+		return nil, err
+	}
+	leaveMoved, err := s.leaveRepo.ReassignCohort(ctx, userID, effectiveDate, toCohort)
+	if err != nil {
+		return nil, err
+	}
+	reflectionsMoved, err := s.reflectionRepo.ReassignCohort(ctx, userID, effectiveDate, toCohort)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.IncrementTokenVersion(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return &domain.CohortTransferResult{
+		UserID:                userID,
+		FromCohort:            fromCohort,
+		ToCohort:              toCohort,
+		EffectiveDate:         effectiveDate,
+		AttendanceMigrated:    attendanceMoved,
+		LeaveRequestsMigrated: leaveMoved,
+		ReflectionsMigrated:   reflectionsMoved,
+	}, nil
+}
+
+func (s *TransferService) History(userID primitive.ObjectID) ([]domain.CohortMembership, error) {
+	ctx := context.Background()
+	return s.membershipRepo.FindByUserID(ctx, userID)
+}
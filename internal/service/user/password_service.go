@@ -0,0 +1,155 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"gofiber-baro/internal/domain"
+	"gofiber-baro/internal/mailer"
+	"gofiber-baro/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ResetTokenTTL is how long a forgot-password link stays usable.
+const ResetTokenTTL = time.Hour
+
+const minPasswordLength = 8
+
+type PasswordService struct {
+	userRepo  domain.UserRepository
+	resetRepo domain.PasswordResetRepository
+	mailer    mailer.Mailer
+	resetURL  string
+}
+
+// NewPasswordService reads the frontend reset page from PASSWORD_RESET_URL;
+// the raw token is appended as ?token=.
+func NewPasswordService(userRepo domain.UserRepository, resetRepo domain.PasswordResetRepository, m mailer.Mailer) *PasswordService {
+	resetURL := os.Getenv("PASSWORD_RESET_URL")
+	if resetURL == "" {
+		resetURL = "http://localhost:5173/reset-password"
+	}
+	return &PasswordService{userRepo: userRepo, resetRepo: resetRepo, mailer: m, resetURL: resetURL}
+}
+
+func validateNewPassword(password string) error {
+	if len(password) < minPasswordLength {
+		return domain.ErrWeakPassword
+	}
+	return nil
+}
+
+// ChangePassword replaces the password of a signed-in user and clears any
+// forced-change flag. Callers should revoke the user's other sessions.
+func (s *PasswordService) ChangePassword(userID primitive.ObjectID, current, next string) error {
+	ctx := context.Background()
+	u, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !utils.CheckPasswordHash(current, u.Password) {
+		return domain.ErrWrongPassword
+	}
+	if err := validateNewPassword(next); err != nil {
+		return err
+	}
+	if current == next {
+		return domain.ErrPasswordUnchanged
+	}
+	return s.setPassword(ctx, userID, next)
+}
+
+// RequestReset mails a reset link if email belongs to an active user. It never
+// reports whether the address exists: the link is issued and mailed in the
+// background, so a registered address answers as fast as an unknown one.
+func (s *PasswordService) RequestReset(email string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	u, err := s.userRepo.FindByEmail(ctx, strings.TrimSpace(email))
+	if err != nil {
+		if err == domain.ErrUserNotFound {
+			return nil
+		}
+		return err
+	}
+	if u.Deleted || u.Disabled {
+		return nil
+	}
+
+	go s.sendReset(u)
+	return nil
+}
+
+// sendReset issues a new reset token for u and mails the link. Failures are
+// only logged; nobody is waiting for them.
+func (s *PasswordService) sendReset(u *domain.User) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Only the newest link should work.
+	if err := s.resetRepo.InvalidateForUser(ctx, u.ID); err != nil {
+		log.Printf("[ERROR] RequestReset: invalidate links for %s: %v", u.ID.Hex(), err)
+		return
+	}
+
+	raw, hash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		log.Printf("[ERROR] RequestReset: generate token: %v", err)
+		return
+	}
+	if err := s.resetRepo.Insert(ctx, &domain.PasswordResetToken{
+		UserID:    u.ID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(ResetTokenTTL),
+	}); err != nil {
+		log.Printf("[ERROR] RequestReset: store token for %s: %v", u.ID.Hex(), err)
+		return
+	}
+
+	link := s.resetURL + "?token=" + url.QueryEscape(raw)
+	body := fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password for your account. "+
+		"Open this link within %d minutes to choose a new one:\n\n%s\n\n"+
+		"If you didn't ask for this, you can ignore this email.\n",
+		u.FirstName, int(ResetTokenTTL.Minutes()), link)
+
+	if err := s.mailer.Send(ctx, u.Email, "Reset your password", body); err != nil {
+		log.Printf("[ERROR] RequestReset: mail to %s failed: %v", u.Email, err)
+	}
+}
+
+// ResetPassword consumes a reset token and sets the new password. It returns
+// the user ID so the caller can revoke existing sessions.
+func (s *PasswordService) ResetPassword(rawToken, next string) (primitive.ObjectID, error) {
+	if err := validateNewPassword(next); err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	ctx := context.Background()
+	token, err := s.resetRepo.Consume(ctx, utils.HashToken(rawToken))
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	if err := s.setPassword(ctx, token.UserID, next); err != nil {
+		return primitive.NilObjectID, err
+	}
+	return token.UserID, nil
+}
+
+func (s *PasswordService) setPassword(ctx context.Context, userID primitive.ObjectID, password string) error {
+	hashed, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	return s.userRepo.Update(ctx, userID, bson.M{
+		"password":             hashed,
+		"must_change_password": false,
+	})
+}
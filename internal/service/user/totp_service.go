@@ -0,0 +1,185 @@
+package user
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"gofiber-baro/internal/domain"
+	"gofiber-baro/pkg/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const recoveryCodeCount = 10
+
+// TOTPService manages two-factor enrollment and checks the second factor at
+// login.
+type TOTPService struct {
+	userRepo      domain.UserRepository
+	issuer        string
+	enforceAdmins bool
+}
+
+// NewTOTPService reads TOTP_ISSUER (the name shown in authenticator apps) and
+// REQUIRE_ADMIN_TOTP, which makes two-factor mandatory for every admin.
+func NewTOTPService(userRepo domain.UserRepository) *TOTPService {
+	issuer := os.Getenv("TOTP_ISSUER")
+	if issuer == "" {
+		issuer = "Baro"
+	}
+	return &TOTPService{
+		userRepo:      userRepo,
+		issuer:        issuer,
+		enforceAdmins: os.Getenv("REQUIRE_ADMIN_TOTP") == "true",
+	}
+}
+
+// Enroll starts (or restarts) enrollment with a fresh secret. Nothing changes
+// at login until Confirm succeeds.
+func (s *TOTPService) Enroll(userID primitive.ObjectID) (secret, uri string, err error) {
+	ctx := context.Background()
+	u, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return "", "", err
+	}
+	if u.TOTPEnabled {
+		return "", "", domain.ErrTOTPAlreadyEnabled
+	}
+
+	secret, err = utils.GenerateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.userRepo.Update(ctx, userID, bson.M{"totp_pending_secret": secret}); err != nil {
+		return "", "", err
+	}
+	return secret, utils.TOTPURI(s.issuer, u.Email, secret), nil
+}
+
+// Confirm checks the first code from a pending enrollment, turns two-factor
+// on and returns the recovery codes. They are shown once; only hashes are
+// kept.
+func (s *TOTPService) Confirm(userID primitive.ObjectID, code string) ([]string, error) {
+	ctx := context.Background()
+	u, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.TOTPEnabled {
+		return nil, domain.ErrTOTPAlreadyEnabled
+	}
+	if u.TOTPPendingSecret == "" {
+		return nil, domain.ErrTOTPNotEnrolled
+	}
+
+	step, ok := utils.ValidateTOTP(u.TOTPPendingSecret, code, time.Now())
+	if !ok {
+		return nil, domain.ErrInvalidTOTPCode
+	}
+
+	codes, hashes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	update := bson.M{
+		"totp_enabled":        true,
+		"totp_secret":         u.TOTPPendingSecret,
+		"totp_pending_secret": "",
+		"totp_last_step":      step,
+		"recovery_codes":      hashes,
+	}
+	if err := s.userRepo.Update(ctx, userID, update); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify checks the second login factor: a current TOTP code, or one of the
+// recovery codes, which is then used up.
+func (s *TOTPService) Verify(u *domain.User, code string) error {
+	ctx := context.Background()
+	if !u.TOTPEnabled {
+		return domain.ErrTOTPNotEnrolled
+	}
+
+	if step, ok := utils.ValidateTOTP(u.TOTPSecret, code, time.Now()); ok {
+		fresh, err := s.userRepo.ConsumeTOTPStep(ctx, u.ID, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return domain.ErrInvalidTOTPCode
+		}
+		return nil
+	}
+
+	used, err := s.userRepo.ConsumeRecoveryCode(ctx, u.ID, utils.HashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return domain.ErrInvalidTOTPCode
+	}
+	return nil
+}
+
+// Disable turns two-factor off for a user who proves both factors. Accounts
+// where it is required cannot opt out.
+func (s *TOTPService) Disable(userID primitive.ObjectID, password, code string) error {
+	ctx := context.Background()
+	u, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !u.TOTPEnabled {
+		return domain.ErrTOTPNotEnrolled
+	}
+	if u.TOTPRequired || (s.enforceAdmins && u.Role == "admin") {
+		return domain.ErrTOTPRequired
+	}
+	if !utils.CheckPasswordHash(password, u.Password) {
+		return domain.ErrWrongPassword
+	}
+	if err := s.Verify(u, code); err != nil {
+		return err
+	}
+	return s.clear(ctx, userID)
+}
+
+// SetRequired lets an admin make two-factor mandatory for a user. Existing
+// tokens are revoked so the next login picks up the requirement.
+func (s *TOTPService) SetRequired(userID primitive.ObjectID, required bool) error {
+	ctx := context.Background()
+	if _, err := s.userRepo.FindAuthState(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.userRepo.Update(ctx, userID, bson.M{"totp_required": required}); err != nil {
+		return err
+	}
+	return s.userRepo.IncrementTokenVersion(ctx, userID)
+}
+
+// Reset wipes a user's two-factor setup, e.g. after a lost phone. If it is
+// required they will be asked to enroll again at next login.
+func (s *TOTPService) Reset(userID primitive.ObjectID) error {
+	ctx := context.Background()
+	if _, err := s.userRepo.FindAuthState(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.clear(ctx, userID); err != nil {
+		return err
+	}
+	return s.userRepo.IncrementTokenVersion(ctx, userID)
+}
+
+func (s *TOTPService) clear(ctx context.Context, userID primitive.ObjectID) error {
+	return s.userRepo.Update(ctx, userID, bson.M{
+		"totp_enabled":        false,
+		"totp_secret":         "",
+		"totp_pending_secret": "",
+		"totp_last_step":      0,
+		"recovery_codes":      nil,
+	})
+}
@@ -4,10 +4,14 @@ import (
 	"context"
 	"crypto/rand"
 	"errors"
+	"log"
 	"math/big"
+	"os"
+	"strings"
 	"time"
 
 	"gofiber-baro/internal/domain"
+	"gofiber-baro/internal/service/wellbeing"
 	"gofiber-baro/pkg/utils"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -16,11 +20,35 @@ import (
 )
 
 type Service struct {
-	repo domain.UserRepository
+	repo        domain.UserRepository
+	reflections domain.ReflectionRepository
+	templates   domain.ReflectionTemplateRepository
+	wellbeing   *wellbeing.Service
+	// editWindow is how long after writing a learner may edit a reflection;
+	// zero means until the end of its Bangkok day, negative never.
+	editWindow time.Duration
 }
 
-func NewService(repo domain.UserRepository) *Service {
-	return &Service{repo: repo}
+func NewService(repo domain.UserRepository, reflections domain.ReflectionRepository, templates domain.ReflectionTemplateRepository, wellbeing *wellbeing.Service) *Service {
+	return &Service{repo: repo, reflections: reflections, templates: templates, wellbeing: wellbeing, editWindow: editWindowFromEnv()}
+}
+
+// editWindowFromEnv reads REFLECTION_EDIT_WINDOW: "day" (the default) for the
+// rest of the reflection's Bangkok day, a duration such as "2h", or "off".
+func editWindowFromEnv() time.Duration {
+	v := strings.TrimSpace(os.Getenv("REFLECTION_EDIT_WINDOW"))
+	switch v {
+	case "", "day":
+		return 0
+	case "off", "0":
+		return -1
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Printf("WARNING: invalid REFLECTION_EDIT_WINDOW %q, using the same day", v)
+		return 0
+	}
+	return d
 }
 
 func (s *Service) GetUserByID(id string) (*domain.User, error) {
@@ -33,6 +61,30 @@ func (s *Service) GetUserByID(id string) (*domain.User, error) {
 	return s.repo.FindByID(ctx, oid)
 }
 
+// GetGroupMembers returns the learners in a project or genmate group,
+// including deleted ones, sorted by first name. They are loaded as summaries
+// (see UserRepository.FindSummaries).
+func (s *Service) GetGroupMembers(groupID primitive.ObjectID) ([]domain.User, error) {
+	ctx := context.Background()
+	filter := domain.UserFilter{Role: "learner", GroupID: groupID}
+	users, _, err := s.repo.FindSummaries(ctx, filter, options.Find().SetSort(bson.D{{Key: "first_name", Value: 1}}))
+	return users, err
+}
+
+// GetRoster returns up to limit users of a cohort (all cohorts when 0) and
+// role (any when ""), sorted by first name, with only the fields of
+// UserRepository.FindRoster. A limit of 0 means no limit.
+func (s *Service) GetRoster(cohort int, role string, limit int) ([]domain.User, error) {
+	ctx := context.Background()
+	filter := domain.UserFilter{Cohort: cohort, Role: role}
+	findOpts := options.Find().SetSort(bson.D{{Key: "first_name", Value: 1}})
+	if limit > 0 {
+		findOpts.SetLimit(int64(limit))
+	}
+	users, _, err := s.repo.FindRoster(ctx, filter, findOpts)
+	return users, err
+}
+
 func (s *Service) GetUserByEmail(email string) (*domain.User, error) {
 	ctx := context.Background()
 	return s.repo.FindByEmail(ctx, email)
@@ -81,6 +133,103 @@ func (s *Service) UpdateUser(id string, update interface{}) error {
 	return s.repo.Update(ctx, oid, update)
 }
 
+// ChangeLearnerStatus records a dated status transition. Backdating is allowed
+// so a dropout can be logged after the fact; future dates are not, since
+// attendance_status must reflect today.
+func (s *Service) ChangeLearnerStatus(userID primitive.ObjectID, status domain.LearnerStatus, effectiveDate, reason, changedBy string) (*domain.StatusTransition, error) {
+	if !status.IsValid() {
+		return nil, domain.ErrInvalidLearnerStatus
+	}
+
+	today := utils.GetThailandDate()
+	if effectiveDate == "" {
+		effectiveDate = today
+	}
+	if _, err := time.Parse("2006-01-02", effectiveDate); err != nil || effectiveDate > today {
+		return nil, domain.ErrInvalidEffectiveDate
+	}
+
+	ctx := context.Background()
+	u, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Carry a legacy attendance_status over as the opening entry so adding
+	// history never silently reactivates an old dropout.
+	if len(u.StatusHistory) == 0 && u.AttendanceStatus != "" {
+		u.StatusHistory = append(u.StatusHistory, domain.StatusTransition{
+			Status:        domain.LearnerStatus(u.AttendanceStatus),
+			EffectiveDate: "0000-01-01",
+			Reason:        "legacy status",
+		})
+		if err := s.repo.AddStatusTransition(ctx, userID, u.StatusHistory[0], u.AttendanceStatus); err != nil {
+			return nil, err
+		}
+	}
+
+	transition := domain.StatusTransition{
+		Status:        status,
+		EffectiveDate: effectiveDate,
+		Reason:        reason,
+		ChangedBy:     changedBy,
+		ChangedAt:     time.Now(),
+	}
+	u.StatusHistory = append(u.StatusHistory, transition)
+
+	current := string(u.StatusOn(today))
+	if current == string(domain.LearnerActive) {
+		current = ""
+	}
+	if err := s.repo.AddStatusTransition(ctx, userID, transition, current); err != nil {
+		return nil, err
+	}
+	return &transition, nil
+}
+
+// IsTokenRevoked satisfies middleware.TokenRevocationChecker. A token is dead
+// once its user is deleted or disabled, their role changed, or their token
+// version was bumped.
+func (s *Service) IsTokenRevoked(ctx context.Context, userID, role string, tokenVersion int) (bool, error) {
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return true, nil
+	}
+	state, err := s.repo.FindAuthState(ctx, oid)
+	if err == domain.ErrUserNotFound {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if state.Deleted || state.Disabled || state.Role != role {
+		return true, nil
+	}
+	return state.TokenVersion != tokenVersion, nil
+}
+
+// SetRole changes a user's role and staff cohort assignment. The token
+// version is bumped so the new scope takes effect on the next request.
+func (s *Service) SetRole(userID primitive.ObjectID, role string, staffCohorts []int) error {
+	ctx := context.Background()
+	if _, err := s.repo.FindAuthState(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.repo.Update(ctx, userID, bson.M{"role": role, "staff_cohorts": staffCohorts}); err != nil {
+		return err
+	}
+	return s.repo.IncrementTokenVersion(ctx, userID)
+}
+
+// SetDisabled blocks or restores a user's access without deleting them.
+func (s *Service) SetDisabled(userID primitive.ObjectID, disabled bool) error {
+	ctx := context.Background()
+	if _, err := s.repo.FindAuthState(ctx, userID); err != nil {
+		return err
+	}
+	return s.repo.Update(ctx, userID, bson.M{"disabled": disabled})
+}
+
 func (s *Service) AwardBadge(userID primitive.ObjectID, badgeType, badgeName, emoji, imageUrl, color, style string) error {
 	ctx := context.Background()
 
@@ -98,47 +247,191 @@ func (s *Service) AwardBadge(userID primitive.ObjectID, badgeType, badgeName, em
 	return s.repo.AddBadge(ctx, userID, badge)
 }
 
-func (s *Service) UpdateReflectionFeedback(userID, reflectionID primitive.ObjectID, feedback string) error {
-	ctx := context.Background()
-	return s.repo.UpdateReflectionFeedback(ctx, userID, reflectionID, feedback)
-}
-
+// CreateReflection stores today's reflection, tagged with the learner's
+// current cohort and with its zone spelled canonically. A second one on the
+// same day returns ErrReflectionExists.
 func (s *Service) CreateReflection(userID primitive.ObjectID, reflection domain.Reflection) (*domain.Reflection, error) {
 	ctx := context.Background()
 
-	user, err := s.repo.FindByID(ctx, userID)
+	state, err := s.repo.FindAuthState(ctx, userID)
 	if err != nil {
 		return nil, domain.ErrUserNotFound
 	}
 
-	now := utils.GetThailandTime()
-	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-	for _, r := range user.Reflections {
-		reflectionDate := time.Date(r.CreatedAt.Year(), r.CreatedAt.Month(), r.CreatedAt.Day(), 0, 0, 0, 0, now.Location())
-		if reflectionDate.Equal(today) {
-			return nil, errors.New("user has already created a reflection today")
-		}
+	// Cohorts with an active template answer its questions; the rest use
+	// the standard form.
+	template, err := s.templates.FindActive(ctx, state.CohortNumber)
+	if err == domain.ErrTemplateNotFound {
+		template, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := setContent(&reflection, reflection, template); err != nil {
+		return nil, err
 	}
 
-	reflection.CreatedAt = now
+	if reflection.Day == "" {
+		reflection.Day = utils.GetThailandDate()
+	}
+	exists, err := s.reflections.ExistsOnDay(ctx, userID, reflection.Day)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, domain.ErrReflectionExists
+	}
+
+	reflection.UserID = userID
+	reflection.CohortNumber = state.CohortNumber
+	reflection.AdminFeedback = ""
+	reflection.UpdatedAt = nil
+	reflection.Revisions = nil
+	reflection.EditedAfterFeedback = false
+	reflection.CreatedAt = utils.GetThailandTime()
 	reflection.ID = primitive.NewObjectID()
 
-	if err := s.repo.CreateReflection(ctx, userID, reflection); err != nil {
+	if err := s.reflections.Insert(ctx, &reflection); err != nil {
 		return nil, err
 	}
+	go s.wellbeing.Evaluate(reflection)
 
 	return &reflection, nil
 }
 
-func (s *Service) GetReflections(userID primitive.ObjectID) ([]domain.Reflection, error) {
+// setContent checks in against template, or the standard form if it is nil,
+// and copies the result into r. Either way the barometer ends up in
+// ReflectionData for the reports.
+func setContent(r *domain.Reflection, in domain.Reflection, template *domain.ReflectionTemplate) error {
+	if template != nil {
+		answers, zone, err := template.Validate(in.Answers)
+		if err != nil {
+			return err
+		}
+		r.Answers = answers
+		r.TemplateID = template.ID
+		r.TemplateVersion = template.Version
+		r.ReflectionData = domain.ReflectionContent{Barometer: zone}
+		return nil
+	}
+
+	zone, ok := domain.ParseBarometerZone(string(in.ReflectionData.Barometer))
+	if !ok {
+		return domain.ErrInvalidBarometerZone
+	}
+	r.ReflectionData = in.ReflectionData
+	r.ReflectionData.Barometer = zone
+	r.Answers = nil
+	r.TemplateID = primitive.NilObjectID
+	r.TemplateVersion = 0
+	return nil
+}
+
+// editable reports whether r can still be edited at now.
+func (s *Service) editable(r *domain.Reflection, now time.Time) bool {
+	switch {
+	case s.editWindow < 0:
+		return false
+	case s.editWindow == 0:
+		return r.DayKey() == utils.GetThailandDate()
+	}
+	return now.Before(r.CreatedAt.Add(s.editWindow))
+}
+
+// UpdateReflection replaces the content of one of the user's reflections
+// within the edit window, keeping the old content as a revision. Template
+// reflections are checked against the template version they were written
+// with.
+func (s *Service) UpdateReflection(userID, reflectionID primitive.ObjectID, in domain.Reflection) (*domain.Reflection, error) {
 	ctx := context.Background()
 
-	user, err := s.repo.FindByID(ctx, userID)
+	r, err := s.reflections.FindByID(ctx, userID, reflectionID)
+	if err != nil {
+		return nil, err
+	}
+	now := utils.GetThailandTime()
+	if !s.editable(r, now) {
+		return nil, domain.ErrReflectionEditClosed
+	}
+
+	var template *domain.ReflectionTemplate
+	if !r.TemplateID.IsZero() {
+		if template, err = s.templates.FindByID(ctx, r.TemplateID); err != nil {
+			return nil, err
+		}
+	}
+
+	readUpdatedAt := r.UpdatedAt
+	previous := domain.ReflectionRevision{ReflectionData: r.ReflectionData, Answers: r.Answers, ReplacedAt: now}
+	if err := setContent(r, in, template); err != nil {
+		return nil, err
+	}
+	r.UpdatedAt = &now
+	r.Revisions = append(r.Revisions, previous)
+	if r.LastStaffReplyAt != nil || r.AdminFeedback != "" {
+		r.EditedAfterFeedback = true
+	}
+
+	if err := s.reflections.UpdateContent(ctx, r, readUpdatedAt); err != nil {
+		return nil, err
+	}
+	// A changed barometer can complete a run.
+	go s.wellbeing.Evaluate(*r)
+	return r, nil
+}
+
+// GetReflectionTemplate returns the template the user's next reflection is
+// checked against, or nil if their cohort uses the standard form.
+func (s *Service) GetReflectionTemplate(userID primitive.ObjectID) (*domain.ReflectionTemplate, error) {
+	ctx := context.Background()
+
+	state, err := s.repo.FindAuthState(ctx, userID)
 	if err != nil {
 		return nil, domain.ErrUserNotFound
 	}
+	template, err := s.templates.FindActive(ctx, state.CohortNumber)
+	if err == domain.ErrTemplateNotFound {
+		return nil, nil
+	}
+	return template, err
+}
 
-	return user.Reflections, nil
+func (s *Service) GetReflections(userID primitive.ObjectID) ([]domain.Reflection, error) {
+	ctx := context.Background()
+
+	if _, err := s.repo.FindAuthState(ctx, userID); err != nil {
+		return nil, domain.ErrUserNotFound
+	}
+
+	reflections, err := s.reflections.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range reflections {
+		reflections[i].UnreadFeedback = reflections[i].HasUnreadFeedback()
+	}
+	return reflections, nil
+}
+
+// GetUserWithReflections is GetUserByID with Reflections filled from the
+// reflections collection, for responses that still carry them inline.
+func (s *Service) GetUserWithReflections(id string) (*domain.User, error) {
+	user, err := s.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+	reflections, err := s.reflections.FindByUser(context.Background(), user.ID)
+	if err != nil {
+		return nil, err
+	}
+	user.Reflections = reflections
+	return user, nil
+}
+
+// GetReflectionDays returns the days each user reflected on.
+func (s *Service) GetReflectionDays(userIDs []primitive.ObjectID) (map[primitive.ObjectID][]string, error) {
+	ctx := context.Background()
+	return s.reflections.FindDays(ctx, userIDs)
 }
 
 func (s *Service) AddProfileComment(userID primitive.ObjectID, commenterID primitive.ObjectID, zoomName string, cohort int, content string, parentID string) error {
@@ -281,7 +574,9 @@ func (s *Service) BulkCreateUsers(inputs []BulkUserInput, cohortNumber int, shar
 			ProjectGroup: in.ProjectGroup,
 			GenmateGroup: in.GenmateGroup,
 			ZoomName:     in.ZoomName,
-			Reflections:  []domain.Reflection{},
+			// The password went through an admin, so the learner must
+			// replace it on first login.
+			MustChangePassword: true,
 		}
 
 		if err := s.repo.Create(ctx, user); err != nil {
@@ -303,11 +598,10 @@ func (s *Service) SoftDeleteUser(id string) error {
 
 	ctx := context.Background()
 	now := time.Now()
+	// Update already wraps the document in $set.
 	update := bson.M{
-		"$set": bson.M{
-			"deleted":    true,
-			"deleted_at": now,
-		},
+		"deleted":    true,
+		"deleted_at": now,
 	}
 	return s.repo.Update(ctx, oid, update)
 }
@@ -0,0 +1,299 @@
+package wellbeing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gofiber-baro/internal/domain"
+	"gofiber-baro/pkg/middleware"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Service checks learners' barometer readings against the wellbeing rules
+// and keeps the cases coaches follow up on.
+type Service struct {
+	repo        domain.WellbeingCaseRepository
+	reflections domain.ReflectionRepository
+	userRepo    domain.UserRepository
+	rules       []domain.WellbeingRule
+}
+
+func NewService(repo domain.WellbeingCaseRepository, reflections domain.ReflectionRepository, userRepo domain.UserRepository) *Service {
+	return &Service{repo: repo, reflections: reflections, userRepo: userRepo, rules: rulesFromEnv()}
+}
+
+// rulesFromEnv reads WELLBEING_RULES, a JSON array of rules replacing the
+// defaults; "[]" turns alerts off.
+func rulesFromEnv() []domain.WellbeingRule {
+	v := strings.TrimSpace(os.Getenv("WELLBEING_RULES"))
+	if v == "" {
+		return domain.DefaultWellbeingRules
+	}
+	var rules []domain.WellbeingRule
+	if err := json.Unmarshal([]byte(v), &rules); err != nil {
+		log.Printf("WARNING: invalid WELLBEING_RULES, using the defaults: %v", err)
+		return domain.DefaultWellbeingRules
+	}
+	for i := range rules {
+		if err := checkRule(&rules[i]); err != nil {
+			log.Printf("WARNING: invalid WELLBEING_RULES, using the defaults: %v", err)
+			return domain.DefaultWellbeingRules
+		}
+	}
+	return rules
+}
+
+// checkRule validates r and normalises the spelling of its zones.
+func checkRule(r *domain.WellbeingRule) error {
+	if r.ID == "" {
+		return fmt.Errorf("rule without id")
+	}
+	if r.Count < 1 || r.WithinDays < 1 {
+		return fmt.Errorf("rule %s: count and within_days must be at least 1", r.ID)
+	}
+	if len(r.Zones) == 0 {
+		return fmt.Errorf("rule %s: no zones", r.ID)
+	}
+	for i, z := range r.Zones {
+		zone, ok := domain.ParseBarometerZone(string(z))
+		if !ok {
+			return fmt.Errorf("rule %s: unknown zone %q", r.ID, z)
+		}
+		r.Zones[i] = zone
+	}
+	if r.Description == "" {
+		r.Description = r.ID
+	}
+	return nil
+}
+
+// Rules returns the rules in effect.
+func (s *Service) Rules() []domain.WellbeingRule {
+	return s.rules
+}
+
+// Evaluate checks the learner's readings up to r, which was just written or
+// edited, and opens a case (or adds to the open one) for every rule they
+// now meet. Errors are logged: a failed check must not fail the reflection.
+func (s *Service) Evaluate(r domain.Reflection) {
+	if len(s.rules) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	reflections, err := s.reflections.FindByUser(ctx, r.UserID)
+	if err != nil {
+		log.Printf("[ERROR] wellbeing: load reflections of %s: %v", r.UserID.Hex(), err)
+		return
+	}
+	// Up to and including r's day, latest last.
+	day := r.DayKey()
+	history := make([]domain.Reflection, 0, len(reflections))
+	for _, ref := range reflections {
+		if ref.DayKey() <= day {
+			history = append(history, ref)
+		}
+	}
+	sort.SliceStable(history, func(i, j int) bool { return history[i].DayKey() < history[j].DayKey() })
+
+	for _, rule := range s.rules {
+		matched := match(rule, history)
+		if matched == nil {
+			continue
+		}
+		if err := s.open(ctx, r, rule, matched); err != nil {
+			log.Printf("[ERROR] wellbeing: open %s case for %s: %v", rule.ID, r.UserID.Hex(), err)
+		}
+	}
+}
+
+// match returns the reflections that make history meet rule, or nil. Only
+// runs ending in the latest reflection count, so a case is raised by the
+// reading that completes it.
+func match(rule domain.WellbeingRule, history []domain.Reflection) []domain.Reflection {
+	if len(history) == 0 || !inZones(rule, history[len(history)-1]) {
+		return nil
+	}
+	latest, err := time.Parse("2006-01-02", history[len(history)-1].DayKey())
+	if err != nil {
+		return nil
+	}
+	from := latest.AddDate(0, 0, -(rule.WithinDays - 1)).Format("2006-01-02")
+
+	var matched []domain.Reflection
+	for i := len(history) - 1; i >= 0 && len(matched) < rule.Count; i-- {
+		ref := history[i]
+		if ref.DayKey() < from {
+			break
+		}
+		if inZones(rule, ref) {
+			matched = append(matched, ref)
+		} else if rule.Consecutive {
+			break
+		}
+	}
+	if len(matched) < rule.Count {
+		return nil
+	}
+	return matched
+}
+
+func inZones(rule domain.WellbeingRule, r domain.Reflection) bool {
+	for _, z := range rule.Zones {
+		if r.ReflectionData.Barometer == z {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) open(ctx context.Context, r domain.Reflection, rule domain.WellbeingRule, matched []domain.Reflection) error {
+	ids := make([]primitive.ObjectID, len(matched))
+	days := make([]string, len(matched))
+	for i, ref := range matched {
+		// matched runs latest first; list the days in order.
+		ids[len(matched)-1-i] = ref.ID
+		days[len(matched)-1-i] = ref.DayKey()
+	}
+	now := time.Now()
+	reason := fmt.Sprintf("%s (%s)", rule.Description, strings.Join(days, ", "))
+
+	c := &domain.WellbeingCase{
+		UserID:        r.UserID,
+		UserName:      s.userName(ctx, r.UserID),
+		CohortNumber:  r.CohortNumber,
+		RuleID:        rule.ID,
+		Reason:        reason,
+		ReflectionIDs: ids,
+		Status:        domain.CaseOpen,
+		History:       []domain.CaseEvent{{Status: domain.CaseOpen, Note: reason, At: now}},
+		OpenedAt:      now,
+		UpdatedAt:     now,
+	}
+	if coach := s.cohortCoach(ctx, r.CohortNumber); coach != nil {
+		c.AssignedTo = coach.ID
+		c.AssignedName = displayName(coach)
+	}
+
+	opened, err := s.repo.Open(ctx, c)
+	if err != nil {
+		return err
+	}
+	if opened {
+		log.Printf("[INFO] wellbeing: opened %s case %s for %s", rule.ID, c.ID.Hex(), r.UserID.Hex())
+	}
+	return nil
+}
+
+// cohortCoach is the coach a new case of the cohort goes to: the first
+// active one by first name, or nil if it has none.
+func (s *Service) cohortCoach(ctx context.Context, cohort int) *domain.User {
+	if cohort <= 0 {
+		return nil
+	}
+	filter := domain.UserFilter{Role: middleware.RoleCoach, StaffCohort: cohort}
+	coaches, _, err := s.userRepo.FindRoster(ctx, filter, options.Find().SetSort(bson.D{{Key: "first_name", Value: 1}}))
+	if err != nil {
+		return nil
+	}
+	for i := range coaches {
+		if !coaches[i].Deleted && !coaches[i].Disabled {
+			return &coaches[i]
+		}
+	}
+	return nil
+}
+
+func (s *Service) userName(ctx context.Context, id primitive.ObjectID) string {
+	users, _, err := s.userRepo.FindRoster(ctx, domain.UserFilter{IDs: []primitive.ObjectID{id}}, nil)
+	if err != nil || len(users) == 0 {
+		return ""
+	}
+	return displayName(&users[0])
+}
+
+func displayName(u *domain.User) string {
+	if name := strings.TrimSpace(u.FirstName + " " + u.LastName); name != "" {
+		return name
+	}
+	return u.ZoomName
+}
+
+// List returns a page of cases, unresolved and most recently updated first.
+func (s *Service) List(filter domain.WellbeingCaseFilter, page, limit int) ([]domain.WellbeingCase, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.repo.FindAll(ctx, filter, int64((page-1)*limit), int64(limit))
+}
+
+func (s *Service) Get(id primitive.ObjectID) (*domain.WellbeingCase, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.repo.FindByID(ctx, id)
+}
+
+// CaseUpdate is a coach's follow-up on a case. Empty fields stay as they are.
+type CaseUpdate struct {
+	Status   domain.CaseStatus
+	Note     string
+	AssignTo primitive.ObjectID
+	By       primitive.ObjectID
+}
+
+// Update records a follow-up. Resolving a case closes it for good; the
+// learner's next matching readings open a new one.
+func (s *Service) Update(id primitive.ObjectID, u CaseUpdate) (*domain.WellbeingCase, error) {
+	if u.Status != "" && !u.Status.Valid() {
+		return nil, domain.ErrInvalidCaseStatus
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !c.Active {
+		return nil, domain.ErrCaseClosed
+	}
+
+	now := time.Now()
+	if !u.AssignTo.IsZero() && u.AssignTo != c.AssignedTo {
+		users, _, err := s.userRepo.FindRoster(ctx, domain.UserFilter{IDs: []primitive.ObjectID{u.AssignTo}}, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(users) == 0 || users[0].Deleted || users[0].Disabled || !middleware.IsStaffRole(users[0].Role) {
+			return nil, domain.ErrInvalidCaseAssignee
+		}
+		c.AssignedTo = users[0].ID
+		c.AssignedName = displayName(&users[0])
+		if u.Note == "" {
+			u.Note = "Assigned to " + c.AssignedName
+		}
+	}
+	if u.Status != "" {
+		c.Status = u.Status
+	}
+	if c.Status == domain.CaseResolved {
+		c.Active = false
+		c.ResolvedAt = &now
+	}
+	c.UpdatedAt = now
+
+	event := domain.CaseEvent{Status: c.Status, Note: strings.TrimSpace(u.Note), By: u.By, At: now}
+	if err := s.repo.Update(ctx, c, event); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
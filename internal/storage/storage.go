@@ -8,4 +8,8 @@ import (
 type Storage interface {
 	Upload(ctx context.Context, key string, body io.Reader, contentType string) (string, error)
 	DeleteObjectsByPrefix(ctx context.Context, prefix string) error
+	// Download opens the object at key. The caller closes it.
+	Download(ctx context.Context, key string) (io.ReadCloser, error)
+	// KeyFromURL recovers the object key from a URL returned by Upload.
+	KeyFromURL(url string) (string, bool)
 }
@@ -57,6 +57,25 @@ func (s *supabaseStorage) Upload(ctx context.Context, key string, body io.Reader
 	return s.publicBaseURL + "/" + key, nil
 }
 
+func (s *supabaseStorage) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Body, nil
+}
+
+func (s *supabaseStorage) KeyFromURL(url string) (string, bool) {
+	prefix := s.publicBaseURL + "/"
+	if !strings.HasPrefix(url, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(url, prefix), true
+}
+
 func (s *supabaseStorage) DeleteObjectsByPrefix(ctx context.Context, prefix string) error {
 	var keys []string
 	var continuationToken *string
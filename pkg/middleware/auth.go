@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -16,9 +18,84 @@ type Claims struct {
 	UserID string `json:"user_id"`
 	Role   string `json:"role"`
 	Cohort int    `json:"cohort"`
+	// Cohorts lists the cohorts a scoped staff role (coach, TA) may manage.
+	Cohorts []int `json:"cohorts,omitempty"`
+	// TokenVersion is compared against the user's stored version so a bump
+	// (cohort transfer, log out all devices) revokes older tokens.
+	TokenVersion int `json:"tv,omitempty"`
+	// MustChangePassword restricts the token to passwordChangePaths.
+	MustChangePassword bool `json:"mcp,omitempty"`
+	// TOTPSetupRequired restricts the token to totpSetupPaths.
+	TOTPSetupRequired bool `json:"tsr,omitempty"`
+	// Impersonator is the admin viewing the app as this user. Such tokens
+	// are read-only: AuthMiddleware rejects them on any mutating method.
+	Impersonator string `json:"impersonator,omitempty"`
+	// APIKeyID and Permissions are set when the request authenticated with
+	// an API key instead of a JWT; the key holds exactly Permissions. They
+	// are never read from a token.
+	APIKeyID    string       `json:"-"`
+	Permissions []Permission `json:"-"`
 	jwt.RegisteredClaims
 }
 
+// passwordChangePaths are the only routes a token carrying
+// MustChangePassword may reach.
+var passwordChangePaths = map[string]bool{
+	"/auth/change-password": true,
+	"/auth/logout-all":      true,
+	"/api/verify-token":     true,
+}
+
+// totpSetupPaths are the only routes a token carrying TOTPSetupRequired may
+// reach.
+var totpSetupPaths = map[string]bool{
+	"/auth/2fa/enroll":  true,
+	"/auth/2fa/verify":  true,
+	"/auth/logout-all":  true,
+	"/api/verify-token": true,
+}
+
+// readOnlyMethods are the only methods an impersonation token may use.
+var readOnlyMethods = map[string]bool{
+	fiber.MethodGet:     true,
+	fiber.MethodHead:    true,
+	fiber.MethodOptions: true,
+}
+
+// TokenRevocationChecker reports whether a token no longer matches its user:
+// deleted, disabled, role changed or sessions revoked.
+type TokenRevocationChecker interface {
+	IsTokenRevoked(ctx context.Context, userID, role string, tokenVersion int) (bool, error)
+}
+
+var revocationChecker TokenRevocationChecker
+
+// SetTokenRevocationChecker wires the database-backed check used by
+// AuthMiddleware. Without one, tokens are validated by signature only.
+func SetTokenRevocationChecker(checker TokenRevocationChecker) {
+	revocationChecker = checker
+}
+
+// apiKeyPrefix marks a bearer credential as an API key (see
+// internal/service/apikey).
+const apiKeyPrefix = "baro_"
+
+// APIKeyAuthenticator looks up integration API keys and records each use.
+type APIKeyAuthenticator interface {
+	// AuthenticateAPIKey reports ok=false for unknown, revoked or expired
+	// keys.
+	AuthenticateAPIKey(ctx context.Context, key string) (keyID string, permissions []string, ok bool, err error)
+	RecordAPIKeyUse(ctx context.Context, keyID, method, route, ip string, status int)
+}
+
+var apiKeyAuthenticator APIKeyAuthenticator
+
+// SetAPIKeyAuthenticator enables API keys in AuthMiddleware. Without one,
+// only JWTs are accepted.
+func SetAPIKeyAuthenticator(a APIKeyAuthenticator) {
+	apiKeyAuthenticator = a
+}
+
 // Load environment variables
 func init() {
 	if err := godotenv.Load(); err != nil {
@@ -41,6 +118,10 @@ func AuthMiddleware(c *fiber.Ctx) error {
 	}
 	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
+	if strings.HasPrefix(tokenString, apiKeyPrefix) {
+		return apiKeyAuth(c, tokenString)
+	}
+
 	// Parse and validate the token
 	claims := &Claims{}
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
@@ -59,6 +140,26 @@ func AuthMiddleware(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusUnauthorized, "Unauthorized: Token expired")
 	}
 
+	if revocationChecker != nil {
+		revoked, err := revocationChecker.IsTokenRevoked(c.Context(), claims.UserID, claims.Role, claims.TokenVersion)
+		if err != nil {
+			return fiber.NewError(fiber.StatusInternalServerError, "Could not verify token")
+		}
+		if revoked {
+			return fiber.NewError(fiber.StatusUnauthorized, "Unauthorized: Token revoked")
+		}
+	}
+
+	if claims.MustChangePassword && !passwordChangePaths[c.Path()] {
+		return fiber.NewError(fiber.StatusForbidden, "Password change required")
+	}
+	if claims.TOTPSetupRequired && !totpSetupPaths[c.Path()] {
+		return fiber.NewError(fiber.StatusForbidden, "Two-factor setup required")
+	}
+	if claims.Impersonator != "" && !readOnlyMethods[c.Method()] {
+		return fiber.NewError(fiber.StatusForbidden, "Impersonation is read-only")
+	}
+
 	// Store the claims in the context for later use
 	c.Locals("user", claims)
 	c.Locals("userID", claims.UserID)
@@ -66,16 +167,51 @@ func AuthMiddleware(c *fiber.Ctx) error {
 	return c.Next()
 }
 
-// CheckAdminRole middleware to validate that the user is an admin
-func CheckAdminRole(c *fiber.Ctx) error {
-	claims, ok := c.Locals("user").(*Claims)
+// apiKeyAuth authenticates an integration by API key. The key gets claims
+// with no user or role, only its own permissions, and every request made
+// with it is recorded once the handler has run, even if it panicked.
+func apiKeyAuth(c *fiber.Ctx, key string) (err error) {
+	if apiKeyAuthenticator == nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "Unauthorized: Invalid token")
+	}
+	keyID, permissions, ok, err := apiKeyAuthenticator.AuthenticateAPIKey(c.Context(), key)
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, "Could not verify API key")
+	}
 	if !ok {
-		return fiber.NewError(fiber.StatusUnauthorized, "Invalid token claims")
+		return fiber.NewError(fiber.StatusUnauthorized, "Unauthorized: Invalid API key")
 	}
 
-	if claims.Role != "admin" {
-		return fiber.NewError(fiber.StatusForbidden, "Access denied: admin role required")
+	claims := &Claims{APIKeyID: keyID}
+	for _, p := range permissions {
+		claims.Permissions = append(claims.Permissions, Permission(p))
 	}
+	c.Locals("user", claims)
+	c.Locals("apiKeyID", keyID)
 
+	defer func() {
+		status := c.Response().StatusCode()
+		recovered := recover()
+		if recovered != nil {
+			status = fiber.StatusInternalServerError
+		} else if err != nil {
+			status = fiber.StatusInternalServerError
+			var fe *fiber.Error
+			if errors.As(err, &fe) {
+				status = fe.Code
+			}
+		}
+		apiKeyAuthenticator.RecordAPIKeyUse(context.Background(), keyID, c.Method(), c.Route().Path, c.IP(), status)
+		if recovered != nil {
+			panic(recovered)
+		}
+	}()
 	return c.Next()
 }
+
+// UserID returns the ID of the signed-in user. Requests made with an API
+// key act for no user, so they get false.
+func UserID(c *fiber.Ctx) (string, bool) {
+	id, ok := c.Locals("userID").(string)
+	return id, ok && id != ""
+}
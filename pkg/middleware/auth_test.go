@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+)
+
+// testKeys accepts every key and remembers the uses recorded.
+type testKeys struct {
+	statuses []int
+}
+
+func (k *testKeys) AuthenticateAPIKey(ctx context.Context, key string) (string, []string, bool, error) {
+	return "key1", nil, true, nil
+}
+
+func (k *testKeys) RecordAPIKeyUse(ctx context.Context, keyID, method, route, ip string, status int) {
+	k.statuses = append(k.statuses, status)
+}
+
+// TestAPIKeyUseRecordedOnPanic checks a handler that panics is still
+// recorded, as a 500.
+func TestAPIKeyUseRecordedOnPanic(t *testing.T) {
+	t.Setenv("JWT_SECRET_KEY", "test-secret")
+	keys := &testKeys{}
+	SetAPIKeyAuthenticator(keys)
+	t.Cleanup(func() { SetAPIKeyAuthenticator(nil) })
+
+	app := fiber.New()
+	app.Use(recover.New())
+	app.Get("/boom", AuthMiddleware, func(c *fiber.Ctx) error { panic("boom") })
+
+	req := httptest.NewRequest(fiber.MethodGet, "/boom", nil)
+	req.Header.Set("Authorization", "Bearer baro_testkey")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", resp.StatusCode, fiber.StatusInternalServerError)
+	}
+	if len(keys.statuses) != 1 || keys.statuses[0] != fiber.StatusInternalServerError {
+		t.Errorf("recorded uses %v, want [%d]", keys.statuses, fiber.StatusInternalServerError)
+	}
+}
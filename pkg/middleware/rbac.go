@@ -0,0 +1,318 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	RoleAdmin   = "admin"
+	RoleCoach   = "coach"
+	RoleTA      = "ta"
+	RoleLearner = "learner"
+)
+
+type Permission string
+
+const (
+	PermUsersRead           Permission = "users:read"
+	PermUsersManage         Permission = "users:manage"
+	PermRolesManage         Permission = "roles:manage"
+	PermRewardsGrant        Permission = "rewards:grant"
+	PermReflectionsRead     Permission = "reflections:read"
+	PermReflectionsFeedback Permission = "reflections:feedback"
+	PermAttendanceManage    Permission = "attendance:manage"
+	PermLeaveManage         Permission = "leave:manage"
+	PermHolidaysManage      Permission = "holidays:manage"
+	PermNotificationsManage Permission = "notifications:manage"
+	PermCohortsManage       Permission = "cohorts:manage"
+	PermBoardModerate       Permission = "board:moderate"
+	PermAuditRead           Permission = "audit:read"
+	PermAttendanceExport    Permission = "attendance:export"
+	PermAPIKeysManage       Permission = "api_keys:manage"
+	PermPrivacyManage       Permission = "privacy:manage"
+	PermUsersImpersonate    Permission = "users:impersonate"
+)
+
+// rolePermissions is the whole policy. Admins hold every permission; staff
+// roles are additionally limited to the cohorts in their token (see
+// unscopedRoles).
+var rolePermissions = map[string]map[Permission]bool{
+	RoleAdmin: {
+		PermUsersRead: true, PermUsersManage: true, PermRolesManage: true,
+		PermRewardsGrant: true, PermReflectionsRead: true, PermReflectionsFeedback: true,
+		PermAttendanceManage: true, PermLeaveManage: true, PermHolidaysManage: true,
+		PermNotificationsManage: true, PermCohortsManage: true, PermBoardModerate: true,
+		PermAuditRead: true, PermAttendanceExport: true, PermAPIKeysManage: true,
+		PermPrivacyManage: true, PermUsersImpersonate: true,
+	},
+	RoleCoach: {
+		PermUsersRead: true, PermReflectionsRead: true, PermReflectionsFeedback: true,
+		PermAttendanceManage: true, PermAttendanceExport: true, PermLeaveManage: true,
+		PermBoardModerate: true,
+	},
+	RoleTA: {
+		PermUsersRead: true, PermReflectionsRead: true,
+		PermAttendanceManage: true, PermAttendanceExport: true, PermLeaveManage: true,
+	},
+	RoleLearner: {},
+}
+
+var unscopedRoles = map[string]bool{RoleAdmin: true}
+
+// IsValidRole reports whether role is one the policy knows about.
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// IsGrantablePermission reports whether p may be given to an API key: any
+// permission the policy knows, except minting further keys and acting as a
+// user.
+func IsGrantablePermission(p Permission) bool {
+	return rolePermissions[RoleAdmin][p] && p != PermAPIKeysManage && p != PermUsersImpersonate
+}
+
+// IsStaffRole reports whether role holds any permission at all.
+func IsStaffRole(role string) bool {
+	return len(rolePermissions[role]) > 0
+}
+
+// IsScopedRole reports whether role is limited to its assigned cohorts.
+func IsScopedRole(role string) bool {
+	return IsStaffRole(role) && !unscopedRoles[role]
+}
+
+func (c *Claims) Can(p Permission) bool {
+	if c.APIKeyID != "" {
+		for _, granted := range c.Permissions {
+			if granted == p {
+				return true
+			}
+		}
+		return false
+	}
+	return rolePermissions[c.Role][p]
+}
+
+// unscoped reports whether the caller reaches every cohort: admins, and API
+// keys, which are limited by permission alone.
+func (c *Claims) unscoped() bool {
+	return unscopedRoles[c.Role] || c.APIKeyID != ""
+}
+
+// InScope reports whether the caller may act on cohort. Unscoped roles reach
+// every cohort; scoped staff only those assigned to them.
+func (c *Claims) InScope(cohort int) bool {
+	if c.unscoped() {
+		return true
+	}
+	for _, n := range c.Cohorts {
+		if n == cohort {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize is the in-handler counterpart of Require for checks that depend
+// on data the handler has already loaded.
+func Authorize(c *fiber.Ctx, p Permission, cohort int) bool {
+	claims, ok := c.Locals("user").(*Claims)
+	if !ok {
+		return false
+	}
+	return claims.Can(p) && claims.InScope(cohort)
+}
+
+// HasPermission checks the permission alone, for data that isn't tied to a
+// cohort.
+func HasPermission(c *fiber.Ctx, p Permission) bool {
+	claims, ok := c.Locals("user").(*Claims)
+	return ok && claims.Can(p)
+}
+
+// RequireStaff admits any role that holds at least one permission, and API
+// keys. Routes behind it still declare their own permission with Require.
+func RequireStaff(c *fiber.Ctx) error {
+	claims, ok := c.Locals("user").(*Claims)
+	if !ok {
+		return fiber.NewError(fiber.StatusUnauthorized, "Invalid token claims")
+	}
+	if !IsStaffRole(claims.Role) && claims.APIKeyID == "" {
+		return fiber.NewError(fiber.StatusForbidden, "Access denied: staff role required")
+	}
+	return c.Next()
+}
+
+// ---- Cohort scopes ----
+
+var ErrResourceNotFound = errors.New("resource not found")
+var errScopeMissing = errors.New("cohort is required")
+
+// Scope tells Require which cohorts a request touches. Returning no cohorts
+// means the route carries no cohort data. Routes declared without a Scope are
+// closed to scoped roles.
+type Scope func(c *fiber.Ctx) ([]int, error)
+
+// CohortResolver maps a resource ID to the cohort it belongs to. It should
+// return ErrResourceNotFound for unknown IDs.
+type CohortResolver func(ctx context.Context, id string) (int, error)
+
+var cohortResolvers = map[string]CohortResolver{}
+
+// RegisterCohortResolver makes kind usable in ResourceParam and ResourceBody.
+func RegisterCohortResolver(kind string, resolver CohortResolver) {
+	cohortResolvers[kind] = resolver
+}
+
+// AnyCohort marks routes whose data is not tied to a cohort (e.g. holidays).
+func AnyCohort(*fiber.Ctx) ([]int, error) {
+	return nil, nil
+}
+
+func CohortQuery(name string) Scope {
+	return func(c *fiber.Ctx) ([]int, error) {
+		n := c.QueryInt(name, 0)
+		if n <= 0 {
+			return nil, errScopeMissing
+		}
+		return []int{n}, nil
+	}
+}
+
+func CohortParam(name string) Scope {
+	return func(c *fiber.Ctx) ([]int, error) {
+		n, err := strconv.Atoi(c.Params(name))
+		if err != nil || n <= 0 {
+			return nil, errScopeMissing
+		}
+		return []int{n}, nil
+	}
+}
+
+func CohortBody(field string) Scope {
+	return func(c *fiber.Ctx) ([]int, error) {
+		var n int
+		if err := bodyField(c, field, &n); err != nil || n <= 0 {
+			return nil, errScopeMissing
+		}
+		return []int{n}, nil
+	}
+}
+
+// ResourceParam resolves the cohort of the resource whose ID is in the path.
+func ResourceParam(kind, param string) Scope {
+	return func(c *fiber.Ctx) ([]int, error) {
+		return resolveCohorts(c, kind, []string{c.Params(param)})
+	}
+}
+
+// ResourceBody resolves the cohorts of the resources whose IDs are in a body
+// field holding either a single ID or a list of them.
+func ResourceBody(kind, field string) Scope {
+	return func(c *fiber.Ctx) ([]int, error) {
+		var ids []string
+		if err := bodyField(c, field, &ids); err != nil {
+			var id string
+			if err := bodyField(c, field, &id); err != nil {
+				return nil, errScopeMissing
+			}
+			ids = []string{id}
+		}
+		return resolveCohorts(c, kind, ids)
+	}
+}
+
+func resolveCohorts(c *fiber.Ctx, kind string, ids []string) ([]int, error) {
+	resolver, ok := cohortResolvers[kind]
+	if !ok {
+		return nil, errors.New("no cohort resolver registered for " + kind)
+	}
+	if len(ids) == 0 {
+		return nil, errScopeMissing
+	}
+	cohorts := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if id == "" {
+			return nil, errScopeMissing
+		}
+		cohort, err := resolver(c.Context(), id)
+		if err != nil {
+			return nil, err
+		}
+		cohorts = append(cohorts, cohort)
+	}
+	return cohorts, nil
+}
+
+func bodyField(c *fiber.Ctx, field string, dst interface{}) error {
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(c.Body(), &body); err != nil {
+		return err
+	}
+	raw, ok := body[field]
+	if !ok {
+		return errScopeMissing
+	}
+	return json.Unmarshal(raw, dst)
+}
+
+// Require is the route-level permission check:
+//
+//	admin.Get("/attendance/logs", middleware.Require(middleware.PermAttendanceManage, middleware.CohortQuery("cohort")), h.GetAttendanceLogs)
+//
+// Unscoped roles only need the permission. Scoped roles also need every
+// cohort the scope resolves to, and are refused outright when no scope is
+// declared.
+func Require(p Permission, scope ...Scope) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals("user").(*Claims)
+		if !ok {
+			return fiber.NewError(fiber.StatusUnauthorized, "Invalid token claims")
+		}
+		if !claims.Can(p) {
+			return fiber.NewError(fiber.StatusForbidden, "Access denied: missing permission "+string(p))
+		}
+		if claims.unscoped() {
+			return c.Next()
+		}
+		if len(scope) == 0 {
+			return fiber.NewError(fiber.StatusForbidden, "Access denied: not available to cohort-scoped roles")
+		}
+
+		for _, s := range scope {
+			cohorts, err := s(c)
+			switch {
+			case errors.Is(err, errScopeMissing):
+				return fiber.NewError(fiber.StatusBadRequest, "A cohort is required for cohort-scoped roles")
+			case errors.Is(err, ErrResourceNotFound):
+				return fiber.NewError(fiber.StatusNotFound, "Not found")
+			case err != nil:
+				return fiber.NewError(fiber.StatusInternalServerError, "Could not check cohort access")
+			}
+			for _, cohort := range cohorts {
+				if !claims.InScope(cohort) {
+					return fiber.NewError(fiber.StatusForbidden, "Access denied: cohort "+strconv.Itoa(cohort)+" is not assigned to you")
+				}
+			}
+		}
+		return c.Next()
+	}
+}
+
+// SelfOr lets callers act on their own record (the user ID in param) and
+// sends everyone else through next, usually a Require.
+func SelfOr(param string, next fiber.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals("user").(*Claims)
+		if ok && claims.UserID == c.Params(param) {
+			return c.Next()
+		}
+		return next(c)
+	}
+}
@@ -19,3 +19,12 @@ func CheckPasswordHash(password, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }
+
+// dummyHash stands in for a stored hash when a login email is unknown.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcrypt.DefaultCost)
+
+// DummyPasswordCheck costs the same as CheckPasswordHash so a login with an
+// unknown email takes as long as one with a wrong password.
+func DummyPasswordCheck(password string) {
+	_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
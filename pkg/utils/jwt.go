@@ -14,6 +14,18 @@ type Claims struct {
 	UserID string `json:"user_id"`
 	Role   string `json:"role"`
 	Cohort int    `json:"cohort"`
+	// Cohorts lists the cohorts a scoped staff role may manage.
+	Cohorts []int `json:"cohorts,omitempty"`
+	// TokenVersion must match the user's current token_version; bumping it
+	// revokes every token issued before.
+	TokenVersion int `json:"tv,omitempty"`
+	// MustChangePassword limits the token to the change-password endpoint.
+	MustChangePassword bool `json:"mcp,omitempty"`
+	// TOTPSetupRequired limits the token to two-factor enrollment.
+	TOTPSetupRequired bool `json:"tsr,omitempty"`
+	// Impersonator marks a read-only token issued to an admin viewing the
+	// app as this user.
+	Impersonator string `json:"impersonator,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -25,12 +37,47 @@ func getJWTKey() []byte {
 	return []byte(key)
 }
 
-func GenerateJWT(userID primitive.ObjectID, role string, cohort int, secretKey string) (string, error) {
+// AccessTokenTTL is kept short because access tokens are only revoked by a
+// token_version bump; clients renew them with a refresh token.
+const AccessTokenTTL = 15 * time.Minute
+
+// ImpersonationTokenTTL bounds an admin's "view as" session. It cannot be
+// refreshed.
+const ImpersonationTokenTTL = 10 * time.Minute
+
+// TokenSubject is everything an access token says about its user.
+type TokenSubject struct {
+	UserID             primitive.ObjectID
+	Role               string
+	Cohort             int
+	StaffCohorts       []int
+	TokenVersion       int
+	MustChangePassword bool
+	TOTPSetupRequired  bool
+	// Impersonator, when set, makes this a read-only impersonation token.
+	Impersonator primitive.ObjectID
+}
+
+func GenerateJWT(sub TokenSubject, secretKey string) (string, error) {
 	claims := jwt.MapClaims{
-		"user_id": userID.Hex(),
-		"role":    role,
-		"cohort":  cohort,
-		"exp":     time.Now().Add(30 * 24 * time.Hour).Unix(),
+		"user_id": sub.UserID.Hex(),
+		"role":    sub.Role,
+		"cohort":  sub.Cohort,
+		"tv":      sub.TokenVersion,
+		"exp":     time.Now().Add(AccessTokenTTL).Unix(),
+	}
+	if !sub.Impersonator.IsZero() {
+		claims["impersonator"] = sub.Impersonator.Hex()
+		claims["exp"] = time.Now().Add(ImpersonationTokenTTL).Unix()
+	}
+	if len(sub.StaffCohorts) > 0 {
+		claims["cohorts"] = sub.StaffCohorts
+	}
+	if sub.MustChangePassword {
+		claims["mcp"] = true
+	}
+	if sub.TOTPSetupRequired {
+		claims["tsr"] = true
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
@@ -60,3 +107,66 @@ func ValidateJWT(tokenString string) (string, error) {
 
 	return "", errors.New("invalid token claims")
 }
+
+// LoginChallengeTTL is how long a user has to enter their two-factor code
+// after the password step.
+const LoginChallengeTTL = 5 * time.Minute
+
+// GenerateLoginChallenge proves the password step of a two-factor login. It
+// is not an access token: AuthMiddleware rejects it because it has no role.
+func GenerateLoginChallenge(userID primitive.ObjectID) (string, error) {
+	claims := jwt.MapClaims{
+		"sub": userID.Hex(),
+		"aud": "login-2fa",
+		"exp": time.Now().Add(LoginChallengeTTL).Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(getJWTKey())
+}
+
+// ParseLoginChallenge returns the user ID from a valid, unexpired challenge.
+func ParseLoginChallenge(challenge string) (string, error) {
+	claims := &jwt.RegisteredClaims{}
+	token, err := jwt.ParseWithClaims(challenge, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return getJWTKey(), nil
+	})
+	if err != nil || !token.Valid || !claims.VerifyAudience("login-2fa", true) {
+		return "", errors.New("invalid login challenge")
+	}
+	return claims.Subject, nil
+}
+
+// OIDCStateTTL bounds how long a single sign-on round trip may take.
+const OIDCStateTTL = 10 * time.Minute
+
+// GenerateOIDCState signs the nonce sent to the identity provider, so the
+// callback can check both without server-side storage.
+func GenerateOIDCState(nonce string) (string, error) {
+	claims := jwt.MapClaims{
+		"aud":   "oidc-state",
+		"nonce": nonce,
+		"exp":   time.Now().Add(OIDCStateTTL).Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(getJWTKey())
+}
+
+// ParseOIDCState returns the nonce from a valid, unexpired state.
+func ParseOIDCState(state string) (string, error) {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(state, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return getJWTKey(), nil
+	})
+	if err != nil || !token.Valid || !claims.VerifyAudience("oidc-state", true) {
+		return "", errors.New("invalid state")
+	}
+	nonce, _ := claims["nonce"].(string)
+	if nonce == "" {
+		return "", errors.New("invalid state")
+	}
+	return nonce, nil
+}